@@ -0,0 +1,90 @@
+package astutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Annotation is a comment line starting with @ in method comments of service interface.
+// For example, "@route PATCH /orders/{id}" has Name "@route" and Params ["PATCH", "/orders/{id}"]
+type Annotation struct {
+	Name   string
+	Params []string
+}
+
+const (
+	// AnnotationRoute declares http method and path of a method, e.g. "@route PATCH /orders/{id}"
+	AnnotationRoute = "@route"
+	// AnnotationPath declares parameters as path variables appended to the path of a method, e.g. "@path userId"
+	AnnotationPath = "@path"
+)
+
+// NewAnnotations parses annotations from comments
+func NewAnnotations(comments []string) []Annotation {
+	var ret []Annotation
+	for _, comment := range comments {
+		fields := strings.Fields(comment)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "@") || len(fields[0]) == 1 {
+			continue
+		}
+		ret = append(ret, Annotation{
+			Name:   fields[0],
+			Params: fields[1:],
+		})
+	}
+	return ret
+}
+
+// pathVarRe matches path variables in gorilla/mux style, e.g. {id} or {id:[0-9]+}
+var pathVarRe = regexp.MustCompile(`{([^{}:]+)(:[^{}]*)?}`)
+
+// PathVarNames returns names of path variables in the path. For example, "/orders/{id}/items/{itemId:[0-9]+}"
+// returns ["id", "itemId"]
+func PathVarNames(path string) []string {
+	var ret []string
+	for _, match := range pathVarRe.FindAllStringSubmatch(path, -1) {
+		ret = append(ret, strings.TrimSpace(match[1]))
+	}
+	return ret
+}
+
+// TrimPathVarPattern removes regular expression patterns of path variables in the path,
+// e.g. "/orders/{id:[0-9]+}" returns "/orders/{id}"
+func TrimPathVarPattern(path string) string {
+	return pathVarRe.ReplaceAllString(path, "{$1}")
+}
+
+// parseRoute fills HttpMethod, Path and PathVars of the method from its annotations
+func (mm *MethodMeta) parseRoute() {
+	var varnames []string
+	for _, item := range mm.Annotations {
+		switch item.Name {
+		case AnnotationRoute:
+			if len(item.Params) > 0 {
+				mm.HttpMethod = strings.ToUpper(item.Params[0])
+			}
+			if len(item.Params) > 1 {
+				mm.Path = item.Params[1]
+				varnames = append(varnames, PathVarNames(mm.Path)...)
+			}
+		case AnnotationPath:
+			varnames = append(varnames, item.Params...)
+		}
+	}
+	for _, name := range varnames {
+		var found bool
+		for _, param := range mm.Params {
+			if param.Name == name {
+				mm.PathVars = append(mm.PathVars, param)
+				found = true
+				break
+			}
+		}
+		if !found {
+			// keep the name so that unknown path variables can be reported by validation
+			mm.PathVars = append(mm.PathVars, FieldMeta{
+				Name: name,
+			})
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package astutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewAnnotations(t *testing.T) {
+	comments := []string{"comment1", "@route PATCH /orders/{id}", "@", "email@example.com", "@public"}
+	want := []Annotation{
+		{
+			Name:   "@route",
+			Params: []string{"PATCH", "/orders/{id}"},
+		},
+		{
+			Name:   "@public",
+			Params: []string{},
+		},
+	}
+	if got := NewAnnotations(comments); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewAnnotations() = %v, want %v", got, want)
+	}
+}
+
+func TestPathVarNames(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "1",
+			path: "/orders/{id}/items/{itemId:[0-9]+}",
+			want: []string{"id", "itemId"},
+		},
+		{
+			name: "2",
+			path: "/orders",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PathVarNames(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PathVarNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimPathVarPattern(t *testing.T) {
+	got := TrimPathVarPattern("/orders/{id}/items/{itemId:[0-9]+}")
+	want := "/orders/{id}/items/{itemId}"
+	if got != want {
+		t.Errorf("TrimPathVarPattern() = %v, want %v", got, want)
+	}
+}
+
+func TestMethodMeta_parseRoute(t *testing.T) {
+	mm := MethodMeta{
+		Name: "UpdateOrder",
+		Params: []FieldMeta{
+			{
+				Name: "ctx",
+				Type: "context.Context",
+			},
+			{
+				Name: "id",
+				Type: "int",
+			},
+		},
+		Annotations: NewAnnotations([]string{"@route patch /orders/{id}"}),
+	}
+	mm.parseRoute()
+	if mm.HttpMethod != "PATCH" {
+		t.Errorf("HttpMethod = %v, want %v", mm.HttpMethod, "PATCH")
+	}
+	if mm.Path != "/orders/{id}" {
+		t.Errorf("Path = %v, want %v", mm.Path, "/orders/{id}")
+	}
+	if len(mm.PathVars) != 1 || mm.PathVars[0].Type != "int" {
+		t.Errorf("PathVars = %v, want [id int]", mm.PathVars)
+	}
+}
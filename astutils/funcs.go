@@ -24,12 +24,7 @@ func FixImport(src []byte, file string) {
 		res []byte
 		err error
 	)
-	if res, err = imports.Process(file, src, &imports.Options{
-		TabWidth:  8,
-		TabIndent: true,
-		Comments:  true,
-		Fragment:  true,
-	}); err != nil {
+	if res, err = GoImports(src, file); err != nil {
 		panic(err)
 	}
 	err = ioutil.WriteFile(file, res, os.ModePerm)
@@ -38,6 +33,16 @@ func FixImport(src []byte, file string) {
 	}
 }
 
+// GoImports fixes imports and formats src as goimports does, without writing to file
+func GoImports(src []byte, file string) ([]byte, error) {
+	return imports.Process(file, src, &imports.Options{
+		TabWidth:  8,
+		TabIndent: true,
+		Comments:  true,
+		Fragment:  true,
+	})
+}
+
 func GetMethodMeta(spec *ast.FuncDecl) MethodMeta {
 	methodName := ExprString(spec.Name)
 	mm := NewMethodMeta(spec.Type, ExprString)
@@ -241,8 +246,9 @@ type MethodMeta struct {
 	Params []FieldMeta
 	// response
 	Results []FieldMeta
-	// not support when generate client code from service interface in svc.go file
 	// when generate client code from openapi3 spec json file, PathVars is parameters in url as path variable.
+	// when generate code from service interface in svc.go file, PathVars is parameters declared as path variables
+	// by @route or @path annotation.
 	PathVars []FieldMeta
 	// not support when generate client code from service interface in svc.go file
 	// when generate client code from openapi3 spec json file, HeaderVars is parameters in header.
@@ -258,11 +264,17 @@ type MethodMeta struct {
 	Files    []FieldMeta
 	Comments []string
 	// api path
-	// not support when generate client code from service interface in svc.go file
+	// when generate code from service interface in svc.go file, Path is declared by @route annotation, empty if not declared.
 	Path string
 	// not support when generate client code from service interface in svc.go file
 	// when generate client code from openapi3 spec json file, QueryParams is parameters in url as query string.
 	QueryParams *FieldMeta
+	// only support when generate code from service interface in svc.go file
+	// Annotations is parsed from comment lines starting with @
+	Annotations []Annotation
+	// only support when generate code from service interface in svc.go file
+	// HttpMethod is declared by @route annotation, empty if not declared.
+	HttpMethod string
 }
 
 const methodTmpl = `func {{ if .Recv }}(receiver {{.Recv}}){{ end }} {{.Name}}({{- range $i, $p := .Params}}
@@ -153,12 +153,15 @@ func (ic *InterfaceCollector) Collect(n ast.Node) ast.Visitor {
 								})
 							}
 						}
-						methods = append(methods, MethodMeta{
-							Name:     mn,
-							Params:   params,
-							Results:  results,
-							Comments: mComments,
-						})
+						mm := MethodMeta{
+							Name:        mn,
+							Params:      params,
+							Results:     results,
+							Comments:    mComments,
+							Annotations: NewAnnotations(mComments),
+						}
+						mm.parseRoute()
+						methods = append(methods, mm)
 					}
 
 					ic.Interfaces = append(ic.Interfaces, InterfaceMeta{
@@ -33,6 +33,7 @@ var handler bool
 var client string
 var doc bool
 var jsonattrcase string
+var dryRun bool
 
 // httpCmd represents the http command
 var httpCmd = &cobra.Command{
@@ -56,6 +57,7 @@ var httpCmd = &cobra.Command{
 			Doc:          doc,
 			Jsonattrcase: jsonattrcase,
 			Env:          baseUrlEnv,
+			DryRun:       dryRun,
 		}
 		s.Http()
 	},
@@ -78,4 +80,5 @@ func init() {
 	httpCmd.Flags().StringVarP(&jsonattrcase, "case", "", "lowerCamel", `apply to json tag of fields in every generated anonymous struct in handlers. optional values: lowerCamel, snake`)
 	httpCmd.Flags().BoolVarP(&doc, "doc", "", false, `whether generate openapi 3.0 json document or not`)
 	httpCmd.Flags().StringVarP(&baseUrlEnv, "env", "e", "", `base url environment variable name`)
+	httpCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, `print changes to handlerimpl.go and svcimpl.go files as unified diff without writing any file`)
 }
@@ -56,6 +56,7 @@ import (
 )
 
 type {{.Meta.Name}}Client struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -79,15 +80,6 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 	{{- end }}
     {{ $p.Name}} {{$p.Type}}
     {{- end }}) ({{(index $m.Results 0).Name}} {{(index $m.Results 0).Type}}, err error) {
-		var (
-			_server string
-			_err error
-		)
-		if _server, _err = receiver.provider.SelectServer(); _err != nil {
-			err = errors.Wrap(_err, "")
-			return
-		}
-
 		_req := receiver.client.R()
 		_req.SetContext(ctx)
 
@@ -134,13 +126,14 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 			{{- end }}
 		{{- end }}
 
+		{{- $call := "Call" }}
 		{{- range $r := $m.Results }}
 			{{- if eq $r.Type "*os.File" }}
-				_req.SetDoNotParseResponse(true)
+				{{- $call = "CallStream" }}
 			{{- end }}
 		{{- end }}
 
-		_resp, _err := _req.{{$m.Name | restyMethod}}(_server + "{{$m.Path}}")
+		_resp, _err := receiver.{{$call}}(receiver.provider, _req, "{{$m.Name}}", "{{$m.Name | httpMethod}}", "{{$m.Path}}")
 		if _err != nil {
 			err = errors.Wrap(_err, "")
 			return
@@ -243,10 +236,6 @@ func httpMethod(method string) string {
 	return "POST"
 }
 
-func restyMethod(method string) string {
-	return strings.Title(strings.ToLower(httpMethod(method)))
-}
-
 func genGoHttp(paths map[string]v3.Path, svcname, dir, env, pkg string) {
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		panic(err)
@@ -268,7 +257,7 @@ func genGoHttp(paths map[string]v3.Path, svcname, dir, env, pkg string) {
 	funcMap := make(map[string]interface{})
 	funcMap["toCamel"] = strcase.ToCamel
 	funcMap["contains"] = strings.Contains
-	funcMap["restyMethod"] = restyMethod
+	funcMap["httpMethod"] = httpMethod
 	funcMap["toUpper"] = strings.ToUpper
 	tpl, err := template.New("http.go.tmpl").Funcs(funcMap).Parse(httptmpl)
 	if err != nil {
@@ -324,6 +313,13 @@ func api2Interface(paths map[string]v3.Path, svcname string) astutils.InterfaceM
 				logrus.Errorln(err)
 			}
 		}
+		if path.Patch != nil {
+			if method, err := operation2Method(endpoint, "Patch", path.Patch, path.Parameters); err == nil {
+				meta.Methods = append(meta.Methods, method)
+			} else {
+				logrus.Errorln(err)
+			}
+		}
 	}
 	return meta
 }
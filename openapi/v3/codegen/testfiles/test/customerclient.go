@@ -11,6 +11,7 @@ import (
 )
 
 type CustomerClient struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -27,21 +28,12 @@ func (receiver *CustomerClient) GetCustomerValidateToken(ctx context.Context,
 		// required
 		Token string `json:"token,omitempty" url:"token"`
 	}) (ret bool, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
 	_req.SetQueryParamsFromValues(_queryParams)
 
-	_resp, _err := _req.Get(_server + "/customer/validateToken")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetCustomerValidateToken", "GET", "/customer/validateToken")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
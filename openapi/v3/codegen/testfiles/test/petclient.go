@@ -13,6 +13,7 @@ import (
 )
 
 type PetClient struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -31,21 +32,12 @@ func (receiver *PetClient) GetPetFindByTags(ctx context.Context,
 	queryParams struct {
 		Tags []string `json:"tags,omitempty" url:"tags"`
 	}) (ret []Pet, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
 	_req.SetQueryParamsFromValues(_queryParams)
 
-	_resp, _err := _req.Get(_server + "/pet/findByTags")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetPetFindByTags", "GET", "/pet/findByTags")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -67,21 +59,12 @@ func (receiver *PetClient) GetPetFindByStatus(ctx context.Context,
 	queryParams struct {
 		Status string `json:"status,omitempty" url:"status"`
 	}) (ret []Pet, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
 	_req.SetQueryParamsFromValues(_queryParams)
 
-	_resp, _err := _req.Get(_server + "/pet/findByStatus")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetPetFindByStatus", "GET", "/pet/findByStatus")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -106,15 +89,6 @@ func (receiver *PetClient) PostPetPetIdUploadImage(ctx context.Context,
 	// required
 	petId int64,
 	_uploadFile *multipart.FileHeader) (ret ApiResponse, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
@@ -127,7 +101,7 @@ func (receiver *PetClient) PostPetPetIdUploadImage(ctx context.Context,
 	}
 	_req.SetFileReader("_uploadFile", _uploadFile.Filename, _f)
 
-	_resp, _err := _req.Post(_server + "/pet/{petId}/uploadImage")
+	_resp, _err := receiver.Call(receiver.provider, _req, "PostPetPetIdUploadImage", "POST", "/pet/{petId}/uploadImage")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -149,20 +123,11 @@ func (receiver *PetClient) GetPetPetId(ctx context.Context,
 	// ID of pet to return
 	// required
 	petId int64) (ret Pet, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetPathParam("petId", fmt.Sprintf("%v", petId))
 
-	_resp, _err := _req.Get(_server + "/pet/{petId}")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetPetPetId", "GET", "/pet/{petId}")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -182,20 +147,11 @@ func (receiver *PetClient) GetPetPetId(ctx context.Context,
 // Add a new pet to the store
 func (receiver *PetClient) PostPet(ctx context.Context,
 	bodyJson Pet) (ret Pet, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetBody(bodyJson)
 
-	_resp, _err := _req.Post(_server + "/pet")
+	_resp, _err := receiver.Call(receiver.provider, _req, "PostPet", "POST", "/pet")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -215,20 +171,11 @@ func (receiver *PetClient) PostPet(ctx context.Context,
 // Update an existing pet by Id
 func (receiver *PetClient) PutPet(ctx context.Context,
 	bodyJson Pet) (ret Pet, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetBody(bodyJson)
 
-	_resp, _err := _req.Put(_server + "/pet")
+	_resp, _err := receiver.Call(receiver.provider, _req, "PutPet", "PUT", "/pet")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -11,6 +11,7 @@ import (
 )
 
 type StoreClient struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -26,19 +27,10 @@ func (receiver *StoreClient) SetClient(client *resty.Client) {
 // Returns pet inventories by status
 // Returns a map of status codes to quantities
 func (receiver *StoreClient) GetStoreInventory(ctx context.Context) (ret map[string]int, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 
-	_resp, _err := _req.Get(_server + "/store/inventory")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetStoreInventory", "GET", "/store/inventory")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -58,20 +50,11 @@ func (receiver *StoreClient) GetStoreInventory(ctx context.Context) (ret map[str
 // Place a new order in the store
 func (receiver *StoreClient) PostStoreOrder(ctx context.Context,
 	bodyJson Order) (ret Order, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetBody(bodyJson)
 
-	_resp, _err := _req.Post(_server + "/store/order")
+	_resp, _err := receiver.Call(receiver.provider, _req, "PostStoreOrder", "POST", "/store/order")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -93,20 +76,11 @@ func (receiver *StoreClient) GetStoreOrderOrderId(ctx context.Context,
 	// ID of order that needs to be fetched
 	// required
 	orderId int64) (ret Order, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetPathParam("orderId", fmt.Sprintf("%v", orderId))
 
-	_resp, _err := _req.Get(_server + "/store/order/{orderId}")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetStoreOrderOrderId", "GET", "/store/order/{orderId}")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -10,6 +10,7 @@ import (
 )
 
 type UnipayClient struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -32,21 +33,12 @@ func (receiver *UnipayClient) GetUnipayStartUnionPay(ctx context.Context,
 		// required
 		FrontUrl string `json:"frontUrl,omitempty" url:"frontUrl"`
 	}) (ret string, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
 	_req.SetQueryParamsFromValues(_queryParams)
 
-	_resp, _err := _req.Get(_server + "/unipay/startUnionPay")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetUnipayStartUnionPay", "GET", "/unipay/startUnionPay")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -12,6 +12,7 @@ import (
 )
 
 type UserClient struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -29,20 +30,11 @@ func (receiver *UserClient) GetUserUsername(ctx context.Context,
 	// The name that needs to be fetched. Use user1 for testing.
 	// required
 	username string) (ret User, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetPathParam("username", fmt.Sprintf("%v", username))
 
-	_resp, _err := _req.Get(_server + "/user/{username}")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetUserUsername", "GET", "/user/{username}")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -62,20 +54,11 @@ func (receiver *UserClient) GetUserUsername(ctx context.Context,
 // Creates list of users with given input array
 func (receiver *UserClient) PostUserCreateWithList(ctx context.Context,
 	bodyJson []User) (ret User, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_req.SetBody(bodyJson)
 
-	_resp, _err := _req.Post(_server + "/user/createWithList")
+	_resp, _err := receiver.Call(receiver.provider, _req, "PostUserCreateWithList", "POST", "/user/createWithList")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
@@ -97,21 +80,12 @@ func (receiver *UserClient) GetUserLogin(ctx context.Context,
 		Password string `json:"password,omitempty" url:"password"`
 		Username string `json:"username,omitempty" url:"username"`
 	}) (ret string, err error) {
-	var (
-		_server string
-		_err    error
-	)
-	if _server, _err = receiver.provider.SelectServer(); _err != nil {
-		err = errors.Wrap(_err, "")
-		return
-	}
-
 	_req := receiver.client.R()
 	_req.SetContext(ctx)
 	_queryParams, _ := _querystring.Values(queryParams)
 	_req.SetQueryParamsFromValues(_queryParams)
 
-	_resp, _err := _req.Get(_server + "/user/login")
+	_resp, _err := receiver.Call(receiver.provider, _req, "GetUserLogin", "GET", "/user/login")
 	if _err != nil {
 		err = errors.Wrap(_err, "")
 		return
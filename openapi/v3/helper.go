@@ -2,11 +2,14 @@ package v3
 
 import (
 	"encoding/json"
+	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
 	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/validate"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -35,7 +38,94 @@ var SchemaNames []string
 // type uint64
 // type uint8
 // type uintptr
+//
+// Validation rules declared by `validate` struct tag of the field are reflected in the returned schema
+// as minimum, maximum, minLength, maxLength, minItems, maxItems, enum and pattern
 func SchemaOf(field astutils.FieldMeta) *Schema {
+	schema := schemaOf(field)
+	if stringutils.IsEmpty(field.Tag) || stringutils.IsNotEmpty(schema.Ref) {
+		return schema
+	}
+	rules, err := validate.RulesOf(field.Tag)
+	if err != nil {
+		logrus.Warnf("ignore validation rules of field %s: %s", field.Name, err)
+		return schema
+	}
+	if rules.IsEmpty() {
+		return schema
+	}
+	var ret Schema
+	if err = copier.DeepCopy(schema, &ret); err != nil {
+		panic(err)
+	}
+	applyRules(&ret, rules)
+	return &ret
+}
+
+// IsRequired returns true if the field is declared as required by `validate` struct tag
+func IsRequired(field astutils.FieldMeta) bool {
+	rules, err := validate.RulesOf(field.Tag)
+	return err == nil && rules.Required
+}
+
+func applyRules(schema *Schema, rules validate.Rules) {
+	if rules.Min != nil {
+		schema.Minimum = *rules.Min
+	}
+	if rules.Max != nil {
+		schema.Maximum = *rules.Max
+	}
+	if schema.Type == ArrayT {
+		if rules.MinLen != nil {
+			schema.MinItems = *rules.MinLen
+		}
+		if rules.MaxLen != nil {
+			schema.MaxItems = *rules.MaxLen
+		}
+		if schema.Items != nil && (len(rules.Enum) > 0 || stringutils.IsNotEmpty(rules.Pattern)) {
+			// enum and pattern apply to each element
+			items := *schema.Items
+			applyRules(&items, validate.Rules{
+				Enum:    rules.Enum,
+				Pattern: rules.Pattern,
+			})
+			schema.Items = &items
+		}
+		return
+	}
+	if rules.MinLen != nil {
+		schema.MinLength = *rules.MinLen
+	}
+	if rules.MaxLen != nil {
+		schema.MaxLength = *rules.MaxLen
+	}
+	if stringutils.IsNotEmpty(rules.Pattern) {
+		schema.Pattern = rules.Pattern
+	}
+	for _, item := range rules.Enum {
+		schema.Enum = append(schema.Enum, enumValueOf(schema.Type, item))
+	}
+}
+
+func enumValueOf(t Type, value string) interface{} {
+	switch t {
+	case IntegerT:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case NumberT:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case BooleanT:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+func schemaOf(field astutils.FieldMeta) *Schema {
 	ft := strings.TrimPrefix(field.Type, "*")
 	switch ft {
 	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32", "byte", "rune", "complex64", "complex128":
@@ -113,22 +203,27 @@ func CopySchema(field astutils.FieldMeta) Schema {
 
 func NewSchema(structmeta astutils.StructMeta) Schema {
 	properties := make(map[string]*Schema)
+	var required []string
 	for _, field := range structmeta.Fields {
 		fschema := CopySchema(field)
 		fschema.Description = strings.Join(field.Comments, "\n")
 		properties[field.DocName] = &fschema
+		if IsRequired(field) {
+			required = append(required, field.DocName)
+		}
 	}
 	return Schema{
 		Title:       structmeta.Name,
 		Type:        ObjectT,
 		Properties:  properties,
 		Description: strings.Join(structmeta.Comments, "\n"),
+		Required:    required,
 	}
 }
 
 func IsBuiltin(field astutils.FieldMeta) bool {
 	simples := []interface{}{Int, Int64, Bool, String, Float32, Float64}
-	pschema := SchemaOf(field)
+	pschema := schemaOf(field)
 	if pschema == nil {
 		return false
 	}
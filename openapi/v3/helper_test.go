@@ -0,0 +1,78 @@
+package v3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		field astutils.FieldMeta
+		want  Schema
+	}{
+		{
+			name:  "no rules",
+			field: astutils.FieldMeta{Name: "Size", Type: "int", Tag: `json:"size"`},
+			want:  *Int,
+		},
+		{
+			name:  "number",
+			field: astutils.FieldMeta{Name: "Size", Type: "int", Tag: `json:"size" validate:"required,min=1,max=100,enum=10|20"`},
+			want: Schema{
+				Type:    IntegerT,
+				Format:  Int32F,
+				Minimum: float64(1),
+				Maximum: float64(100),
+				Enum:    []interface{}{int64(10), int64(20)},
+			},
+		},
+		{
+			name:  "string",
+			field: astutils.FieldMeta{Name: "Name", Type: "string", Tag: `validate:"minlen=1,maxlen=20,pattern=^[a-z]+$"`},
+			want: Schema{
+				Type:      StringT,
+				MinLength: 1,
+				MaxLength: 20,
+				Pattern:   "^[a-z]+$",
+			},
+		},
+		{
+			name:  "array",
+			field: astutils.FieldMeta{Name: "Tags", Type: "[]string", Tag: `validate:"maxlen=3,enum=a|b"`},
+			want: Schema{
+				Type:     ArrayT,
+				MaxItems: 3,
+				Items: &Schema{
+					Type: StringT,
+					Enum: []interface{}{"a", "b"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SchemaOf(tt.field); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("SchemaOf() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+	if Int.Minimum != nil {
+		t.Error("SchemaOf() should not modify shared schema")
+	}
+}
+
+func TestNewSchemaRequired(t *testing.T) {
+	schema := NewSchema(astutils.StructMeta{
+		Name: "PageQuery",
+		Fields: []astutils.FieldMeta{
+			{Name: "PageNo", Type: "int", Tag: `json:"pageNo" validate:"required"`, DocName: "pageNo"},
+			{Name: "Filter", Type: "string", DocName: "filter"},
+		},
+	})
+	if !reflect.DeepEqual(schema.Required, []string{"pageNo"}) {
+		t.Errorf("NewSchema() required = %v, want %v", schema.Required, []string{"pageNo"})
+	}
+}
@@ -36,12 +36,9 @@ type Tag struct {
 type In string
 
 const (
-	InQuery In = "query"
-	// TODO
-	InPath In = "path"
-	// TODO
+	InQuery  In = "query"
+	InPath   In = "path"
 	InHeader In = "header"
-	// TODO
 	InCookie In = "cookie"
 )
 
@@ -61,12 +58,13 @@ type MediaType struct {
 }
 
 type Content struct {
-	TextPlain *MediaType `json:"text/plain,omitempty"`
-	Json      *MediaType `json:"application/json,omitempty"`
-	FormUrl   *MediaType `json:"application/x-www-form-urlencoded,omitempty"`
-	Stream    *MediaType `json:"application/octet-stream,omitempty"`
-	FormData  *MediaType `json:"multipart/form-data,omitempty"`
-	Default   *MediaType `json:"*/*,omitempty"`
+	TextPlain   *MediaType `json:"text/plain,omitempty"`
+	Json        *MediaType `json:"application/json,omitempty"`
+	FormUrl     *MediaType `json:"application/x-www-form-urlencoded,omitempty"`
+	Stream      *MediaType `json:"application/octet-stream,omitempty"`
+	FormData    *MediaType `json:"multipart/form-data,omitempty"`
+	EventStream *MediaType `json:"text/event-stream,omitempty"`
+	Default     *MediaType `json:"*/*,omitempty"`
 }
 
 type Parameter struct {
@@ -147,10 +145,13 @@ type Operation struct {
 }
 
 type Path struct {
-	Get    *Operation `json:"get,omitempty"`
-	Post   *Operation `json:"post,omitempty"`
-	Put    *Operation `json:"put,omitempty"`
-	Delete *Operation `json:"delete,omitempty"`
+	Get     *Operation `json:"get,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
 	// TODO
 	Parameters []Parameter `json:"parameters,omitempty"`
 }
@@ -183,6 +184,8 @@ type Schema struct {
 	ExclusiveMinimum interface{}        `json:"exclusiveMinimum,omitempty"`
 	MaxLength        int                `json:"maxLength,omitempty"`
 	MinLength        int                `json:"minLength,omitempty"`
+	MaxItems         int                `json:"maxItems,omitempty"`
+	MinItems         int                `json:"minItems,omitempty"`
 	Required         []string           `json:"required,omitempty"`
 	Enum             []interface{}      `json:"enum,omitempty"`
 	AllOf            []*Schema          `json:"allOf,omitempty"`
@@ -282,4 +285,21 @@ var (
 		Type:  ArrayT,
 		Items: File,
 	}
+	// ErrorEnvelope is schema of json body of error responses from go-doudou services, see ddhttp.ErrorEnvelope
+	ErrorEnvelope = &Schema{
+		Type:  ObjectT,
+		Title: "ErrorEnvelope",
+		Properties: map[string]*Schema{
+			"error": {
+				Type: ObjectT,
+				Properties: map[string]*Schema{
+					"code":    Int,
+					"message": String,
+					"details": Any,
+				},
+				Required: []string{"code", "message"},
+			},
+		},
+		Required: []string{"error"},
+	}
 )
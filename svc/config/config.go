@@ -1,8 +1,12 @@
 package config
 
 import (
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type envVariable string
@@ -18,13 +22,61 @@ const (
 	GddIdleTimeout   envVariable = "GDD_IDLETIMEOUT"
 	GddOutput        envVariable = "GDD_OUTPUT"
 	GddRouteRootPath envVariable = "GDD_ROUTE_ROOT_PATH"
+	// GddRouter accepts 'chi' for go-chi/chi based http server, otherwise gorilla/mux based http server is used
+	GddRouter envVariable = "GDD_ROUTER"
+	// GddLogFormat accepts 'json' for json logs, otherwise logs are text
+	GddLogFormat envVariable = "GDD_LOG_FORMAT"
+	// GddTracingOutput accepts 'stdout' or a file path to export spans as json lines, spans are not exported if empty
+	GddTracingOutput envVariable = "GDD_TRACING_OUTPUT"
+	// GddTLSCert is the certificate file of the server, it is also used as the client certificate of clients created by
+	// ddhttp.NewClient. The server serves https if both GddTLSCert and GddTLSKey are set
+	GddTLSCert envVariable = "GDD_TLS_CERT"
+	// GddTLSKey is the private key file of GddTLSCert
+	GddTLSKey envVariable = "GDD_TLS_KEY"
+	// GddTLSCA is the CA certificate file to verify client certificates by the server and server certificates by clients
+	GddTLSCA envVariable = "GDD_TLS_CA"
+	// GddTLSClientAuth accepts 'require' to require client certificates verified by GddTLSCA, or 'verify' to verify them
+	// only if given, otherwise client certificates are not requested
+	GddTLSClientAuth envVariable = "GDD_TLS_CLIENT_AUTH"
+	// GddCorsAllowedOrigins is comma separated origins allowed by ddhttp.Cors middleware, which supports * as wildcard,
+	// e.g. https://*.example.com,http://localhost:*. CORS is disabled if empty
+	GddCorsAllowedOrigins envVariable = "GDD_CORS_ALLOWED_ORIGINS"
+	// GddCorsAllowedMethods is comma separated methods allowed by preflight requests, GET,POST,PUT,PATCH,DELETE,HEAD by default
+	GddCorsAllowedMethods envVariable = "GDD_CORS_ALLOWED_METHODS"
+	// GddCorsAllowedHeaders is comma separated headers allowed by preflight requests, headers requested by browsers are
+	// allowed if empty
+	GddCorsAllowedHeaders envVariable = "GDD_CORS_ALLOWED_HEADERS"
+	// GddCorsExposedHeaders is comma separated response headers readable by browsers besides CORS-safelisted ones
+	GddCorsExposedHeaders envVariable = "GDD_CORS_EXPOSED_HEADERS"
+	// GddCorsAllowCredentials accepts 'true' to allow cookies and authorization headers in cross origin requests
+	GddCorsAllowCredentials envVariable = "GDD_CORS_ALLOW_CREDENTIALS"
+	// GddCorsMaxAge is how long browsers cache preflight results, e.g. 10m
+	GddCorsMaxAge envVariable = "GDD_CORS_MAX_AGE"
+	// GddPrometheusBuckets is comma separated buckets in seconds of latency histograms exposed by /go-doudou/prometheus,
+	// e.g. 0.01,0.05,0.1,0.5,1. Default buckets of prometheus client are used if empty
+	GddPrometheusBuckets envVariable = "GDD_PROMETHEUS_BUCKETS"
+	// GddMaxBody is the max size of request bodies enforced by ddhttp.BodyLimit middleware, e.g. 32MB, which is overridden
+	// by @maxbody annotations of methods in svc.go. Bodies are not limited if it is 0, and 32MB is used if it is empty
+	GddMaxBody envVariable = "GDD_MAX_BODY"
+	// GddReadHeaderTimeout is how long the server waits for request headers, e.g. 5s, GDD_READTIMEOUT is used if empty
+	GddReadHeaderTimeout envVariable = "GDD_READ_HEADER_TIMEOUT"
+	// GddMaxHeaderBytes is the max size of request headers including the request line, e.g. 1MB, 1MB by default
+	GddMaxHeaderBytes envVariable = "GDD_MAX_HEADER_BYTES"
+	// GddShutdownDelay is how long the service keeps serving after readiness checks start failing on shutdown, e.g. 5s,
+	// so that load balancers like kubernetes stop routing requests to it in time. It is bounded by GDD_GRACETIMEOUT
+	GddShutdownDelay envVariable = "GDD_SHUTDOWN_DELAY"
+	// GddStreamTimeout is how long each write of event and byte streams may take, e.g. 15s, instead of GDD_WRITETIMEOUT
+	// limiting the whole response. GDD_WRITETIMEOUT is used if empty, and writes of streams never time out if 0
+	GddStreamTimeout envVariable = "GDD_STREAM_TIMEOUT"
 
 	GddName     envVariable = "GDD_NAME"
 	GddHostname envVariable = "GDD_HOSTNAME"
 	GddPort     envVariable = "GDD_PORT"
 	GddMemPort  envVariable = "GDD_MEM_PORT"
 	GddBaseUrl  envVariable = "GDD_BASE_URL"
-	GddSeed     envVariable = "GDD_SEED"
+	// GddWeight is weight of the node for weighted round robin load balancing of clients, 1 by default
+	GddWeight envVariable = "GDD_WEIGHT"
+	GddSeed   envVariable = "GDD_SEED"
 	// Accept 'mono' for monolith mode or 'micro' for microservice mode
 	GddMode envVariable = "GDD_MODE"
 	// GddManage if true, it will add built-in apis with /go-doudou path prefix for online api document and service status monitor etc.
@@ -73,3 +125,38 @@ func (ll *LogLevel) Decode(value string) error {
 	}
 	return nil
 }
+
+// ByteSize is size in bytes decoded from values like 512, 64KB, 10MB or 1GB, units are case insensitive and the B suffix
+// can be omitted, e.g. 10m. K, M and G are multiples of 1024
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+func (bs *ByteSize) Decode(value string) error {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	unit := float64(1)
+	for _, item := range byteUnits {
+		if strings.HasSuffix(s, item.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, item.suffix))
+			unit = item.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || n < 0 || n*unit > math.MaxInt64 {
+		return errors.Errorf("invalid byte size %q, it should be like 512, 64KB, 10MB or 1GB", value)
+	}
+	*bs = ByteSize(n * unit)
+	return nil
+}
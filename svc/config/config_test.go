@@ -142,3 +142,30 @@ func TestLogLevel_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestByteSize_Decode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"64KB", 64 << 10, false},
+		{"10mb", 10 << 20, false},
+		{"1.5M", 3 << 19, false},
+		{" 1 GB ", 1 << 30, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"-1MB", 0, true},
+		{"10TB", 0, true},
+	}
+	for _, tt := range tests {
+		var bs ByteSize
+		if err := bs.Decode(tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("Decode(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if bs != tt.want {
+			t.Errorf("Decode(%q) = %d, want %d", tt.value, bs, tt.want)
+		}
+	}
+}
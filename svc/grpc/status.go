@@ -0,0 +1,136 @@
+package ddgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var httpToCode = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	499:                            codes.Canceled,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+var codeToHttp = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+// CodeOf returns grpc status code corresponding to http status code
+func CodeOf(httpStatus int) codes.Code {
+	if code, ok := httpToCode[httpStatus]; ok {
+		return code
+	}
+	switch {
+	case httpStatus >= 200 && httpStatus < 300:
+		return codes.OK
+	case httpStatus >= 400 && httpStatus < 500:
+		return codes.FailedPrecondition
+	default:
+		return codes.Unknown
+	}
+}
+
+// HttpStatusOf returns http status code corresponding to grpc status code
+func HttpStatusOf(code codes.Code) int {
+	if status, ok := codeToHttp[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ToStatus converts error returned by service implementation to grpc status error.
+// Errors which are already grpc status errors are returned as they are. The others are converted by ddhttp.ToHttpError,
+// then grpc status code is mapped from http status code, and application defined error code and details
+// are kept in status details, so that they can be decoded back by FromStatus.
+// context.DeadlineExceeded is converted to codes.DeadlineExceeded
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	herr := ddhttp.ToHttpError(err)
+	st := status.New(CodeOf(herr.Status), herr.Message)
+	fields := map[string]interface{}{
+		"code": herr.Code,
+	}
+	if herr.Details != nil {
+		// details should be json compatible values for structpb
+		var details interface{}
+		if b, err := json.Marshal(herr.Details); err == nil && json.Unmarshal(b, &details) == nil {
+			fields["details"] = details
+		}
+	}
+	if s, err := structpb.NewStruct(fields); err == nil {
+		if withDetails, err := st.WithDetails(s); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+// FromStatus converts grpc status error returned by grpc client to *ddhttp.HttpError.
+// Http status code is mapped from grpc status code, and application defined error code and details
+// are decoded from status details set by ToStatus. Errors which are not grpc status errors are returned as they are
+func FromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	httpStatus := HttpStatusOf(st.Code())
+	herr := ddhttp.NewHttpError(httpStatus, httpStatus, st.Message())
+	for _, item := range st.Details() {
+		s, ok := item.(*structpb.Struct)
+		if !ok {
+			continue
+		}
+		fields := s.AsMap()
+		if code, ok := fields["code"].(float64); ok {
+			herr.Code = int(code)
+		}
+		if details, ok := fields["details"]; ok {
+			herr.Details = details
+		}
+		break
+	}
+	return herr
+}
@@ -0,0 +1,64 @@
+package ddgrpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"nil", nil, codes.OK},
+		{"http error", ddhttp.NewHttpError(http.StatusNotFound, 10001, "user not found"), codes.NotFound},
+		{"wrapped http error", errors.Wrap(ddhttp.NewHttpError(http.StatusUnauthorized, 401, "no token"), "wrapped"), codes.Unauthenticated},
+		{"other error", errors.New("boom"), codes.Internal},
+		{"canceled", context.Canceled, codes.InvalidArgument},
+		{"deadline exceeded", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"status", status.Error(codes.Aborted, "aborted"), codes.Aborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(ToStatus(tt.err)); got != tt.want {
+				t.Errorf("ToStatus() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	err := ToStatus(ddhttp.NewHttpError(http.StatusBadRequest, 10002, "validation failed").
+		WithDetails([]map[string]string{{"field": "name", "rule": "required"}}))
+	got, ok := FromStatus(err).(*ddhttp.HttpError)
+	if !ok {
+		t.Fatalf("FromStatus() should return *ddhttp.HttpError, got %T", FromStatus(err))
+	}
+	want := &ddhttp.HttpError{
+		Status:  http.StatusBadRequest,
+		Code:    10002,
+		Message: "validation failed",
+		Details: []interface{}{map[string]interface{}{"field": "name", "rule": "required"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromStatus() got = %+v, want %+v", got, want)
+	}
+
+	got, _ = FromStatus(status.Error(codes.Unavailable, "no connection")).(*ddhttp.HttpError)
+	if got == nil || got.Status != http.StatusServiceUnavailable || got.Code != http.StatusServiceUnavailable {
+		t.Errorf("FromStatus() got = %+v, want status 503", got)
+	}
+
+	plain := errors.New("plain")
+	if FromStatus(plain) != plain {
+		t.Error("FromStatus() should return non-status error as it is")
+	}
+}
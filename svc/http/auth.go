@@ -0,0 +1,261 @@
+package ddhttp
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+// Principal is the authenticated user or client of a request
+type Principal struct {
+	// Subject identifies the user or client, e.g. sub claim of jwt
+	Subject string
+	Roles   []string
+	// Claims are claims of jwt, or any attributes set by stores
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal has any of roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if sliceutils.StringContains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalCtx struct{}
+
+// WithPrincipal returns copy of ctx carrying principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalCtx{}, principal)
+}
+
+// PrincipalFrom returns the principal attached by Auth middleware. Generated http handlers pass request context
+// into service methods, so service implementations can get the principal by PrincipalFrom(ctx)
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalCtx{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Authenticator authenticates requests by one kind of credentials. It returns nil principal and nil error
+// if the request doesn't carry credentials of its kind, so that the next authenticator is tried.
+// Returned errors are responded as 401 unless they are *HttpError, e.g. 503 if the user store is unavailable
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts a function to Authenticator
+type AuthenticatorFunc func(r *http.Request) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return f(r)
+}
+
+// Challenger is optionally implemented by Authenticator to set WWW-Authenticate header of 401 responses
+type Challenger interface {
+	Challenge() string
+}
+
+// ErrInvalidCredentials is returned by built-in authenticators if credentials are present but invalid
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// UserStore verifies user name and password, e.g. against users table in database.
+// It returns ErrInvalidCredentials if they don't match
+type UserStore interface {
+	Authenticate(ctx context.Context, username, password string) (*Principal, error)
+}
+
+// BasicAuthenticator authenticates requests by http basic auth against a UserStore
+type BasicAuthenticator struct {
+	store UserStore
+	realm string
+}
+
+// NewBasicAuthenticator creates BasicAuthenticator, realm is shown by browsers in the login dialog
+func NewBasicAuthenticator(store UserStore, realm string) *BasicAuthenticator {
+	return &BasicAuthenticator{
+		store: store,
+		realm: realm,
+	}
+}
+
+func (b *BasicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	return b.store.Authenticate(r.Context(), username, password)
+}
+
+func (b *BasicAuthenticator) Challenge() string {
+	return `Basic realm="` + b.realm + `"`
+}
+
+// APIKeyStore finds the principal owning the api key. It returns ErrInvalidCredentials if the key is unknown
+type APIKeyStore interface {
+	Lookup(ctx context.Context, key string) (*Principal, error)
+}
+
+// StaticAPIKeys is APIKeyStore of fixed keys, e.g. loaded from config at startup
+type StaticAPIKeys map[string]*Principal
+
+func (s StaticAPIKeys) Lookup(ctx context.Context, key string) (*Principal, error) {
+	for k, principal := range s {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return principal, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// APIKeyAuthenticator authenticates requests by X-API-Key header, or api_key query parameter if the header is absent
+type APIKeyAuthenticator struct {
+	store APIKeyStore
+}
+
+func NewAPIKeyAuthenticator(store APIKeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		store: store,
+	}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := ByAPIKey(r)
+	if stringutils.IsEmpty(key) {
+		return nil, nil
+	}
+	return a.store.Lookup(r.Context(), key)
+}
+
+type routePolicy struct {
+	public bool
+	roles  []string
+}
+
+// Auth is a middleware authenticating requests by authenticators in order, and authorizing them by policies of routes.
+// Routes require authentication unless set public by WithPublicRoutes
+type Auth struct {
+	authenticators []Authenticator
+	policies       map[string]*routePolicy
+}
+
+type AuthOption func(*Auth)
+
+// WithAuthenticators appends authenticators tried in order, the first one returning a principal or an error wins
+func WithAuthenticators(authenticators ...Authenticator) AuthOption {
+	return func(a *Auth) {
+		a.authenticators = append(a.authenticators, authenticators...)
+	}
+}
+
+// WithPublicRoutes lets anonymous requests through the routes named as RouteName returns.
+// Requests with valid credentials still get the principal
+func WithPublicRoutes(routes ...string) AuthOption {
+	return func(a *Auth) {
+		for _, route := range routes {
+			a.policyOf(route).public = true
+		}
+	}
+}
+
+// WithRouteRoles requires principals of the route to have any of roles, otherwise 403 is responded
+func WithRouteRoles(route string, roles ...string) AuthOption {
+	return func(a *Auth) {
+		policy := a.policyOf(route)
+		policy.roles = append(policy.roles, roles...)
+	}
+}
+
+// NewAuth creates Auth. Generated AuthOptions function in transport/httpsrv returns options declared by @public
+// and @role annotations in svc.go
+func NewAuth(opts ...AuthOption) *Auth {
+	a := &Auth{
+		policies: make(map[string]*routePolicy),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *Auth) policyOf(route string) *routePolicy {
+	policy, ok := a.policies[route]
+	if !ok {
+		policy = &routePolicy{}
+		a.policies[route] = policy
+	}
+	return policy
+}
+
+func (a *Auth) authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil || principal != nil {
+			return principal, err
+		}
+	}
+	return nil, nil
+}
+
+func (a *Auth) challenge(w http.ResponseWriter) {
+	for _, authenticator := range a.authenticators {
+		if c, ok := authenticator.(Challenger); ok {
+			w.Header().Add("WWW-Authenticate", c.Challenge())
+		}
+	}
+}
+
+// Middleware responds 401 if the request is not authenticated, and 403 if the principal lacks roles required by the route.
+// Built-in apis under /go-doudou are skipped as they are protected by GDD_MANAGE_USER and GDD_MANAGE_PASS
+func (a *Auth) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, config.GddRouteRootPath.Load()+gddPathPrefix+"/") {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		route := RouteName(r)
+		policy, ok := a.policies[route]
+		if !ok {
+			policy = &routePolicy{}
+		}
+		principal, err := a.authenticate(r)
+		if policy.public {
+			if err == nil && principal != nil {
+				r = r.WithContext(WithPrincipal(r.Context(), principal))
+			}
+			inner.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			var herr *HttpError
+			if !errors.As(err, &herr) {
+				logrus.Debugf("authenticate request of route %s failed: %v", route, err)
+				herr = NewHttpError(http.StatusUnauthorized, http.StatusUnauthorized, "invalid credentials")
+			}
+			if herr.Status == http.StatusUnauthorized {
+				a.challenge(w)
+			}
+			HandleError(w, herr)
+			return
+		}
+		if principal == nil {
+			a.challenge(w)
+			HandleError(w, NewHttpError(http.StatusUnauthorized, http.StatusUnauthorized, "authentication required"))
+			return
+		}
+		if len(policy.roles) > 0 && !principal.HasRole(policy.roles...) {
+			HandleError(w, NewHttpError(http.StatusForbidden, http.StatusForbidden, "permission denied"))
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
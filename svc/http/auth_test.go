@@ -0,0 +1,249 @@
+package ddhttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hs256Token(secret []byte, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, file string, keys map[string]*rsa.PrivateKey) {
+	var jwks []map[string]string
+	for kid, key := range keys {
+		jwks = append(jwks, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	b, _ := json.Marshal(map[string]interface{}{"keys": jwks})
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type users map[string]string
+
+func (u users) Authenticate(ctx context.Context, username, password string) (*Principal, error) {
+	if pass, ok := u[username]; ok && pass == password {
+		return &Principal{Subject: username}, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	secret := []byte("secret")
+	j, err := NewJWTAuthenticator(WithHMACSecret(secret), WithJWTIssuer("issuer"), WithJWTAudience("usersvc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "jack", "iss": "issuer", "aud": []string{"usersvc"}, "exp": now + 60, "roles": []string{"admin"}}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", hs256Token(secret, valid), false},
+		{"wrong secret", hs256Token([]byte("wrong"), valid), true},
+		{"expired", hs256Token(secret, map[string]interface{}{"iss": "issuer", "aud": "usersvc", "exp": now - 120}), true},
+		{"not valid yet", hs256Token(secret, map[string]interface{}{"iss": "issuer", "aud": "usersvc", "nbf": now + 120}), true},
+		{"wrong issuer", hs256Token(secret, map[string]interface{}{"iss": "other", "aud": "usersvc"}), true},
+		{"wrong audience", hs256Token(secret, map[string]interface{}{"iss": "issuer", "aud": "ordersvc"}), true},
+		{"none algorithm", encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(valid) + ".", true},
+		{"malformed", "abc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/users/1", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			principal, err := j.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (principal.Subject != "jack" || !principal.HasRole("admin")) {
+				t.Errorf("Authenticate() = %+v", principal)
+			}
+		})
+	}
+	principal, err := j.Authenticate(httptest.NewRequest("GET", "/users/1", nil))
+	if principal != nil || err != nil {
+		t.Error("request without token should be left to other authenticators")
+	}
+}
+
+func TestJWTAuthenticator_JWKS(t *testing.T) {
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, file, map[string]*rsa.PrivateKey{"key1": key1})
+	j, err := NewJWTAuthenticator(WithJWKSFile(file), WithJWTRolesClaim("scope"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate := func(token string) (*Principal, error) {
+		req := httptest.NewRequest("GET", "/users/1", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		return j.Authenticate(req)
+	}
+	principal, err := authenticate(rs256Token(t, key1, "key1", map[string]interface{}{"sub": "jack", "scope": "read write"}))
+	if err != nil || !principal.HasRole("write") {
+		t.Fatalf("Authenticate() = %+v, %v", principal, err)
+	}
+	if _, err = authenticate(rs256Token(t, key2, "key1", nil)); err == nil {
+		t.Error("token signed by another key should be rejected")
+	}
+	if _, err = authenticate(rs256Token(t, key2, "key2", nil)); err == nil {
+		t.Error("token of unknown key should be rejected")
+	}
+	// an RS token re-signed as HS by the public key must not pass
+	if _, err = authenticate(hs256Token(key1.N.Bytes(), nil)); err == nil {
+		t.Error("HS token should be rejected without hmac secret")
+	}
+
+	// rotate keys
+	writeJWKS(t, file, map[string]*rsa.PrivateKey{"key1": key1, "key2": key2})
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(file, future, future)
+	if _, err = authenticate(rs256Token(t, key2, "key2", nil)); err != nil {
+		t.Errorf("token of rotated key should be accepted, got %v", err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddManage.String():     "true",
+		config.GddManageUser.String(): "",
+		config.GddManagePass.String(): "",
+	})
+	secret := []byte("secret")
+	j, err := NewJWTAuthenticator(WithHMACSecret(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuth(
+		WithAuthenticators(
+			j,
+			NewAPIKeyAuthenticator(StaticAPIKeys{"key1": {Subject: "batch", Roles: []string{"admin"}}}),
+			NewBasicAuthenticator(users{"jack": "123"}, "usersvc"),
+		),
+		WithPublicRoutes("GetUser"),
+		WithRouteRoles("DeleteUser", "admin"),
+	)
+	routes := append(testRoutes(), model.Route{
+		Name:    "DeleteUser",
+		Method:  "DELETE",
+		Pattern: "/users/{id:[0-9]+}",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := PrincipalFrom(r.Context())
+			w.Write([]byte("deleted by " + principal.Subject))
+		},
+	})
+	routes[0].HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := PrincipalFrom(r.Context()); ok {
+			w.Write([]byte("hello " + principal.Subject))
+			return
+		}
+		w.Write([]byte("hello anonymous"))
+	}
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		header        map[string]string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{"public anonymous", "GET", "/users/1", nil, http.StatusOK, "hello anonymous", ""},
+		{"public with credentials", "GET", "/users/1", map[string]string{"X-API-Key": "key1"}, http.StatusOK, "hello batch", ""},
+		{"public with invalid credentials", "GET", "/users/1", map[string]string{"X-API-Key": "wrong"}, http.StatusOK, "hello anonymous", ""},
+		{"anonymous", "POST", "/users", nil, http.StatusUnauthorized, "authentication required", `Bearer, Basic realm="usersvc"`},
+		{"invalid token", "POST", "/users", map[string]string{"Authorization": "Bearer abc"}, http.StatusUnauthorized, "invalid credentials", `Bearer, Basic realm="usersvc"`},
+		{"jwt", "POST", "/users", map[string]string{"Authorization": "Bearer " + hs256Token(secret, map[string]interface{}{"sub": "jack"})}, http.StatusOK, "users", ""},
+		{"basic auth", "POST", "/users", map[string]string{"Authorization": "Basic amFjazoxMjM="}, http.StatusOK, "users", ""},
+		{"wrong password", "POST", "/users", map[string]string{"Authorization": "Basic amFjazo0NTY="}, http.StatusUnauthorized, "invalid credentials", ""},
+		{"without role", "DELETE", "/users/1", map[string]string{"Authorization": "Basic amFjazoxMjM="}, http.StatusForbidden, "permission denied", ""},
+		{"with role", "DELETE", "/users/1", map[string]string{"X-API-Key": "key1"}, http.StatusOK, "deleted by batch", ""},
+		{"manage api", "GET", "/go-doudou/health", nil, http.StatusOK, `"status":"up"`, ""},
+	}
+	srvs := map[string]func() Srv{
+		"gorilla": NewDefaultHttpSrv,
+		"chi":     NewChiHttpSrv,
+	}
+	for name, newSrv := range srvs {
+		srv := newSrv()
+		srv.AddMiddleware(auth.Middleware)
+		srv.AddRoute(routes...)
+		handler := srv.(http.Handler)
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				for k, v := range tt.header {
+					req.Header.Set(k, v)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+				}
+				if !strings.Contains(rec.Body.String(), tt.wantBody) {
+					t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+				}
+				if tt.wantChallenge != "" && strings.Join(rec.Header().Values("WWW-Authenticate"), ", ") != tt.wantChallenge {
+					t.Errorf("WWW-Authenticate = %v, want %s", rec.Header().Values("WWW-Authenticate"), tt.wantChallenge)
+				}
+			})
+		}
+	}
+}
+
+func TestAuth_HttpError(t *testing.T) {
+	auth := NewAuth(WithAuthenticators(AuthenticatorFunc(func(r *http.Request) (*Principal, error) {
+		return nil, NewHttpError(http.StatusServiceUnavailable, 10001, "user store unavailable")
+	})))
+	rec := httptest.NewRecorder()
+	auth.Middleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "10001") {
+		t.Errorf("got %d %s, want 503 with application error code", rec.Code, rec.Body.String())
+	}
+}
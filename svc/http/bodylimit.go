@@ -0,0 +1,107 @@
+package ddhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+// DefaultMaxBody is the max size of request bodies if GDD_MAX_BODY is empty
+const DefaultMaxBody = 32 << 20
+
+// MaxMultipartMemory is the max bytes of multipart forms parsed into memory by generated handlers,
+// the rest of uploaded files are stored in temporary files. Total size of the form is limited by BodyLimit
+var MaxMultipartMemory int64 = 32 << 20
+
+// errBodyTooLarge is the error message of bodies wrapped by http.MaxBytesReader once they are read beyond the limit
+const errBodyTooLarge = "http: request body too large"
+
+// isBodyTooLarge reports whether err is returned from reading a body beyond the limit set by BodyLimit
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errBodyTooLarge)
+}
+
+// BodyLimit is a middleware limiting size of request bodies by http.MaxBytesReader. Requests with larger Content-Length
+// get 413 at once, and reading chunked bodies beyond the limit fails, which handlers report by HandleBadRequest as 413
+type BodyLimit struct {
+	max    int64
+	routes map[string]int64
+}
+
+type BodyLimitOption func(*BodyLimit)
+
+// WithMaxBody sets max size of request bodies of routes without their own limits, bodies are not limited if max is 0
+func WithMaxBody(max int64) BodyLimitOption {
+	return func(b *BodyLimit) {
+		b.max = max
+	}
+}
+
+// WithRouteMaxBody sets max size of request bodies of the route named as RouteName returns
+func WithRouteMaxBody(route string, max int64) BodyLimitOption {
+	return func(b *BodyLimit) {
+		b.routes[route] = max
+	}
+}
+
+// NewBodyLimit creates BodyLimit with max size set by GDD_MAX_BODY, which is overridden by opts. Generated BodyLimitOptions
+// function in transport/httpsrv returns options declared by @maxbody annotations in svc.go
+func NewBodyLimit(opts ...BodyLimitOption) *BodyLimit {
+	b := &BodyLimit{
+		max:    maxBody(),
+		routes: make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// maxBody returns max size of request bodies set by GDD_MAX_BODY, or DefaultMaxBody if it is empty or invalid
+func maxBody() int64 {
+	value := config.GddMaxBody.Load()
+	if stringutils.IsEmpty(value) {
+		return DefaultMaxBody
+	}
+	var max config.ByteSize
+	if err := max.Decode(value); err != nil {
+		logrus.Warnf("Parse %s %s as byte size failed: %s, use default 32MB instead.\n", "GDD_MAX_BODY", value, err.Error())
+		return DefaultMaxBody
+	}
+	return int64(max)
+}
+
+type bodyLimitCtx struct{}
+
+// bodyLimitOf returns max size of the request body set by BodyLimit, ok is false if BodyLimit is not applied to r
+func bodyLimitOf(r *http.Request) (max int64, ok bool) {
+	max, ok = r.Context().Value(bodyLimitCtx{}).(int64)
+	return
+}
+
+func (b *BodyLimit) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := b.max
+		if limit, ok := b.routes[RouteName(r)]; ok {
+			max = limit
+		}
+		// middlewares reading bodies like Idempotency follow the limit of the route
+		r = r.WithContext(context.WithValue(r.Context(), bodyLimitCtx{}, max))
+		if max <= 0 || r.Body == nil || r.Body == http.NoBody {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength > max {
+			HandleError(w, NewHttpError(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body is larger than %d bytes", max)))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		inner.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,83 @@
+package ddhttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+func TestBodyLimit(t *testing.T) {
+	var calls int
+	read := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			HandleBadRequest(w, err)
+			return
+		}
+		w.Write(body)
+	}
+	routes := testRoutes()
+	routes[1].HandlerFunc = read
+	routes = append(routes, model.Route{
+		Name:        "Upload",
+		Method:      "POST",
+		Pattern:     "/upload",
+		HandlerFunc: read,
+	})
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(NewBodyLimit(WithMaxBody(10), WithRouteMaxBody("Upload", 100)).Middleware)
+	srv.AddRoute(routes...)
+	post := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		srv.(http.Handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/users", "small", false); rec.Code != http.StatusOK || rec.Body.String() != "small" {
+		t.Errorf("got %d %s", rec.Code, rec.Body.String())
+	}
+	rec := post("/users", strings.Repeat("a", 20), false)
+	if rec.Code != http.StatusRequestEntityTooLarge || calls != 1 {
+		t.Errorf("request with large Content-Length should get 413 before the handler, got %d after %d calls", rec.Code, calls)
+	}
+	rec = post("/users", strings.Repeat("a", 20), true)
+	var envelope ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil || rec.Code != http.StatusRequestEntityTooLarge ||
+		envelope.Error.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("reading chunked body beyond the limit should get 413, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = post("/upload", strings.Repeat("a", 50), false); rec.Code != http.StatusOK {
+		t.Errorf("route limit should override the default one, got %d", rec.Code)
+	}
+}
+
+func TestNewBodyLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", DefaultMaxBody},
+		{"1KB", 1 << 10},
+		{"0", 0},
+		{"large", DefaultMaxBody},
+	}
+	for _, tt := range tests {
+		setEnv(t, map[string]string{
+			config.GddMaxBody.String(): tt.value,
+		})
+		if got := NewBodyLimit().max; got != tt.want {
+			t.Errorf("max of GDD_MAX_BODY %q = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package ddhttp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned by generated clients when the circuit of the selected server is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuit struct {
+	failures int
+	// openedAt is zero if the circuit is closed, otherwise the time it opened or the last trial request was let through
+	openedAt time.Time
+}
+
+// CircuitBreaker tracks failures of each server by its base url.
+// The circuit of a server opens after threshold consecutive failures, and no request will be sent to it
+// until openTimeout elapsed. Then a trial request is let through, the circuit closes if it succeeds, otherwise opens again.
+// The same CircuitBreaker can be passed to both DdClient by WithCircuitBreaker and MemberlistServiceProvider by WithBreaker,
+// so that servers with open circuit are skipped when selecting servers.
+type CircuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	mu          sync.Mutex
+	circuits    map[string]*circuit
+}
+
+// NewCircuitBreaker creates a CircuitBreaker. threshold defaults to 5 and openTimeout defaults to 30 seconds if not positive
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if openTimeout <= 0 {
+		openTimeout = 30 * time.Second
+	}
+	return &CircuitBreaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		circuits:    make(map[string]*circuit),
+	}
+}
+
+// Available reports whether a request to server would be allowed without changing state of the circuit
+func (b *CircuitBreaker) Available(server string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[server]
+	return !ok || c.openedAt.IsZero() || time.Since(c.openedAt) >= b.openTimeout
+}
+
+// available returns servers whose circuits are not open, or all servers if none is available
+func (b *CircuitBreaker) available(servers []Server) []Server {
+	var result []Server
+	for _, server := range servers {
+		if b.Available(server.BaseUrl) {
+			result = append(result, server)
+		}
+	}
+	if len(result) == 0 {
+		return servers
+	}
+	return result
+}
+
+// Allow reports whether a request can be sent to server. It lets one trial request through
+// each openTimeout while the circuit is open
+func (b *CircuitBreaker) Allow(server string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[server]
+	if !ok || c.openedAt.IsZero() {
+		return true
+	}
+	if time.Since(c.openedAt) >= b.openTimeout {
+		c.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// Success closes the circuit of server
+func (b *CircuitBreaker) Success(server string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, server)
+}
+
+// Failure records a failed request to server, and opens the circuit if failures reach the threshold or the trial request failed
+func (b *CircuitBreaker) Failure(server string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[server]
+	if !ok {
+		c = &circuit{}
+		b.circuits[server] = c
+	}
+	c.failures++
+	if !c.openedAt.IsZero() || c.failures >= b.threshold {
+		c.openedAt = time.Now()
+	}
+}
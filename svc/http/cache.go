@@ -0,0 +1,353 @@
+package ddhttp
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+)
+
+// maxETagBodySize is the max size of response bodies buffered to compute ETag, larger responses are written through
+const maxETagBodySize = 1 << 20
+
+// ResponseCache is an in-process LRU cache of responses of GET routes, entries expire by max-age or s-maxage
+// of Cache-Control of their routes
+type ResponseCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type cachedResponse struct {
+	key     string
+	route   string
+	status  int
+	header  http.Header
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// NewResponseCache creates ResponseCache holding size responses at most, the least recently used one is evicted
+// when it is full
+func NewResponseCache(size int) *ResponseCache {
+	return &ResponseCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// DefaultResponseCache is the ResponseCache invalidated by InvalidateCache, use it by ddhttp.WithResponseCache(ddhttp.DefaultResponseCache)
+var DefaultResponseCache = NewResponseCache(1000)
+
+// InvalidateCache removes responses of routes named as RouteName returns from DefaultResponseCache.
+// Call it in service implementations once data returned by the routes is changed
+func InvalidateCache(routes ...string) {
+	DefaultResponseCache.Invalidate(routes...)
+}
+
+func (c *ResponseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cachedResponse)
+	if c.now().After(entry.expires) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *ResponseCache) set(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[entry.key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[entry.key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// Invalidate removes responses of routes, or all responses if no route passed in
+func (c *ResponseCache) Invalidate(routes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.items {
+		if len(routes) == 0 || sliceutils.StringContains(routes, elem.Value.(*cachedResponse).route) {
+			c.ll.Remove(elem)
+			delete(c.items, key)
+		}
+	}
+}
+
+// Len returns number of cached responses including expired ones not evicted yet
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Cache is a middleware of http caching for GET requests. It sets weak ETag of 200 responses and responds 304
+// if it matches If-None-Match header, sets Cache-Control of routes, and serves responses from ResponseCache
+// if set by WithResponseCache
+type Cache struct {
+	controls map[string]string
+	store    *ResponseCache
+}
+
+type CacheOption func(*Cache)
+
+// WithRouteCacheControl sets Cache-Control header of 200 responses of the route, e.g. "public, max-age=60".
+// Responses are kept in ResponseCache for max-age or s-maxage unless directives contain no-store, no-cache or private
+func WithRouteCacheControl(route, directives string) CacheOption {
+	return func(c *Cache) {
+		c.controls[route] = directives
+	}
+}
+
+// WithResponseCache caches responses of routes with Cache-Control set by WithRouteCacheControl in store.
+// Requests authenticated by ddhttp.Auth or carrying credentials like Authorization, X-API-Key, api_key query parameter
+// or cookies only hit and fill the store if the route is public, as shared caches do
+func WithResponseCache(store *ResponseCache) CacheOption {
+	return func(c *Cache) {
+		c.store = store
+	}
+}
+
+// NewCache creates Cache. Generated CacheOptions function in transport/httpsrv returns options declared by
+// @cache annotations in svc.go
+func NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		controls: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ttlOf returns how long responses can be kept by directives of Cache-Control
+func ttlOf(directives string) time.Duration {
+	var maxAge, sMaxAge = -1, -1
+	for _, item := range strings.Split(directives, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "no-store" || item == "no-cache" || item == "private":
+			return 0
+		case strings.HasPrefix(item, "max-age="):
+			maxAge, _ = strconv.Atoi(strings.TrimPrefix(item, "max-age="))
+		case strings.HasPrefix(item, "s-maxage="):
+			sMaxAge, _ = strconv.Atoi(strings.TrimPrefix(item, "s-maxage="))
+		}
+	}
+	if sMaxAge >= 0 {
+		maxAge = sMaxAge
+	}
+	if maxAge <= 0 {
+		return 0
+	}
+	return time.Duration(maxAge) * time.Second
+}
+
+// isPersonalized reports whether r is authenticated or carries credentials, whose responses may be specific to the caller
+func isPersonalized(r *http.Request) bool {
+	if _, ok := PrincipalFrom(r.Context()); ok {
+		return true
+	}
+	for _, header := range []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key"} {
+		if stringutils.IsNotEmpty(r.Header.Get(header)) {
+			return true
+		}
+	}
+	return stringutils.IsNotEmpty(r.URL.Query().Get("api_key"))
+}
+
+func hasDirective(directives, directive string) bool {
+	for _, item := range strings.Split(directives, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatch reports whether If-None-Match header matches etag by weak comparison
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware should be added after middlewares rejecting requests like ddhttp.Auth, so that cached responses are only
+// served to authorized requests. Responses are cached by route name and request uri including path variables and query
+func (c *Cache) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := RouteName(r)
+		if r.Method != http.MethodGet || stringutils.IsEmpty(route) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		control := c.controls[route]
+		ttl := ttlOf(control)
+		store := c.store
+		if ttl <= 0 || (isPersonalized(r) && !hasDirective(control, "public")) {
+			store = nil
+		}
+		key := route + " " + r.URL.RequestURI()
+		if store != nil && !hasDirective(r.Header.Get("Cache-Control"), "no-cache") {
+			if entry, ok := store.get(key); ok {
+				for k, v := range entry.header {
+					w.Header()[k] = v
+				}
+				c.write(w, r, entry.status, entry.etag, control, entry.body)
+				return
+			}
+		}
+		before := w.Header().Clone()
+		cw := &bufferedWriter{ResponseWriter: w, limit: maxETagBodySize}
+		cw.beforeFlush = func(status int) {
+			if status == http.StatusOK && stringutils.IsNotEmpty(control) && stringutils.IsEmpty(w.Header().Get("Cache-Control")) {
+				w.Header().Set("Cache-Control", control)
+			}
+		}
+		inner.ServeHTTP(cw, r)
+		if cw.passthrough {
+			return
+		}
+		status := cw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		var etag string
+		if status == http.StatusOK {
+			if etag = w.Header().Get("ETag"); stringutils.IsEmpty(etag) {
+				sum := sha1.Sum(cw.buf.Bytes())
+				etag = `W/"` + hex.EncodeToString(sum[:]) + `"`
+			}
+		}
+		if store != nil && status == http.StatusOK && stringutils.IsEmpty(w.Header().Get("Set-Cookie")) {
+			// headers set by outer middlewares before, e.g. X-Request-Id, are not cached
+			store.set(&cachedResponse{
+				key:     key,
+				route:   route,
+				status:  status,
+				header:  headerDiff(before, w.Header()),
+				body:    append([]byte(nil), cw.buf.Bytes()...),
+				etag:    etag,
+				expires: store.now().Add(ttl),
+			})
+		}
+		c.write(w, r, status, etag, control, cw.buf.Bytes())
+	})
+}
+
+func (c *Cache) write(w http.ResponseWriter, r *http.Request, status int, etag, control string, body []byte) {
+	if status == http.StatusOK {
+		if stringutils.IsNotEmpty(control) && stringutils.IsEmpty(w.Header().Get("Cache-Control")) {
+			w.Header().Set("Cache-Control", control)
+		}
+		w.Header().Set("ETag", etag)
+		if ifNoneMatch := r.Header.Get("If-None-Match"); stringutils.IsNotEmpty(ifNoneMatch) && etagMatch(ifNoneMatch, etag) {
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// headerDiff returns headers of after which are added or changed since before
+func headerDiff(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for k, v := range after {
+		if strings.Join(before[k], ",") != strings.Join(v, ",") {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	return header
+}
+
+// bufferedWriter buffers the response, e.g. to compute its ETag. It writes through once the body exceeds limit
+// or the handler flushes, e.g. streams. beforeFlush is called if set before writing through
+type bufferedWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	limit       int
+	passthrough bool
+	beforeFlush func(status int)
+}
+
+func (cw *bufferedWriter) WriteHeader(code int) {
+	if cw.passthrough {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *bufferedWriter) Write(b []byte) (int, error) {
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if cw.buf.Len()+len(b) > cw.limit {
+		cw.pass()
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.buf.Write(b)
+}
+
+func (cw *bufferedWriter) Flush() {
+	cw.pass()
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *bufferedWriter) pass() {
+	if cw.passthrough {
+		return
+	}
+	cw.passthrough = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.beforeFlush != nil {
+		cw.beforeFlush(cw.status)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() > 0 {
+		cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
@@ -0,0 +1,178 @@
+package ddhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+func TestCache(t *testing.T) {
+	var hits int
+	routes := testRoutes()
+	routes[0].HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("X-Version", "1")
+		w.Write([]byte("user " + mux.Vars(r)["id"]))
+	}
+	routes = append(routes, model.Route{
+		Name:    "Watch",
+		Method:  "GET",
+		Pattern: "/events",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			StartEventStream(w)
+			WriteEvent(w, "hello")
+		},
+	})
+	store := NewResponseCache(2)
+	now := time.Now()
+	store.now = func() time.Time {
+		return now
+	}
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", r.Header.Get("X-Rid"))
+			inner.ServeHTTP(w, r)
+		})
+	}, NewCache(WithRouteCacheControl("GetUser", "max-age=60"), WithResponseCache(store)).Middleware)
+	srv.AddRoute(routes...)
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.(http.Handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/users/1", map[string]string{"X-Rid": "a"})
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || rec.Body.String() != "user 1" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("got %d %s with etag %s", rec.Code, rec.Body.String(), etag)
+	}
+	if rec.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("Cache-Control = %s, want max-age=60", rec.Header().Get("Cache-Control"))
+	}
+
+	rec = get("/users/1", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || hits != 1 {
+		t.Errorf("got %d %s after %d hits, want 304 from cache", rec.Code, rec.Body.String(), hits)
+	}
+	// headers set by outer middlewares are not replayed from cache
+	rec = get("/users/1", map[string]string{"If-None-Match": `"other"`})
+	rec2 := get("/users/1", map[string]string{"X-Rid": "b"})
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Version") != "1" || rec2.Header().Get("X-Request-Id") != "b" || hits != 1 {
+		t.Errorf("got %d %v after %d hits", rec.Code, rec.Header(), hits)
+	}
+
+	get("/users/2?page=1", nil)
+	if hits != 2 {
+		t.Errorf("path variables and query should be part of cache keys, got %d hits", hits)
+	}
+	get("/users/1", map[string]string{"Cache-Control": "no-cache"})
+	get("/users/1", map[string]string{"Authorization": "Bearer abc"})
+	if hits != 4 {
+		t.Errorf("no-cache and private requests should bypass cache, got %d hits", hits)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	get("/users/2?page=1", nil)
+	if hits != 5 {
+		t.Errorf("expired response should be refreshed, got %d hits", hits)
+	}
+	store.Invalidate("GetUser")
+	if store.Len() != 0 {
+		t.Errorf("store should be empty after invalidation, got %d", store.Len())
+	}
+
+	rec = get("/events", nil)
+	if rec.Header().Get("ETag") != "" || !strings.Contains(rec.Body.String(), "hello") || !rec.Flushed {
+		t.Errorf("streams should be written through, got %v %s", rec.Header(), rec.Body.String())
+	}
+	req := httptest.NewRequest("POST", "/users", nil)
+	rec = httptest.NewRecorder()
+	srv.(http.Handler).ServeHTTP(rec, req)
+	if rec.Header().Get("ETag") != "" {
+		t.Error("only GET responses should get etag")
+	}
+}
+
+func TestCache_Credentials(t *testing.T) {
+	var hits int
+	routes := testRoutes()
+	routes[0].HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		principal, _ := PrincipalFrom(r.Context())
+		w.Write([]byte("user of " + principal.Subject))
+	}
+	keys := StaticAPIKeys{
+		"k1": {Subject: "jack"},
+		"k2": {Subject: "rose"},
+	}
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(NewAuth(WithAuthenticators(NewAPIKeyAuthenticator(keys))).Middleware,
+		NewCache(WithRouteCacheControl("GetUser", "max-age=60"), WithResponseCache(NewResponseCache(10))).Middleware)
+	srv.AddRoute(routes...)
+	get := func(path string, header map[string]string) string {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.(http.Handler).ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	if body := get("/users/1", map[string]string{"X-API-Key": "k1"}); body != "user of jack" {
+		t.Fatalf("got %s", body)
+	}
+	if body := get("/users/1", map[string]string{"X-API-Key": "k2"}); body != "user of rose" {
+		t.Errorf("response of jack should not be served to rose, got %s", body)
+	}
+	get("/users/1?api_key=k1", nil)
+	get("/users/1?api_key=k1", nil)
+	if hits != 4 {
+		t.Errorf("authenticated requests should bypass cache, got %d hits", hits)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	store := NewResponseCache(2)
+	for _, key := range []string{"a", "b", "a", "c"} {
+		store.set(&cachedResponse{key: key, route: key, expires: time.Now().Add(time.Minute)})
+	}
+	if _, ok := store.get("b"); ok {
+		t.Error("least recently used entry should be evicted")
+	}
+	if _, ok := store.get("a"); !ok {
+		t.Error("recently used entry should be kept")
+	}
+	store.Invalidate()
+	if store.Len() != 0 {
+		t.Errorf("Invalidate() without routes should remove all entries, got %d", store.Len())
+	}
+}
+
+func Test_ttlOf(t *testing.T) {
+	tests := []struct {
+		directives string
+		want       time.Duration
+	}{
+		{"", 0},
+		{"max-age=60", time.Minute},
+		{"public, max-age=60, s-maxage=10", 10 * time.Second},
+		{"private, max-age=60", 0},
+		{"no-store", 0},
+	}
+	for _, tt := range tests {
+		if got := ttlOf(tt.directives); got != tt.want {
+			t.Errorf("ttlOf(%q) = %v, want %v", tt.directives, got, tt.want)
+		}
+	}
+}
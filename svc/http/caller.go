@@ -0,0 +1,308 @@
+package ddhttp
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/svc/http/prometheus"
+	"github.com/unionj-cloud/go-doudou/svc/tracing"
+)
+
+// DefaultTimeout is the deadline of each call of generated clients when no timeout is set for the method.
+// It doesn't apply to methods returning streams or files, which are only limited by ctx and timeouts set explicitly
+const DefaultTimeout = time.Minute
+
+// RetryPolicy defines how generated clients retry failed requests with exponential backoff.
+// A request is failed if it cannot be sent, or the response status is 5xx or 429
+type RetryPolicy struct {
+	// MaxRetries is the max number of retries after the first attempt
+	MaxRetries int
+	// WaitTime is the wait time before the first retry and doubles for each next retry, 100ms by default
+	WaitTime time.Duration
+	// MaxWaitTime caps the wait time between retries, 2s by default
+	MaxWaitTime time.Duration
+	// RetryNonIdempotent retries POST and PATCH requests as well, only GET, HEAD, PUT, DELETE and OPTIONS
+	// requests are retried by default. Note that files uploaded by multipart requests cannot be sent again
+	RetryNonIdempotent bool
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.WaitTime
+	if wait <= 0 {
+		wait = 100 * time.Millisecond
+	}
+	max := p.MaxWaitTime
+	if max <= 0 {
+		max = 2 * time.Second
+	}
+	d := wait << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	// jitter in [d/2, d] to avoid retries of many clients hitting servers at the same time
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func isIdempotent(httpMethod string) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func isFailure(resp *resty.Response, err error) bool {
+	return err != nil || resp.StatusCode() >= http.StatusInternalServerError
+}
+
+func shouldRetry(resp *resty.Response, err error) bool {
+	return isFailure(resp, err) || resp.StatusCode() == http.StatusTooManyRequests
+}
+
+// Caller sends requests of generated clients with retry policy, timeout, circuit breaker and load balancer.
+// Generated clients embed it to implement SetRetry, SetTimeout, SetCircuitBreaker and SetLoadBalancer of DdClient
+type Caller struct {
+	retry    RetryPolicy
+	retries  map[string]RetryPolicy
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	breaker  *CircuitBreaker
+	balancer LoadBalancer
+}
+
+// SetRetry sets retry policy for methods, or for all methods if no method name passed in
+func (c *Caller) SetRetry(policy RetryPolicy, methods ...string) {
+	if len(methods) == 0 {
+		c.retry = policy
+		return
+	}
+	if c.retries == nil {
+		c.retries = make(map[string]RetryPolicy)
+	}
+	for _, method := range methods {
+		c.retries[method] = policy
+	}
+}
+
+// SetTimeout sets deadline of each call including all retries for methods, or for all methods if no method name passed in.
+// The earlier deadline takes effect if ctx passed to the method has one
+func (c *Caller) SetTimeout(timeout time.Duration, methods ...string) {
+	if len(methods) == 0 {
+		c.timeout = timeout
+		return
+	}
+	if c.timeouts == nil {
+		c.timeouts = make(map[string]time.Duration)
+	}
+	for _, method := range methods {
+		c.timeouts[method] = timeout
+	}
+}
+
+// SetCircuitBreaker sets circuit breaker which records results of requests to each server
+func (c *Caller) SetCircuitBreaker(breaker *CircuitBreaker) {
+	c.breaker = breaker
+}
+
+// SetLoadBalancer sets load balancer to pick servers listed by service providers implementing ServerLister,
+// otherwise servers are selected by SelectServer of service providers
+func (c *Caller) SetLoadBalancer(balancer LoadBalancer) {
+	c.balancer = balancer
+}
+
+func (c *Caller) retryOf(method string) RetryPolicy {
+	if policy, ok := c.retries[method]; ok {
+		return policy
+	}
+	return c.retry
+}
+
+func (c *Caller) timeoutOf(method string, stream bool) time.Duration {
+	if timeout, ok := c.timeouts[method]; ok {
+		return timeout
+	}
+	if c.timeout > 0 || stream {
+		return c.timeout
+	}
+	return DefaultTimeout
+}
+
+// Call sends req to path of the server selected from provider by httpMethod,
+// method is the name of the client method used to look up its retry policy and timeout.
+// The server is selected again for each retry, so that a sick server won't fail the call.
+// A client span named by method is started as a child of the span carried by ctx of req and propagated by traceparent header
+func (c *Caller) Call(provider IServiceProvider, req *resty.Request, method, httpMethod, path string) (*resty.Response, error) {
+	return c.call(provider, req, method, httpMethod, path, false)
+}
+
+// CallStream is the same as Call except that the response body is left unread for streams and files.
+// The caller must close resp.RawBody(), which also releases the timeout of the call
+func (c *Caller) CallStream(provider IServiceProvider, req *resty.Request, method, httpMethod, path string) (*resty.Response, error) {
+	req.SetDoNotParseResponse(true)
+	return c.call(provider, req, method, httpMethod, path, true)
+}
+
+func (c *Caller) call(provider IServiceProvider, req *resty.Request, method, httpMethod, path string, stream bool) (*resty.Response, error) {
+	ctx, span := tracing.Start(req.Context(), method, tracing.SpanKindClient)
+	req.SetContext(ctx)
+	injectSpan(req, span)
+	cancel := func() {}
+	if timeout := c.timeoutOf(method, stream); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		req.SetContext(ctx)
+	}
+	policy := c.retryOf(method)
+	maxRetries := policy.MaxRetries
+	if !policy.RetryNonIdempotent && !isIdempotent(httpMethod) {
+		maxRetries = 0
+	}
+	var (
+		resp *resty.Response
+		err  error
+		done func()
+	)
+	for attempt := 0; ; attempt++ {
+		resp, done, err = c.attempt(provider, req, method, httpMethod, path)
+		if attempt >= maxRetries || !shouldRetry(resp, err) || ctx.Err() != nil {
+			break
+		}
+		if stream && resp != nil && resp.RawBody() != nil {
+			resp.RawBody().Close()
+		}
+		done()
+		if !sleep(ctx, policy.backoff(attempt)) {
+			err = ctx.Err()
+			break
+		}
+	}
+	endSpan(span, resp, err)
+	if err != nil || !stream || resp.RawResponse == nil {
+		cancel()
+		done()
+		span.End()
+		return resp, err
+	}
+	resp.RawResponse.Body = &releaseBody{ReadCloser: resp.RawResponse.Body, release: func() {
+		cancel()
+		done()
+		span.End()
+	}}
+	return resp, nil
+}
+
+func injectSpan(req *resty.Request, span *tracing.Span) {
+	sc := span.Context()
+	req.SetHeader(tracing.TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		req.SetHeader(tracing.TracestateHeader, sc.TraceState)
+	}
+}
+
+func endSpan(span *tracing.Span, resp *resty.Response, err error) {
+	if resp != nil && resp.Request != nil {
+		span.SetAttribute("http.method", resp.Request.Method)
+		span.SetAttribute("http.url", resp.Request.URL)
+	}
+	if err != nil {
+		span.SetStatus(tracing.StatusError, err.Error())
+		return
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode())
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		span.SetStatus(tracing.StatusError, resp.Status())
+	}
+}
+
+func (c *Caller) selectServer(ctx context.Context, provider IServiceProvider) (string, func(), error) {
+	lister, ok := provider.(ServerLister)
+	if c.balancer == nil || !ok {
+		server, err := provider.SelectServer()
+		return server, func() {}, err
+	}
+	servers, err := lister.Servers()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(servers) == 0 {
+		return "", nil, errors.New("no server found")
+	}
+	if c.breaker != nil {
+		servers = c.breaker.available(servers)
+	}
+	server := c.balancer.Pick(ctx, servers)
+	if tracker, ok := c.balancer.(RequestTracker); ok {
+		var once sync.Once
+		return server.BaseUrl, func() {
+			once.Do(func() {
+				tracker.Finish(server)
+			})
+		}, nil
+	}
+	return server.BaseUrl, func() {}, nil
+}
+
+// serviceOf returns name of the service for client metrics, which is the service name of MemberlistServiceProvider
+// or the environment variable name of ServiceProvider
+func serviceOf(provider IServiceProvider) string {
+	if named, ok := provider.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// attempt sends req to the selected server, done must be called once the response is not used any more
+func (c *Caller) attempt(provider IServiceProvider, req *resty.Request, method, httpMethod, path string) (resp *resty.Response, done func(), err error) {
+	server, done, err := c.selectServer(req.Context(), provider)
+	if err != nil {
+		prometheus.ObserveClientRequest(serviceOf(provider), "", method, 0, err, 0)
+		return nil, func() {}, err
+	}
+	if c.breaker != nil && !c.breaker.Allow(server) {
+		err = errors.Wrap(ErrCircuitOpen, server)
+		prometheus.ObserveClientRequest(serviceOf(provider), server, method, 0, err, 0)
+		return nil, done, err
+	}
+	start := time.Now()
+	resp, err = req.Execute(httpMethod, server+path)
+	var code int
+	if resp != nil {
+		code = resp.StatusCode()
+	}
+	prometheus.ObserveClientRequest(serviceOf(provider), server, method, code, err, time.Since(start))
+	if c.breaker != nil && !errors.Is(req.Context().Err(), context.Canceled) {
+		if isFailure(resp, err) {
+			c.breaker.Failure(server)
+		} else {
+			c.breaker.Success(server)
+		}
+	}
+	return resp, done, err
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// releaseBody releases the timeout, the load balancer and the span of a streaming call when its body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,218 @@
+package ddhttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/unionj-cloud/go-doudou/svc/http/prometheus"
+)
+
+type roundRobin struct {
+	servers []string
+	next    int
+}
+
+func (p *roundRobin) SelectServer() (string, error) {
+	server := p.servers[p.next%len(p.servers)]
+	p.next++
+	return server, nil
+}
+
+func flaky(failures int32, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+}
+
+func TestCaller_Retry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, WaitTime: time.Millisecond}
+	tests := []struct {
+		name       string
+		httpMethod string
+		policy     RetryPolicy
+		wantStatus int
+		wantHits   int32
+	}{
+		{"idempotent", http.MethodGet, policy, http.StatusOK, 3},
+		{"no retry", http.MethodGet, RetryPolicy{}, http.StatusServiceUnavailable, 1},
+		{"non idempotent", http.MethodPost, policy, http.StatusServiceUnavailable, 1},
+		{"retry non idempotent", http.MethodPost, RetryPolicy{MaxRetries: 3, WaitTime: time.Millisecond, RetryNonIdempotent: true}, http.StatusOK, 3},
+		{"max retries", http.MethodGet, RetryPolicy{MaxRetries: 1, WaitTime: time.Millisecond}, http.StatusServiceUnavailable, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			ts := flaky(2, &hits)
+			defer ts.Close()
+			var caller Caller
+			caller.SetRetry(tt.policy, "GetUser")
+			resp, err := caller.Call(&roundRobin{servers: []string{ts.URL}}, resty.New().R(), "GetUser", tt.httpMethod, "/user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode() != tt.wantStatus || hits != tt.wantHits {
+				t.Errorf("got status %d after %d requests, want %d after %d", resp.StatusCode(), hits, tt.wantStatus, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCaller_CircuitBreaker(t *testing.T) {
+	var sickHits, healthyHits int32
+	sick := flaky(100, &sickHits)
+	defer sick.Close()
+	healthy := flaky(0, &healthyHits)
+	defer healthy.Close()
+	provider := &roundRobin{servers: []string{sick.URL, healthy.URL}}
+
+	var caller Caller
+	caller.SetRetry(RetryPolicy{MaxRetries: 1, WaitTime: time.Millisecond})
+	caller.SetCircuitBreaker(NewCircuitBreaker(1, time.Minute))
+	for i := 0; i < 10; i++ {
+		resp, err := caller.Call(provider, resty.New().R(), "GetUser", http.MethodGet, "/user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			t.Errorf("call %d should be retried on the healthy server, got status %d", i, resp.StatusCode())
+		}
+	}
+	if sickHits != 1 {
+		t.Errorf("sick server should be requested only once before its circuit opens, got %d", sickHits)
+	}
+
+	caller.SetRetry(RetryPolicy{})
+	provider.servers = provider.servers[:1]
+	if _, err := caller.Call(provider, resty.New().R(), "GetUser", http.MethodGet, "/user"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("want ErrCircuitOpen, got %v", err)
+	}
+}
+
+// scrape returns metrics exposed by /go-doudou/prometheus
+func scrape() string {
+	rec := httptest.NewRecorder()
+	prometheus.Routes()[0].HandlerFunc(rec, httptest.NewRequest("GET", "/go-doudou/prometheus", nil))
+	return rec.Body.String()
+}
+
+func TestCaller_Metrics(t *testing.T) {
+	var hits int32
+	ts := flaky(1, &hits)
+	defer ts.Close()
+	setEnv(t, map[string]string{"METRICSVC": ts.URL})
+	var caller Caller
+	caller.SetRetry(RetryPolicy{MaxRetries: 1, WaitTime: time.Millisecond})
+	if _, err := caller.Call(NewServiceProvider("METRICSVC"), resty.New().R(), "GetUser", http.MethodGet, "/user"); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("METRICSVC", "")
+	caller.Call(NewServiceProvider("METRICSVC"), resty.New().R(), "GetUser", http.MethodGet, "/user")
+
+	metrics := scrape()
+	for _, want := range []string{
+		`http_client_requests_total{method="GetUser",node="` + ts.URL + `",service="METRICSVC",status="5xx"} 1`,
+		`http_client_requests_total{method="GetUser",node="` + ts.URL + `",service="METRICSVC",status="2xx"} 1`,
+		`http_client_requests_total{method="GetUser",node="",service="METRICSVC",status="error"} 2`,
+		`http_client_request_duration_seconds_count{method="GetUser",node="` + ts.URL + `",service="METRICSVC"} 2`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics should contain %s, got %s", want, metrics)
+		}
+	}
+}
+
+func TestCaller_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+	provider := &roundRobin{servers: []string{ts.URL}}
+
+	var caller Caller
+	caller.SetTimeout(50*time.Millisecond, "Slow")
+	start := time.Now()
+	if _, err := caller.Call(provider, resty.New().R(), "Slow", http.MethodGet, "/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := caller.Call(provider, resty.New().R().SetContext(ctx), "Fast", http.MethodGet, "/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded of ctx, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("calls should time out soon, took %s", elapsed)
+	}
+}
+
+func TestCaller_CallStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		StartEventStream(w)
+		time.Sleep(20 * time.Millisecond)
+		WriteEvent(w, "done")
+	}))
+	defer ts.Close()
+
+	var caller Caller
+	caller.SetTimeout(time.Second)
+	resp, err := caller.CallStream(&roundRobin{servers: []string{ts.URL}}, resty.New().R(), "Watch", http.MethodGet, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.RawBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.RawBody().Close()
+	if string(b) != "data: \"done\"\n\n" {
+		t.Errorf("body = %q", string(b))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server := "http://localhost:6060"
+	breaker := NewCircuitBreaker(2, 50*time.Millisecond)
+	breaker.Failure(server)
+	if !breaker.Allow(server) {
+		t.Error("circuit should be closed before failures reach threshold")
+	}
+	breaker.Failure(server)
+	if breaker.Allow(server) || breaker.Available(server) {
+		t.Error("circuit should be open")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !breaker.Available(server) || !breaker.Allow(server) {
+		t.Error("trial request should be allowed after open timeout")
+	}
+	if breaker.Allow(server) {
+		t.Error("only one trial request should be allowed")
+	}
+	breaker.Failure(server)
+	if breaker.Allow(server) {
+		t.Error("circuit should open again if trial request failed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !breaker.Allow(server) {
+		t.Error("trial request should be allowed after open timeout")
+	}
+	breaker.Success(server)
+	if !breaker.Allow(server) || !breaker.Allow(server) {
+		t.Error("circuit should be closed after trial request succeeded")
+	}
+}
@@ -1,12 +1,151 @@
 package ddhttp
 
 import (
+	"net/http"
+	"strings"
+	"sync"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/health"
 	"github.com/unionj-cloud/go-doudou/svc/http/model"
+	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
+	"github.com/unionj-cloud/go-doudou/svc/http/prometheus"
 )
 
-// TODO https://github.com/go-chi/chi
+// ChiHttpSrv is Srv based on https://github.com/go-chi/chi.
+// Middlewares are applied to each route after routing as DefaultHttpSrv does. As chi routes take middlewares
+// when they are registered, routes are registered when the server starts serving, so middlewares added
+// after AddRoute are applied too
 type ChiHttpSrv struct {
 	*chi.Mux
-	routes []model.Route
+	rootPath    string
+	gddRoutes   []model.Route
+	probeRoutes []model.Route
+	routes      []model.Route
+	// pending are routes added by AddRoute but not registered yet
+	pending     []model.Route
+	middlewares []func(http.Handler) http.Handler
+	// names are route names by method and pattern, for middlewares applied before routing
+	names map[string]string
+	once  sync.Once
+	built bool
+}
+
+func NewChiHttpSrv() Srv {
+	var gddRoutes, probeRoutes []model.Route
+	if config.GddManage.Load() == "true" {
+		gddRoutes = append(gddRoutes, onlinedoc.Routes()...)
+		gddRoutes = append(gddRoutes, prometheus.Routes()...)
+		gddRoutes = append(gddRoutes, health.Routes()...)
+		probeRoutes = health.ProbeRoutes()
+	}
+	router := chi.NewRouter()
+	// the same as StrictSlash(true) of gorilla/mux in DefaultHttpSrv
+	router.Use(middleware.RedirectSlashes)
+	srv := &ChiHttpSrv{
+		Mux:         router,
+		rootPath:    strings.TrimSuffix(config.GddRouteRootPath.Load(), "/"),
+		gddRoutes:   gddRoutes,
+		probeRoutes: probeRoutes,
+		routes:      append(append([]model.Route{}, gddRoutes...), probeRoutes...),
+		names:       make(map[string]string),
+	}
+	// chi panics if middlewares are used after routes, so the metrics middleware is used before any route is added
+	if config.GddManage.Load() == "true" {
+		router.Use(prometheus.Middleware(srv.routeName))
+	}
+	return srv
+}
+
+func (srv *ChiHttpSrv) AddRoute(route ...model.Route) {
+	srv.routes = append(append([]model.Route{}, route...), srv.routes...)
+	srv.pending = append(srv.pending, append(preflightRoutes(route), route...)...)
+	if srv.built {
+		srv.register()
+	}
+}
+
+// build registers routes with all middlewares added by AddMiddleware once the server starts serving
+func (srv *ChiHttpSrv) build() {
+	srv.once.Do(func() {
+		if len(srv.gddRoutes) > 0 {
+			basicAuth := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if BasicAuth(w, r) {
+						next.ServeHTTP(w, r)
+					}
+				})
+			}
+			for _, item := range srv.gddRoutes {
+				srv.handle(item, append(srv.middlewares[:len(srv.middlewares):len(srv.middlewares)], basicAuth)...)
+			}
+		}
+		// probes are not protected by basic auth
+		for _, item := range srv.probeRoutes {
+			srv.handle(item, srv.middlewares...)
+		}
+		srv.register()
+		srv.built = true
+	})
+}
+
+// register registers pending routes
+func (srv *ChiHttpSrv) register() {
+	for _, item := range srv.pending {
+		srv.handle(item, srv.middlewares...)
+	}
+	srv.pending = nil
+}
+
+// handle registers route with middlewares which can get its name by RouteName
+func (srv *ChiHttpSrv) handle(route model.Route, mwf ...func(http.Handler) http.Handler) {
+	mwf = append([]func(http.Handler) http.Handler{withRouteName(route.Name)}, mwf...)
+	srv.With(mwf...).Method(route.Method, srv.rootPath+route.Pattern, withMuxVars(route.HandlerFunc))
+	srv.names[route.Method+" "+srv.rootPath+route.Pattern] = route.Name
+}
+
+// routeName returns name of the route matched by r, it is only available after r is routed
+func (srv *ChiHttpSrv) routeName(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return srv.names[r.Method+" "+rctx.RoutePattern()]
+	}
+	return ""
+}
+
+// withMuxVars copies url params of chi into route variables of gorilla/mux,
+// so that path variables can be read by mux.Vars in generated http handlers
+func withMuxVars(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.URLParams.Keys) > 0 {
+			vars := make(map[string]string)
+			for i, key := range rctx.URLParams.Keys {
+				vars[key] = rctx.URLParams.Values[i]
+			}
+			r = mux.SetURLVars(r, vars)
+		}
+		handler(w, r)
+	}
+}
+
+func (srv *ChiHttpSrv) AddMiddleware(mwf ...func(http.Handler) http.Handler) {
+	if srv.built {
+		logrus.Errorln("middlewares cannot be added after ChiHttpSrv starts serving, ignored")
+		return
+	}
+	srv.middlewares = append(srv.middlewares, mwf...)
+}
+
+// ServeHTTP registers routes before serving the first request, in case it is used as http.Handler without Run
+func (srv *ChiHttpSrv) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.build()
+	srv.Mux.ServeHTTP(w, r)
+}
+
+func (srv *ChiHttpSrv) Run() {
+	srv.build()
+	run(srv, srv.routes)
 }
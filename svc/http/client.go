@@ -3,7 +3,9 @@ package ddhttp
 import (
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/http/tlsutils"
 	"github.com/unionj-cloud/go-doudou/svc/registry"
 	"net"
 	"net/http"
@@ -16,6 +18,10 @@ import (
 type DdClient interface {
 	SetProvider(provider IServiceProvider)
 	SetClient(client *resty.Client)
+	SetRetry(policy RetryPolicy, methods ...string)
+	SetTimeout(timeout time.Duration, methods ...string)
+	SetCircuitBreaker(breaker *CircuitBreaker)
+	SetLoadBalancer(balancer LoadBalancer)
 }
 
 type DdClientOption func(DdClient)
@@ -32,6 +38,35 @@ func WithClient(client *resty.Client) DdClientOption {
 	}
 }
 
+// WithRetry sets retry policy for methods of the client, or for all methods if no method name passed in
+func WithRetry(policy RetryPolicy, methods ...string) DdClientOption {
+	return func(c DdClient) {
+		c.SetRetry(policy, methods...)
+	}
+}
+
+// WithTimeout sets deadline of each call for methods of the client, or for all methods if no method name passed in
+func WithTimeout(timeout time.Duration, methods ...string) DdClientOption {
+	return func(c DdClient) {
+		c.SetTimeout(timeout, methods...)
+	}
+}
+
+// WithCircuitBreaker sets circuit breaker of the client
+func WithCircuitBreaker(breaker *CircuitBreaker) DdClientOption {
+	return func(c DdClient) {
+		c.SetCircuitBreaker(breaker)
+	}
+}
+
+// WithLoadBalancer sets load balancer of the client, which picks servers listed by the service provider
+// if it implements ServerLister like MemberlistServiceProvider
+func WithLoadBalancer(balancer LoadBalancer) DdClientOption {
+	return func(c DdClient) {
+		c.SetLoadBalancer(balancer)
+	}
+}
+
 type IServiceProvider interface {
 	SelectServer() (string, error)
 }
@@ -48,6 +83,11 @@ func (s *ServiceProvider) SelectServer() (string, error) {
 	return address, nil
 }
 
+// Name returns the environment variable name, which labels client metrics of the service
+func (s *ServiceProvider) Name() string {
+	return s.Env
+}
+
 type ServiceProviderOption func(IServiceProvider)
 
 func NewServiceProvider(env string, opts ...ServiceProviderOption) IServiceProvider {
@@ -62,17 +102,24 @@ func NewServiceProvider(env string, opts ...ServiceProviderOption) IServiceProvi
 	return provider
 }
 
+// NewClient creates resty client for generated clients, which dials https with the client certificate
+// set by GDD_TLS_CERT and GDD_TLS_KEY, verifying server certificates by GDD_TLS_CA. The process exits if they are invalid
 func NewClient() *resty.Client {
 	client := resty.New()
-	client.SetTimeout(1 * time.Minute)
 
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
 		DualStack: true,
 	}
+	tlsConfig, err := tlsutils.ClientConfig()
+	if err != nil {
+		// exit instead of falling back to default tls config, which fails handshakes requiring client certificates
+		logrus.Fatalf("Configure tls of client failed: %v\n", err)
+	}
 	client.SetTransport(&http.Transport{
 		Proxy:                 http.ProxyFromEnvironment,
+		TLSClientConfig:       tlsConfig,
 		DialContext:           dialer.DialContext,
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
@@ -90,21 +137,57 @@ type MemberlistServiceProvider struct {
 	name     string
 	registry registry.IRegistry
 	current  uint64
+	breaker  *CircuitBreaker
 }
 
+// SelectServer selects servers in turn, skipping servers with open circuit if WithBreaker is used
 func (m *MemberlistServiceProvider) SelectServer() (string, error) {
-	nodes, err := m.registry.Discover(m.name)
+	servers, err := m.Servers()
 	if err != nil {
 		return "", errors.Wrap(err, "SelectServer() fail")
 	}
-	next := int(atomic.AddUint64(&m.current, uint64(1)) % uint64(len(nodes)))
-	m.current = uint64(next)
-	selected := nodes[next]
-	return selected.BaseUrl(), nil
+	if len(servers) == 0 {
+		return "", errors.Errorf("SelectServer() fail: no server of service %s found", m.name)
+	}
+	if m.breaker != nil {
+		servers = m.breaker.available(servers)
+	}
+	next := (atomic.AddUint64(&m.current, 1) - 1) % uint64(len(servers))
+	return servers[next].BaseUrl, nil
+}
+
+// Name returns name of the service, which labels client metrics of the service
+func (m *MemberlistServiceProvider) Name() string {
+	return m.name
+}
+
+// Servers returns all alive servers of the service with their weights
+func (m *MemberlistServiceProvider) Servers() ([]Server, error) {
+	nodes, err := m.registry.Discover(m.name)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]Server, 0, len(nodes))
+	for _, node := range nodes {
+		servers = append(servers, Server{
+			BaseUrl: node.BaseUrl(),
+			Weight:  node.Weight(),
+		})
+	}
+	return servers, nil
 }
 
 type MemberlistProviderOption func(IServiceProvider)
 
+// WithBreaker makes MemberlistServiceProvider skip nodes whose circuit is open in breaker
+func WithBreaker(breaker *CircuitBreaker) MemberlistProviderOption {
+	return func(provider IServiceProvider) {
+		if m, ok := provider.(*MemberlistServiceProvider); ok {
+			m.breaker = breaker
+		}
+	}
+}
+
 func NewMemberlistServiceProvider(name string, registry registry.IRegistry, opts ...MemberlistProviderOption) IServiceProvider {
 	provider := &MemberlistServiceProvider{
 		name:     name,
@@ -0,0 +1,263 @@
+package ddhttp
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+// DefaultCorsMethods are methods allowed by preflight requests if GDD_CORS_ALLOWED_METHODS is empty
+var DefaultCorsMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
+
+// CorsPolicy answers preflight requests and sets CORS headers of responses for allowed origins
+type CorsPolicy struct {
+	origins     []*regexp.Regexp
+	anyOrigin   bool
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+type CorsOption func(*CorsPolicy)
+
+// WithCorsOrigins sets allowed origins, * matches any origin or any part of host and port,
+// e.g. https://*.example.com matches https://api.example.com
+func WithCorsOrigins(origins ...string) CorsOption {
+	return func(c *CorsPolicy) {
+		c.origins = nil
+		c.anyOrigin = false
+		for _, origin := range origins {
+			if origin == "*" {
+				c.anyOrigin = true
+				continue
+			}
+			pattern := strings.Replace(regexp.QuoteMeta(strings.TrimSuffix(origin, "/")), `\*`, `[a-zA-Z0-9.\-]*`, -1)
+			c.origins = append(c.origins, regexp.MustCompile("(?i)^"+pattern+"$"))
+		}
+	}
+}
+
+// WithCorsMethods sets methods allowed by preflight requests
+func WithCorsMethods(methods ...string) CorsOption {
+	return func(c *CorsPolicy) {
+		c.methods = nil
+		for _, method := range methods {
+			c.methods = append(c.methods, strings.ToUpper(method))
+		}
+	}
+}
+
+// WithCorsHeaders sets headers allowed by preflight requests, any requested headers are allowed if not set
+func WithCorsHeaders(headers ...string) CorsOption {
+	return func(c *CorsPolicy) {
+		c.headers = headers
+	}
+}
+
+// WithCorsExposedHeaders sets response headers readable by browsers besides CORS-safelisted ones
+func WithCorsExposedHeaders(headers ...string) CorsOption {
+	return func(c *CorsPolicy) {
+		c.exposed = headers
+	}
+}
+
+// WithCorsCredentials allows cookies and authorization headers in cross origin requests. It is ignored if any origin is
+// allowed by *, as browsers don't send credentials to * and echoing origins would expose responses to every site
+func WithCorsCredentials(allow bool) CorsOption {
+	return func(c *CorsPolicy) {
+		c.credentials = allow
+	}
+}
+
+// WithCorsMaxAge sets how long browsers cache preflight results
+func WithCorsMaxAge(maxAge time.Duration) CorsOption {
+	return func(c *CorsPolicy) {
+		c.maxAge = maxAge
+	}
+}
+
+// splitList splits comma separated value, empty items are dropped
+func splitList(value string) []string {
+	var ret []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); stringutils.IsNotEmpty(item) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// NewCorsPolicy creates CorsPolicy configured by GDD_CORS_* environment variables, which are overridden by opts
+func NewCorsPolicy(opts ...CorsOption) *CorsPolicy {
+	c := &CorsPolicy{}
+	WithCorsOrigins(splitList(config.GddCorsAllowedOrigins.Load())...)(c)
+	methods := splitList(config.GddCorsAllowedMethods.Load())
+	if len(methods) == 0 {
+		methods = DefaultCorsMethods
+	}
+	WithCorsMethods(methods...)(c)
+	c.headers = splitList(config.GddCorsAllowedHeaders.Load())
+	c.exposed = splitList(config.GddCorsExposedHeaders.Load())
+	c.credentials = config.GddCorsAllowCredentials.Load() == "true"
+	if maxAge := config.GddCorsMaxAge.Load(); stringutils.IsNotEmpty(maxAge) {
+		var err error
+		if c.maxAge, err = time.ParseDuration(maxAge); err != nil {
+			logrus.Warnf("Parse %s %s as time.Duration failed: %s, preflight results are not cached.\n", "GDD_CORS_MAX_AGE",
+				maxAge, err.Error())
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.anyOrigin && c.credentials {
+		// echoing any origin with credentials would let every site read responses of logged in users
+		logrus.Warnln("CORS credentials are not allowed for * origin, set GDD_CORS_ALLOWED_ORIGINS to trusted origins instead")
+		c.credentials = false
+	}
+	return c
+}
+
+// Enabled reports whether any origin is allowed
+func (c *CorsPolicy) Enabled() bool {
+	return c.anyOrigin || len(c.origins) > 0
+}
+
+func (c *CorsPolicy) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	for _, re := range c.origins {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CorsPolicy) allowHeaders(requested []string) bool {
+	if len(c.headers) == 0 {
+		return true
+	}
+	for _, header := range requested {
+		var found bool
+		for _, allowed := range c.headers {
+			if strings.EqualFold(header, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CorsPolicy) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Middleware answers preflight requests with 204, and sets CORS headers of other requests from allowed origins.
+// Requests from disallowed origins get no CORS headers, so browsers block them. Add it before middlewares
+// rejecting requests like ddhttp.Auth, as browsers send preflight requests without credentials
+func (c *CorsPolicy) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !c.Enabled() || stringutils.IsEmpty(origin) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if r.Method != http.MethodOptions || stringutils.IsEmpty(r.Header.Get("Access-Control-Request-Method")) {
+			if c.allowOrigin(origin) {
+				c.setOrigin(h, origin)
+				if len(c.exposed) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(c.exposed, ", "))
+				}
+			}
+			inner.ServeHTTP(w, r)
+			return
+		}
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		requested := splitList(r.Header.Get("Access-Control-Request-Headers"))
+		if c.allowOrigin(origin) && sliceutils.StringContains(c.methods, method) && c.allowHeaders(requested) {
+			c.setOrigin(h, origin)
+			h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+			if len(c.headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+			} else if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if c.maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+var (
+	corsOnce    sync.Once
+	defaultCors *CorsPolicy
+)
+
+// Cors handles CORS by CorsPolicy configured by GDD_CORS_* environment variables, which are read once
+// when the middleware is applied first. Requests pass through if GDD_CORS_ALLOWED_ORIGINS is empty
+func Cors(inner http.Handler) http.Handler {
+	corsOnce.Do(func() {
+		defaultCors = NewCorsPolicy()
+	})
+	return defaultCors.Middleware(inner)
+}
+
+// preflightRoutes returns OPTIONS routes answering preflight requests to patterns of routes if CORS is enabled
+// by GDD_CORS_ALLOWED_ORIGINS, so that the requests go through middlewares including Cors instead of getting 405.
+// The routes are named after routes of the same patterns, so that route based middlewares apply to them as well
+func preflightRoutes(routes []model.Route) []model.Route {
+	if stringutils.IsEmpty(config.GddCorsAllowedOrigins.Load()) {
+		return nil
+	}
+	patterns := make(map[string]bool)
+	for _, item := range routes {
+		if item.Method == http.MethodOptions {
+			patterns[item.Pattern] = true
+		}
+	}
+	var ret []model.Route
+	for _, item := range routes {
+		if patterns[item.Pattern] {
+			continue
+		}
+		patterns[item.Pattern] = true
+		ret = append(ret, model.Route{
+			Name:    item.Name,
+			Method:  http.MethodOptions,
+			Pattern: item.Pattern,
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		})
+	}
+	return ret
+}
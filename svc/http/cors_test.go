@@ -0,0 +1,137 @@
+package ddhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+func TestCorsPolicy(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddCorsAllowedOrigins.String():   "https://*.example.com, http://localhost:*",
+		config.GddCorsAllowedMethods.String():   "",
+		config.GddCorsAllowedHeaders.String():   "",
+		config.GddCorsExposedHeaders.String():   "X-Request-Id",
+		config.GddCorsAllowCredentials.String(): "true",
+		config.GddCorsMaxAge.String():           "10m",
+	})
+	tests := []struct {
+		name       string
+		policy     *CorsPolicy
+		method     string
+		header     map[string]string
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{"no origin", NewCorsPolicy(), "GET", nil, http.StatusOK, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"allowed origin", NewCorsPolicy(), "GET", map[string]string{"Origin": "https://api.example.com"}, http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":      "https://api.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    "X-Request-Id",
+			"Vary":                             "Origin",
+		}},
+		{"wildcard port", NewCorsPolicy(), "GET", map[string]string{"Origin": "http://localhost:8080"}, http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "http://localhost:8080",
+		}},
+		{"disallowed origin", NewCorsPolicy(), "GET", map[string]string{"Origin": "https://example.com.evil.com"}, http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"preflight", NewCorsPolicy(), "OPTIONS", map[string]string{
+			"Origin":                         "https://app.example.com",
+			"Access-Control-Request-Method":  "DELETE",
+			"Access-Control-Request-Headers": "Authorization, Content-Type",
+		}, http.StatusNoContent, map[string]string{
+			"Access-Control-Allow-Origin":  "https://app.example.com",
+			"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, HEAD",
+			"Access-Control-Allow-Headers": "Authorization, Content-Type",
+			"Access-Control-Max-Age":       "600",
+		}},
+		{"preflight disallowed method", NewCorsPolicy(WithCorsMethods("GET")), "OPTIONS", map[string]string{
+			"Origin":                        "https://app.example.com",
+			"Access-Control-Request-Method": "DELETE",
+		}, http.StatusNoContent, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"preflight disallowed header", NewCorsPolicy(WithCorsHeaders("Content-Type")), "OPTIONS", map[string]string{
+			"Origin":                         "https://app.example.com",
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "X-Secret",
+		}, http.StatusNoContent, map[string]string{"Access-Control-Allow-Origin": ""}},
+		{"any origin", NewCorsPolicy(WithCorsOrigins("*"), WithCorsCredentials(false)), "GET", map[string]string{"Origin": "https://evil.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Credentials": ""}},
+		{"any origin with credentials", NewCorsPolicy(WithCorsOrigins("*")), "GET", map[string]string{"Origin": "https://evil.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Credentials": ""}},
+		{"disabled", NewCorsPolicy(WithCorsOrigins()), "GET", map[string]string{"Origin": "https://api.example.com"},
+			http.StatusOK, map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users/1", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			tt.policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("user 1"))
+			})).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for k, v := range tt.wantHeader {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %s, want %s", k, got, v)
+				}
+			}
+		})
+	}
+	if got := NewCorsPolicy(WithCorsMaxAge(time.Minute)).maxAge; got != time.Minute {
+		t.Errorf("options should override environment variables, got max age %s", got)
+	}
+}
+
+func TestCors_Preflight(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddCorsAllowedOrigins.String(): "https://*.example.com",
+	})
+	auth := NewAuth(WithAuthenticators(NewAPIKeyAuthenticator(StaticAPIKeys{})))
+	srvs := map[string]func() Srv{
+		"gorilla": NewDefaultHttpSrv,
+		"chi":     NewChiHttpSrv,
+	}
+	for name, newSrv := range srvs {
+		t.Run(name, func(t *testing.T) {
+			srv := newSrv()
+			srv.AddMiddleware(NewCorsPolicy().Middleware, auth.Middleware)
+			srv.AddRoute(testRoutes()...)
+			req := httptest.NewRequest("OPTIONS", "/users/1", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", "GET")
+			rec := httptest.NewRecorder()
+			srv.(http.Handler).ServeHTTP(rec, req)
+			if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+				t.Errorf("preflight got %d %v", rec.Code, rec.Header())
+			}
+			// the actual request still requires credentials
+			req = httptest.NewRequest("GET", "/users/1", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			rec = httptest.NewRecorder()
+			srv.(http.Handler).ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+				t.Errorf("request got %d %v", rec.Code, rec.Header())
+			}
+		})
+	}
+
+	// OPTIONS routes are not registered if CORS is disabled
+	setEnv(t, map[string]string{
+		config.GddCorsAllowedOrigins.String(): "",
+	})
+	srv := NewDefaultHttpSrv()
+	srv.AddRoute(testRoutes()...)
+	rec := httptest.NewRecorder()
+	srv.(http.Handler).ServeHTTP(rec, httptest.NewRequest("OPTIONS", "/users/1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
@@ -1,22 +1,17 @@
 package ddhttp
 
 import (
-	"context"
 	"crypto/subtle"
-	"github.com/common-nighthawk/go-figure"
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/stringutils"
 	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/health"
 	"github.com/unionj-cloud/go-doudou/svc/http/model"
 	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
 	"github.com/unionj-cloud/go-doudou/svc/http/prometheus"
 	"github.com/urfave/negroni"
 	"net/http"
-	"os"
-	"os/signal"
 	"strings"
-	"time"
 )
 
 // gorilla
@@ -31,11 +26,13 @@ const gddPathPrefix = "/go-doudou"
 func NewDefaultHttpSrv() Srv {
 	var gddRouter *mux.Router
 	var routes []model.Route
+	rootRouter := mux.NewRouter().PathPrefix(config.GddRouteRootPath.Load()).Subrouter().StrictSlash(true)
 	if config.GddManage.Load() == "true" {
 		gddRouter = mux.NewRouter().PathPrefix(config.GddRouteRootPath.Load() + gddPathPrefix).Subrouter().StrictSlash(true)
 		var mergedRoutes []model.Route
 		mergedRoutes = append(mergedRoutes, onlinedoc.Routes()...)
 		mergedRoutes = append(mergedRoutes, prometheus.Routes()...)
+		mergedRoutes = append(mergedRoutes, health.Routes()...)
 		for _, item := range mergedRoutes {
 			gddRouter.
 				Methods(item.Method).
@@ -44,9 +41,22 @@ func NewDefaultHttpSrv() Srv {
 				Handler(item.HandlerFunc)
 		}
 		routes = append(routes, mergedRoutes...)
+		// probes are matched before the basic auth protected gddRouter
+		for _, item := range health.ProbeRoutes() {
+			rootRouter.
+				Methods(item.Method).
+				Path(item.Pattern).
+				Name(item.Name).
+				Handler(item.HandlerFunc)
+		}
+		routes = append(routes, health.ProbeRoutes()...)
+	}
+	// the metrics middleware is used once here, so that requests are not counted twice if AddMiddleware is called again
+	if config.GddManage.Load() == "true" {
+		rootRouter.Use(prometheus.Middleware(RouteName))
 	}
 	return &DefaultHttpSrv{
-		mux.NewRouter().PathPrefix(config.GddRouteRootPath.Load()).Subrouter().StrictSlash(true),
+		rootRouter,
 		gddRouter,
 		routes,
 	}
@@ -58,7 +68,8 @@ func (srv *DefaultHttpSrv) AddRoute(route ...model.Route) {
 	routes = append(routes, srv.routes...)
 	srv.routes = routes[:]
 	routes = nil
-	for _, item := range route {
+	// preflight routes are registered first, so that routes got by name are the original ones
+	for _, item := range append(preflightRoutes(route), route...) {
 		srv.
 			Methods(item.Method).
 			Path(item.Pattern).
@@ -87,9 +98,6 @@ func BasicAuth(w http.ResponseWriter, r *http.Request) bool {
 }
 
 func (srv *DefaultHttpSrv) AddMiddleware(mwf ...func(http.Handler) http.Handler) {
-	if config.GddManage.Load() == "true" {
-		srv.Use(prometheus.PrometheusMiddleware)
-	}
 	var middlewares []mux.MiddlewareFunc
 	for _, item := range mwf {
 		middlewares = append(middlewares, item)
@@ -108,62 +116,5 @@ func (srv *DefaultHttpSrv) AddMiddleware(mwf ...func(http.Handler) http.Handler)
 }
 
 func (srv *DefaultHttpSrv) Run() {
-	start := time.Now()
-	var logptr *string
-	logpath, isSet := os.LookupEnv(config.GddLogPath.String())
-	if isSet {
-		logptr = &logpath
-	}
-	var loglevel config.LogLevel
-	(&loglevel).Decode(config.GddLogLevel.Load())
-
-	logFile := configureLogger(logrus.StandardLogger(), logptr, logrus.Level(loglevel))
-	defer func() {
-		if logFile != nil {
-			logFile.Close()
-		}
-	}()
-
-	var bannerSwitch config.Switch
-	(&bannerSwitch).Decode(config.GddBanner.Load())
-	if bannerSwitch {
-		banner := config.GddBannerText.Load()
-		if stringutils.IsEmpty(banner) {
-			banner = "Go-doudou"
-		}
-		figure.NewColorFigure(banner, "doom", "green", true).Print()
-	}
-
-	printRoutes(srv.routes)
-
-	server := newServer(srv)
-
-	logrus.Infof("Started in %s\n", time.Since(start))
-
-	c := make(chan os.Signal, 1)
-	// We'll accept graceful shutdowns when quit via SIGINT (Ctrl+C)
-	// SIGKILL, SIGQUIT or SIGTERM (Ctrl+/) will not be caught.
-	signal.Notify(c, os.Interrupt)
-
-	// Block until we receive our signal.
-	<-c
-
-	// Create a deadline to wait for.
-	grace, err := time.ParseDuration(config.GddGraceTimeout.Load())
-	if err != nil {
-		logrus.Warnf("Parse %s %s as time.Duration failed: %s, use default 15s instead.\n", "GDD_GRACETIMEOUT",
-			config.GddGraceTimeout.Load(), err.Error())
-		grace = 15 * time.Second
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), grace)
-	defer cancel()
-	// Doesn't block if no connections, but will otherwise wait
-	// until the timeout deadline.
-	server.Shutdown(ctx)
-
-	// Optionally, you could run srv.Shutdown in a goroutine and block on
-	// <-ctx.Done() if your application should wait for other services
-	// to finalize based on context cancellation.
-	logrus.Infoln("shutting down")
+	run(srv, srv.routes)
 }
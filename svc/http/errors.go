@@ -0,0 +1,119 @@
+package ddhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// HttpError is an error with application defined error code, message, details and http status code.
+// Service implementations can return it as error result, then generated http handlers will respond
+// with its status code and ErrorEnvelope json body, and generated go clients will decode it back.
+type HttpError struct {
+	// Status is http status code, default to 500
+	Status int `json:"-"`
+	// Code is application defined error code
+	Code int `json:"code"`
+	// Message is human readable error message
+	Message string `json:"message"`
+	// Details is any additional information about the error, e.g. invalid fields
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("status: %d, code: %d, message: %s", e.Status, e.Code, e.Message)
+}
+
+// WithDetails sets details of the error and returns it
+func (e *HttpError) WithDetails(details interface{}) *HttpError {
+	e.Details = details
+	return e
+}
+
+// NewHttpError creates a HttpError
+func NewHttpError(status int, code int, message string) *HttpError {
+	return &HttpError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// ErrorEnvelope is json body of error responses, e.g. {"error":{"code":404,"message":"user not found"}}
+type ErrorEnvelope struct {
+	Error *HttpError `json:"error"`
+}
+
+// ToHttpError converts err to *HttpError. If err is or wraps a *HttpError, it will be returned, or a copy of it
+// with status 500 if its status is not set, as it may be shared by concurrent requests.
+// context.Canceled is converted to 400, reading request bodies beyond the limit of BodyLimit is converted to 413,
+// and any other errors are converted to 500.
+// Error code is the same as http status code if not *HttpError
+func ToHttpError(err error) *HttpError {
+	var herr *HttpError
+	if errors.As(err, &herr) {
+		if herr.Status == 0 {
+			copied := *herr
+			copied.Status = http.StatusInternalServerError
+			return &copied
+		}
+		return herr
+	}
+	status := http.StatusInternalServerError
+	if errors.Is(err, context.Canceled) {
+		status = http.StatusBadRequest
+	} else if isBodyTooLarge(err) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	return NewHttpError(status, status, err.Error())
+}
+
+// HandleError writes err as ErrorEnvelope json body with http status code of the error
+func HandleError(w http.ResponseWriter, err error) {
+	herr := ToHttpError(err)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(herr.Status)
+	json.NewEncoder(w).Encode(ErrorEnvelope{
+		Error: herr,
+	})
+}
+
+// HandleBadRequest writes err as ErrorEnvelope json body with 400 status code unless err is or wraps a *HttpError,
+// or is returned from reading request bodies beyond the limit of BodyLimit, which is 413
+func HandleBadRequest(w http.ResponseWriter, err error) {
+	var herr *HttpError
+	if isBodyTooLarge(err) {
+		herr = NewHttpError(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, err.Error())
+	} else if !errors.As(err, &herr) {
+		herr = NewHttpError(http.StatusBadRequest, http.StatusBadRequest, err.Error())
+	}
+	HandleError(w, herr)
+}
+
+// DecodeError decodes error response into *HttpError.
+// If the response body is not ErrorEnvelope json, an error with body as message is returned.
+// Raw body of the response is read and closed if the request is sent with SetDoNotParseResponse(true)
+func DecodeError(resp *resty.Response) error {
+	body := resp.Body()
+	if len(body) == 0 && resp.RawBody() != nil {
+		body, _ = ioutil.ReadAll(resp.RawBody())
+		resp.RawBody().Close()
+	}
+	var envelope ErrorEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error == nil {
+		msg := strings.TrimSpace(string(body))
+		if stringutils.IsEmpty(msg) {
+			msg = resp.Status()
+		}
+		return errors.New(msg)
+	}
+	envelope.Error.Status = resp.StatusCode()
+	return envelope.Error
+}
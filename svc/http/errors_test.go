@@ -0,0 +1,132 @@
+package ddhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+func TestToHttpError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   int
+	}{
+		{
+			name:       "http error",
+			err:        NewHttpError(http.StatusNotFound, 10404, "user not found"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   10404,
+		},
+		{
+			name:       "wrapped http error",
+			err:        errors.Wrap(NewHttpError(http.StatusConflict, 10409, "user exists"), "create user"),
+			wantStatus: http.StatusConflict,
+			wantCode:   10409,
+		},
+		{
+			name:       "http error without status",
+			err:        &HttpError{Code: 1, Message: "oops"},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   1,
+		},
+		{
+			name:       "canceled",
+			err:        context.Canceled,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			err:        errors.Wrap(errors.New(errBodyTooLarge), "multipart: NextPart"),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "other error",
+			err:        errors.New("oops"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToHttpError(tt.err)
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode {
+				t.Errorf("ToHttpError() = %d %d, want %d %d", got.Status, got.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestToHttpError_Shared(t *testing.T) {
+	errNotFound := &HttpError{Code: 10404, Message: "user not found"}
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			if got := ToHttpError(errors.Wrap(errNotFound, "get user")); got.Status != http.StatusInternalServerError || got.Code != 10404 {
+				t.Errorf("ToHttpError() = %d %d", got.Status, got.Code)
+			}
+		}()
+	}
+	<-done
+	<-done
+	if errNotFound.Status != 0 {
+		t.Errorf("ToHttpError() should not change the original error, got status %d", errNotFound.Status)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/biz":
+			HandleError(w, NewHttpError(http.StatusNotFound, 10404, "user not found").WithDetails([]string{"id"}))
+		case "/bad":
+			HandleBadRequest(w, errors.New("invalid id"))
+		default:
+			http.Error(w, "plain text", http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+
+	client := resty.New()
+	tests := []struct {
+		path        string
+		wantStatus  int
+		wantCode    int
+		wantMessage string
+	}{
+		{"/biz", http.StatusNotFound, 10404, "user not found"},
+		{"/bad", http.StatusBadRequest, http.StatusBadRequest, "invalid id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := client.R().Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var herr *HttpError
+			if !errors.As(DecodeError(resp), &herr) {
+				t.Fatalf("DecodeError() should return *HttpError")
+			}
+			if herr.Status != tt.wantStatus || herr.Code != tt.wantCode || herr.Message != tt.wantMessage {
+				t.Errorf("DecodeError() = %+v", herr)
+			}
+		})
+	}
+
+	resp, err := client.R().Get(ts.URL + "/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var herr *HttpError
+	if err = DecodeError(resp); errors.As(err, &herr) || err.Error() != "plain text" {
+		t.Errorf("DecodeError() = %v", err)
+	}
+}
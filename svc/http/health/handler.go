@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+// Routes returns the health route which responds results of all checks with errors, it is protected by basic auth
+// as other routes under /go-doudou
+func Routes() []model.Route {
+	return []model.Route{
+		{
+			Name:        "Health",
+			Method:      "GET",
+			Pattern:     "/go-doudou/health",
+			HandlerFunc: handle(defaultRegistry.Health, false),
+		},
+	}
+}
+
+// ProbeRoutes returns liveness and readiness routes for probes of orchestrators like kubernetes.
+// They are not protected by basic auth, so errors of checks are not responded
+func ProbeRoutes() []model.Route {
+	return []model.Route{
+		{
+			Name:        "Livez",
+			Method:      "GET",
+			Pattern:     "/go-doudou/livez",
+			HandlerFunc: handle(defaultRegistry.Liveness, true),
+		},
+		{
+			Name:        "Readyz",
+			Method:      "GET",
+			Pattern:     "/go-doudou/readyz",
+			HandlerFunc: handle(defaultRegistry.Readiness, true),
+		},
+	}
+}
+
+// handle responds result as json with status code 200 if up, otherwise 503
+func handle(run func(ctx context.Context) Result, brief bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := run(r.Context())
+		if brief {
+			for name, item := range result.Checks {
+				item.Error = ""
+				result.Checks[name] = item
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if result.Status != StatusUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,248 @@
+// Package health serves health, liveness and readiness of the service under /go-doudou
+// by aggregating named checks
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+)
+
+// DefaultTimeout is the timeout of each check if not set by WithTimeout
+const DefaultTimeout = 3 * time.Second
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check reports the dependency is unhealthy by returning an error. It should return when ctx is done
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Result is the aggregated status of checks, which is down if any of checks is down
+type Result struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type check struct {
+	fn       Check
+	timeout  time.Duration
+	liveness bool
+}
+
+type CheckOption func(*check)
+
+// WithTimeout sets timeout of the check, DefaultTimeout by default
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// WithLiveness makes the check part of liveness as well as readiness. Failed liveness checks get the service restarted
+// by orchestrators like kubernetes, so only use it for failures which cannot recover without restarting
+func WithLiveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// Registry keeps named checks
+type Registry struct {
+	mu           sync.RWMutex
+	checks       map[string]*check
+	shuttingDown int32
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		checks: make(map[string]*check),
+	}
+}
+
+// Register adds check by name, the check with the same name is replaced
+func (r *Registry) Register(name string, fn Check, opts ...CheckOption) {
+	c := &check{
+		fn:      fn,
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// SetShuttingDown makes readiness fail, so that no more requests are routed to the service during graceful shutdown
+func (r *Registry) SetShuttingDown() {
+	atomic.StoreInt32(&r.shuttingDown, 1)
+}
+
+func (r *Registry) ShuttingDown() bool {
+	return atomic.LoadInt32(&r.shuttingDown) == 1
+}
+
+// Health runs all checks concurrently
+func (r *Registry) Health(ctx context.Context) Result {
+	return r.run(ctx, func(c *check) bool {
+		return true
+	})
+}
+
+// Liveness runs checks registered with WithLiveness, it is up if there is none
+func (r *Registry) Liveness(ctx context.Context) Result {
+	return r.run(ctx, func(c *check) bool {
+		return c.liveness
+	})
+}
+
+// Readiness runs all checks, and is down once SetShuttingDown is called
+func (r *Registry) Readiness(ctx context.Context) Result {
+	result := r.Health(ctx)
+	if r.ShuttingDown() {
+		result.Status = StatusDown
+		if result.Checks == nil {
+			result.Checks = make(map[string]CheckResult)
+		}
+		result.Checks["shutdown"] = CheckResult{
+			Status:   StatusDown,
+			Error:    "service is shutting down",
+			Duration: "0s",
+		}
+	}
+	return result
+}
+
+func (r *Registry) run(ctx context.Context, filter func(c *check) bool) Result {
+	r.mu.RLock()
+	var names []string
+	checks := make(map[string]*check)
+	for name, c := range r.checks {
+		if filter(c) {
+			names = append(names, name)
+			checks[name] = c
+		}
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	result := Result{
+		Status: StatusUp,
+	}
+	for i, name := range names {
+		if result.Checks == nil {
+			result.Checks = make(map[string]CheckResult)
+		}
+		result.Checks[name] = results[i]
+		if results[i].Status == StatusDown {
+			result.Status = StatusDown
+		}
+	}
+	return result
+}
+
+// runCheck returns once the check times out even if it ignores ctx
+func runCheck(ctx context.Context, c *check) CheckResult {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				done <- errors.Errorf("panic: %v", e)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check not finished")
+	}
+	result := CheckResult{
+		Status:   StatusUp,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Pinger is implemented by *sql.DB and *sqlx.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// DbCheck checks the database is reachable, e.g. health.Register("db", health.DbCheck(conn)) in main.go
+func DbCheck(db Pinger) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MemberlistCheck checks node is alive, and has joined the cluster if GDD_SEED is set
+func MemberlistCheck(node *registry.Node) Check {
+	return func(ctx context.Context) error {
+		if state := node.State(); state != registry.Alive {
+			return errors.Errorf("node is %s", state)
+		}
+		if config.GddSeed.Load() != "" && node.NumNodes() < 2 {
+			return errors.New("node is not in any cluster")
+		}
+		return nil
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry served by /go-doudou/health, /go-doudou/livez and /go-doudou/readyz
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds check to DefaultRegistry
+func Register(name string, fn Check, opts ...CheckOption) {
+	defaultRegistry.Register(name, fn, opts...)
+}
+
+// SetShuttingDown makes readiness of DefaultRegistry fail
+func SetShuttingDown() {
+	defaultRegistry.SetShuttingDown()
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if result := r.Health(context.Background()); result.Status != StatusUp || result.Checks != nil {
+		t.Errorf("health without checks should be up, got %+v", result)
+	}
+	r.Register("db", func(ctx context.Context) error {
+		return nil
+	}, WithLiveness())
+	r.Register("cache", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	r.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, WithTimeout(10*time.Millisecond))
+	r.Register("panic", func(ctx context.Context) error {
+		panic("oops")
+	})
+
+	start := time.Now()
+	result := r.Health(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("slow checks should time out")
+	}
+	want := map[string]Status{"db": StatusUp, "cache": StatusDown, "slow": StatusDown, "panic": StatusDown}
+	if result.Status != StatusDown || len(result.Checks) != len(want) {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	for name, status := range want {
+		if result.Checks[name].Status != status {
+			t.Errorf("status of %s = %s, want %s", name, result.Checks[name].Status, status)
+		}
+	}
+	if result.Checks["cache"].Error != "connection refused" || result.Checks["panic"].Error != "panic: oops" {
+		t.Errorf("unexpected errors %+v", result.Checks)
+	}
+
+	if result := r.Liveness(context.Background()); result.Status != StatusUp || len(result.Checks) != 1 {
+		t.Errorf("liveness should only run liveness checks, got %+v", result)
+	}
+
+	r.Unregister("cache")
+	r.Unregister("slow")
+	r.Unregister("panic")
+	if result := r.Readiness(context.Background()); result.Status != StatusUp {
+		t.Errorf("readiness should be up, got %+v", result)
+	}
+	r.SetShuttingDown()
+	if result := r.Readiness(context.Background()); result.Status != StatusDown || result.Checks["shutdown"].Status != StatusDown {
+		t.Errorf("readiness should be down during shutdown, got %+v", result)
+	}
+	if result := r.Liveness(context.Background()); result.Status != StatusUp {
+		t.Errorf("liveness should not be affected by shutdown, got %+v", result)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	run := func(ctx context.Context) Result {
+		return Result{
+			Status: StatusDown,
+			Checks: map[string]CheckResult{
+				"db": {Status: StatusDown, Error: "dial tcp 10.0.0.1:3306: i/o timeout"},
+			},
+		}
+	}
+	for _, brief := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		handle(run, brief)(rec, httptest.NewRequest("GET", "/go-doudou/readyz", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d", rec.Code)
+		}
+		var result Result
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if (result.Checks["db"].Error == "") != brief {
+			t.Errorf("errors of checks should be responded unless brief, got %s", rec.Body.String())
+		}
+	}
+}
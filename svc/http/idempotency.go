@@ -0,0 +1,282 @@
+package ddhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying idempotency keys generated by clients, e.g. uuid
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to true in responses replayed from IdempotencyStore
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotentBodySize is the max size of response bodies stored for replay, larger responses are not stored
+	maxIdempotentBodySize = 1 << 20
+	maxIdempotencyKeyLen  = 255
+)
+
+// ErrIdempotencyInFlight is returned by IdempotencyStore.Begin if a request with the same key is being processed
+var ErrIdempotencyInFlight = errors.New("request with the same idempotency key is in progress")
+
+// StoredResponse is the first response of requests with the same idempotency key
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Fingerprint is hash of the request, replay is refused if the request with the same key has a different one
+	Fingerprint string
+}
+
+// IdempotencyStore stores responses by idempotency key, e.g. in redis by SET NX for multiple instances of the service
+type IdempotencyStore interface {
+	// Begin reserves key for the request until Complete or Release is called or ttl expires.
+	// It returns the stored response if the key is completed, ErrIdempotencyInFlight if the key is reserved,
+	// or nil response and nil error if the request should be processed
+	Begin(ctx context.Context, key string, ttl time.Duration) (*StoredResponse, error)
+	// Complete stores response of key, which expires after ttl
+	Complete(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error
+	// Release removes reservation of key so that the request can be retried, e.g. after 5xx responses
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	resp    *StoredResponse
+	expires time.Time
+}
+
+// MemoryIdempotencyStore is IdempotencyStore in memory, which only dedups requests to the same instance of the service
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	swept   time.Time
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		swept:   time.Now(),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryIdempotencyStore) Begin(ctx context.Context, key string, ttl time.Duration) (*StoredResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	if entry, ok := m.entries[key]; ok && !now.After(entry.expires) {
+		if entry.resp == nil {
+			return nil, ErrIdempotencyInFlight
+		}
+		return entry.resp, nil
+	}
+	m.entries[key] = &idempotencyEntry{
+		expires: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// sweep drops expired entries every minute, so that the map doesn't grow unbounded
+func (m *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for key, entry := range m.entries {
+		if now.After(entry.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+func (m *MemoryIdempotencyStore) Complete(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = &idempotencyEntry{
+		resp:    resp,
+		expires: m.now().Add(ttl),
+	}
+	return nil
+}
+
+func (m *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// Idempotency is a middleware deduplicating requests of routes with the same Idempotency-Key header.
+// The first response is stored and replayed for duplicates, concurrent duplicates get 409
+type Idempotency struct {
+	routes  map[string]bool
+	store   IdempotencyStore
+	ttl     time.Duration
+	maxBody int64
+}
+
+type IdempotencyOption func(*Idempotency)
+
+// WithIdempotentRoutes deduplicates requests of the routes named as RouteName returns
+func WithIdempotentRoutes(routes ...string) IdempotencyOption {
+	return func(i *Idempotency) {
+		for _, route := range routes {
+			i.routes[route] = true
+		}
+	}
+}
+
+// WithIdempotencyStore sets IdempotencyStore, MemoryIdempotencyStore by default
+func WithIdempotencyStore(store IdempotencyStore) IdempotencyOption {
+	return func(i *Idempotency) {
+		i.store = store
+	}
+}
+
+// WithIdempotencyTTL sets how long responses are stored, 24 hours by default
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(i *Idempotency) {
+		i.ttl = ttl
+	}
+}
+
+// NewIdempotency creates Idempotency. Generated IdempotencyOptions function in transport/httpsrv returns options declared
+// by @idempotent annotations in svc.go
+func NewIdempotency(opts ...IdempotencyOption) *Idempotency {
+	i := &Idempotency{
+		routes:  make(map[string]bool),
+		store:   NewMemoryIdempotencyStore(),
+		ttl:     24 * time.Hour,
+		maxBody: maxBody(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// fingerprint returns hash of method, uri and body of r, and restores the body for handlers.
+// Bodies larger than max are not read into memory, unless max is 0
+func fingerprint(r *http.Request, max int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	if r.Body != nil {
+		var reader io.Reader = r.Body
+		if max > 0 {
+			reader = io.LimitReader(r.Body, max+1)
+		}
+		body, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		if max > 0 && int64(len(body)) > max {
+			return "", errors.New(errBodyTooLarge)
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Middleware should be added after ddhttp.Auth, as keys are scoped by route and the principal if any.
+// Responses of 5xx or larger than 1MB are not stored, so that requests can be retried with the same key.
+// Requests with bodies larger than the limit of the route set by BodyLimit, or GDD_MAX_BODY if BodyLimit is not added
+// before Idempotency, get 413
+func (i *Idempotency) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := RouteName(r)
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if !i.routes[route] || stringutils.IsEmpty(key) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			HandleError(w, NewHttpError(http.StatusBadRequest, http.StatusBadRequest, "idempotency key is too long"))
+			return
+		}
+		scoped := route + ":" + key
+		if principal, ok := PrincipalFrom(r.Context()); ok {
+			scoped = route + ":" + principal.Subject + ":" + key
+		}
+		max, ok := bodyLimitOf(r)
+		if !ok {
+			max = i.maxBody
+		}
+		fp, err := fingerprint(r, max)
+		if err != nil {
+			HandleBadRequest(w, errors.Wrap(err, "read request body failed"))
+			return
+		}
+		ctx := r.Context()
+		stored, err := i.store.Begin(ctx, scoped, i.ttl)
+		switch {
+		case errors.Is(err, ErrIdempotencyInFlight):
+			HandleError(w, NewHttpError(http.StatusConflict, http.StatusConflict, err.Error()))
+			return
+		case err != nil:
+			logrus.Errorf("begin idempotent request of route %s failed: %v", route, err)
+			HandleError(w, NewHttpError(http.StatusServiceUnavailable, http.StatusServiceUnavailable, "idempotency store unavailable"))
+			return
+		case stored != nil:
+			if stored.Fingerprint != fp {
+				HandleError(w, NewHttpError(http.StatusUnprocessableEntity, http.StatusUnprocessableEntity,
+					"idempotency key is reused with a different request"))
+				return
+			}
+			for k, v := range stored.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		before := w.Header().Clone()
+		bw := &bufferedWriter{ResponseWriter: w, limit: maxIdempotentBodySize}
+		completed := false
+		defer func() {
+			if !completed {
+				if err := i.store.Release(context.Background(), scoped); err != nil {
+					logrus.Errorf("release idempotency key of route %s failed: %v", route, err)
+				}
+			}
+		}()
+		inner.ServeHTTP(bw, r)
+		if bw.passthrough {
+			return
+		}
+		status := bw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status < http.StatusInternalServerError {
+			resp := &StoredResponse{
+				Status:      status,
+				Header:      headerDiff(before, w.Header()),
+				Body:        append([]byte(nil), bw.buf.Bytes()...),
+				Fingerprint: fp,
+			}
+			if err = i.store.Complete(context.Background(), scoped, resp, i.ttl); err != nil {
+				logrus.Errorf("store response of idempotent request of route %s failed: %v", route, err)
+			} else {
+				completed = true
+			}
+		}
+		w.WriteHeader(status)
+		w.Write(bw.buf.Bytes())
+	})
+}
@@ -0,0 +1,168 @@
+package ddhttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+func TestIdempotency(t *testing.T) {
+	var orders, failures int32
+	started, release := make(chan struct{}), make(chan struct{})
+	routes := testRoutes()
+	routes[1].HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch string(body) {
+		case "slow":
+			close(started)
+			<-release
+		case "fail":
+			if atomic.AddInt32(&failures, 1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		id := atomic.AddInt32(&orders, 1)
+		w.Header().Set("Location", "/orders/"+strconv.Itoa(int(id)))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("order " + strconv.Itoa(int(id))))
+	}
+	idempotency := NewIdempotency(WithIdempotentRoutes("PageUsers"))
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(WithPrincipal(r.Context(), &Principal{Subject: user}))
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}, idempotency.Middleware)
+	srv.AddRoute(routes...)
+	post := func(key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		srv.(http.Handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("key1", "jack", "apple")
+	if rec.Code != http.StatusCreated || rec.Body.String() != "order 1" {
+		t.Fatalf("got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = post("key1", "jack", "apple")
+	if rec.Code != http.StatusCreated || rec.Body.String() != "order 1" || rec.Header().Get("Location") != "/orders/1" ||
+		rec.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("duplicate should be replayed, got %d %s %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if rec = post("key1", "jack", "banana"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused with a different body should get 422, got %d", rec.Code)
+	}
+	if rec = post("key1", "rose", "apple"); rec.Body.String() != "order 2" {
+		t.Errorf("keys should be scoped by principal, got %s", rec.Body.String())
+	}
+	if rec = post("", "jack", "apple"); rec.Body.String() != "order 3" {
+		t.Errorf("requests without key should pass through, got %s", rec.Body.String())
+	}
+
+	// 5xx responses are not stored, so the request can be retried with the same key
+	if rec = post("key2", "jack", "fail"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("got %d, want 500", rec.Code)
+	}
+	if rec = post("key2", "jack", "fail"); rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("retry after 5xx should be processed, got %d %v", rec.Code, rec.Header())
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- post("key3", "jack", "slow")
+	}()
+	<-started
+	if rec = post("key3", "jack", "slow"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "in progress") {
+		t.Errorf("concurrent duplicate should get 409, got %d %s", rec.Code, rec.Body.String())
+	}
+	close(release)
+	if rec = <-done; rec.Code != http.StatusCreated {
+		t.Errorf("got %d, want 201", rec.Code)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore()
+	now := time.Now()
+	store.now = func() time.Time {
+		return now
+	}
+	if resp, err := store.Begin(ctx, "key", time.Minute); resp != nil || err != nil {
+		t.Fatalf("Begin() = %v, %v", resp, err)
+	}
+	if _, err := store.Begin(ctx, "key", time.Minute); err != ErrIdempotencyInFlight {
+		t.Errorf("Begin() error = %v, want ErrIdempotencyInFlight", err)
+	}
+	store.Complete(ctx, "key", &StoredResponse{Status: http.StatusCreated}, time.Minute)
+	if resp, _ := store.Begin(ctx, "key", time.Minute); resp == nil || resp.Status != http.StatusCreated {
+		t.Errorf("Begin() = %v, want stored response", resp)
+	}
+	store.Begin(ctx, "other", time.Second)
+	now = now.Add(time.Minute + time.Second)
+	if resp, err := store.Begin(ctx, "key", time.Minute); resp != nil || err != nil {
+		t.Errorf("expired key should be reserved again, got %v, %v", resp, err)
+	}
+	if _, ok := store.entries["other"]; ok {
+		t.Error("expired entries should be swept")
+	}
+}
+
+func TestIdempotency_MaxBody(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddMaxBody.String(): "10",
+	})
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(NewIdempotency(WithIdempotentRoutes("PageUsers")).Middleware)
+	srv.AddRoute(testRoutes()...)
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(strings.Repeat("a", 20)))
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	rec := httptest.NewRecorder()
+	srv.(http.Handler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d, want 413", rec.Code)
+	}
+}
+
+func TestIdempotency_RouteMaxBody(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddMaxBody.String(): "10",
+	})
+	srv := NewDefaultHttpSrv()
+	srv.AddMiddleware(NewBodyLimit(WithRouteMaxBody("PageUsers", 100)).Middleware,
+		NewIdempotency(WithIdempotentRoutes("PageUsers")).Middleware)
+	srv.AddRoute(testRoutes()...)
+	tests := []struct {
+		size       int
+		wantStatus int
+	}{
+		{20, http.StatusOK},
+		{200, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(strings.Repeat("a", tt.size)))
+		// chunked bodies are limited by reading
+		req.ContentLength = -1
+		req.Header.Set(IdempotencyKeyHeader, "key"+strconv.Itoa(tt.size))
+		rec := httptest.NewRecorder()
+		srv.(http.Handler).ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("body of %d bytes got %d, want %d", tt.size, rec.Code, tt.wantStatus)
+		}
+	}
+}
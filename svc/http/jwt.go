@@ -0,0 +1,335 @@
+package ddhttp
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+)
+
+// JWTAuthenticator authenticates requests by jwt in Authorization header with Bearer scheme.
+// HS256, HS384 and HS512 tokens are verified by the secret set by WithHMACSecret, RS256, RS384 and RS512 tokens
+// are verified by keys in the jwks file set by WithJWKSFile. Tokens of other algorithms are rejected
+type JWTAuthenticator struct {
+	secret     []byte
+	jwksFile   string
+	issuer     string
+	audience   string
+	rolesClaim string
+	leeway     time.Duration
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	modTime time.Time
+}
+
+type JWTOption func(*JWTAuthenticator)
+
+// WithHMACSecret accepts HS256, HS384 and HS512 tokens signed by secret
+func WithHMACSecret(secret []byte) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.secret = secret
+	}
+}
+
+// WithJWKSFile accepts RS256, RS384 and RS512 tokens signed by RSA keys in the jwks file, which is json like
+// {"keys":[{"kty":"RSA","kid":"...","n":"...","e":"AQAB"}]}. The file is reloaded if a token has an unknown kid
+// and the file has been modified, so keys can be rotated without restarting the service
+func WithJWKSFile(file string) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.jwksFile = file
+	}
+}
+
+// WithJWTIssuer requires iss claim of tokens to be issuer
+func WithJWTIssuer(issuer string) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.issuer = issuer
+	}
+}
+
+// WithJWTAudience requires aud claim of tokens to contain audience
+func WithJWTAudience(audience string) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.audience = audience
+	}
+}
+
+// WithJWTRolesClaim sets the claim holding roles of the principal, 'roles' by default.
+// The claim is either an array of strings or a space separated string like 'scope' claim
+func WithJWTRolesClaim(claim string) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.rolesClaim = claim
+	}
+}
+
+// WithJWTLeeway tolerates clock skew when checking exp and nbf claims, 1 minute by default
+func WithJWTLeeway(leeway time.Duration) JWTOption {
+	return func(j *JWTAuthenticator) {
+		j.leeway = leeway
+	}
+}
+
+// NewJWTAuthenticator creates JWTAuthenticator, it returns error if neither WithHMACSecret nor WithJWKSFile is used
+// or the jwks file cannot be loaded
+func NewJWTAuthenticator(opts ...JWTOption) (*JWTAuthenticator, error) {
+	j := &JWTAuthenticator{
+		rolesClaim: "roles",
+		leeway:     time.Minute,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	if len(j.secret) == 0 && stringutils.IsEmpty(j.jwksFile) {
+		return nil, errors.New("NewJWTAuthenticator() error: either hmac secret or jwks file is required")
+	}
+	if stringutils.IsNotEmpty(j.jwksFile) {
+		if err := j.loadKeys(); err != nil {
+			return nil, errors.Wrap(err, "NewJWTAuthenticator() error")
+		}
+	}
+	return j, nil
+}
+
+func (j *JWTAuthenticator) Challenge() string {
+	return "Bearer"
+}
+
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return nil, nil
+	}
+	claims, err := j.verify(strings.TrimSpace(authorization[7:]))
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{
+		Claims: claims,
+	}
+	principal.Subject, _ = claims["sub"].(string)
+	switch roles := claims[j.rolesClaim].(type) {
+	case string:
+		principal.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, s)
+			}
+		}
+	}
+	return principal, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks signature and registered claims of token, and returns its claims
+func (j *JWTAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrInvalidCredentials, "malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(ErrInvalidCredentials, "malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidCredentials, "malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if err = j.verifySignature(header, signed, signature); err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(ErrInvalidCredentials, "malformed token claims")
+	}
+	if err = j.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// jwtHashes are hash functions of supported algorithms
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// verifySignature only verifies HS tokens by the hmac secret and RS tokens by the jwks keys,
+// so that a RSA public key is never taken as a hmac secret
+func (j *JWTAuthenticator) verifySignature(header jwtHeader, signed, signature []byte) error {
+	h, ok := jwtHashes[header.Alg]
+	switch {
+	case !ok:
+	case strings.HasPrefix(header.Alg, "HS") && len(j.secret) > 0:
+		mac := hmac.New(h.New, j.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.Wrap(ErrInvalidCredentials, "invalid token signature")
+		}
+		return nil
+	case strings.HasPrefix(header.Alg, "RS") && stringutils.IsNotEmpty(j.jwksFile):
+		key, err := j.keyOf(header.Kid)
+		if err != nil {
+			return err
+		}
+		digest := h.New()
+		digest.Write(signed)
+		if err = rsa.VerifyPKCS1v15(key, h, digest.Sum(nil), signature); err != nil {
+			return errors.Wrap(ErrInvalidCredentials, "invalid token signature")
+		}
+		return nil
+	}
+	return errors.Wrapf(ErrInvalidCredentials, "not support %s token", header.Alg)
+}
+
+func (j *JWTAuthenticator) verifyClaims(claims map[string]interface{}) error {
+	now := j.now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(j.leeway)) {
+		return errors.Wrap(ErrInvalidCredentials, "token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(j.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.Wrap(ErrInvalidCredentials, "token not valid yet")
+	}
+	if stringutils.IsNotEmpty(j.issuer) {
+		if iss, _ := claims["iss"].(string); iss != j.issuer {
+			return errors.Wrap(ErrInvalidCredentials, "unexpected token issuer")
+		}
+	}
+	if stringutils.IsNotEmpty(j.audience) {
+		var found bool
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == j.audience
+		case []interface{}:
+			for _, item := range aud {
+				if item == j.audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return errors.Wrap(ErrInvalidCredentials, "unexpected token audience")
+		}
+	}
+	return nil
+}
+
+// keyOf returns the key by kid, or the only key if kid is empty. The jwks file is reloaded once modified
+// if the key is not found
+func (j *JWTAuthenticator) keyOf(kid string) (*rsa.PublicKey, error) {
+	if key := j.lookup(kid); key != nil {
+		return key, nil
+	}
+	info, err := os.Stat(j.jwksFile)
+	if err == nil && info.ModTime().After(j.loadedAt()) {
+		if err = j.loadKeys(); err != nil {
+			return nil, errors.Wrap(err, "reload jwks failed")
+		}
+		if key := j.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrInvalidCredentials, "unknown key id %s", kid)
+}
+
+func (j *JWTAuthenticator) lookup(kid string) *rsa.PublicKey {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if stringutils.IsEmpty(kid) && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key
+		}
+	}
+	return j.keys[kid]
+}
+
+func (j *JWTAuthenticator) loadedAt() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.modTime
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadKeys loads RSA keys from the jwks file, keys of other types are ignored
+func (j *JWTAuthenticator) loadKeys() error {
+	info, err := os.Stat(j.jwksFile)
+	if err != nil {
+		return errors.Wrap(err, "stat jwks file failed")
+	}
+	b, err := ioutil.ReadFile(j.jwksFile)
+	if err != nil {
+		return errors.Wrap(err, "read jwks file failed")
+	}
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(b, &jwks); err != nil {
+		return errors.Wrap(err, "jwks file is not valid json")
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, item := range jwks.Keys {
+		if item.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(item.N)
+		if err != nil {
+			return errors.Wrapf(err, "invalid modulus of key %s", item.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(item.E)
+		if err != nil {
+			return errors.Wrapf(err, "invalid exponent of key %s", item.Kid)
+		}
+		keys[item.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return errors.Errorf("no RSA key found in %s", j.jwksFile)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys = keys
+	j.modTime = info.ModTime()
+	return nil
+}
@@ -0,0 +1,266 @@
+package ddhttp
+
+import (
+	"context"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Server is a candidate server for load balancing
+type Server struct {
+	BaseUrl string
+	// Weight is used by WeightedRoundRobin, values less than 1 are treated as 1
+	Weight int
+}
+
+func (s Server) weight() int {
+	if s.Weight > 0 {
+		return s.Weight
+	}
+	return 1
+}
+
+// ServerLister is implemented by service providers which can list all servers of the service,
+// so that generated clients can pick servers by their own LoadBalancer set by WithLoadBalancer
+type ServerLister interface {
+	Servers() ([]Server, error)
+}
+
+// LoadBalancer picks a server from servers for each request. servers is never empty,
+// and implementations must be safe for concurrent use
+type LoadBalancer interface {
+	Pick(ctx context.Context, servers []Server) Server
+}
+
+// RequestTracker is implemented by load balancers which count in-flight requests of servers.
+// Finish is called once the request sent to the server returned by Pick completes
+type RequestTracker interface {
+	Finish(server Server)
+}
+
+type hashKeyCtx struct{}
+
+// WithHashKey returns a copy of ctx carrying key, ConsistentHash sends requests with the same key to the same server
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyCtx{}, key)
+}
+
+// RoundRobin picks servers in turn
+type RoundRobin struct {
+	next uint64
+}
+
+func NewRoundRobin() LoadBalancer {
+	return &RoundRobin{}
+}
+
+func (lb *RoundRobin) Pick(ctx context.Context, servers []Server) Server {
+	return servers[(atomic.AddUint64(&lb.next, 1)-1)%uint64(len(servers))]
+}
+
+// WeightedRoundRobin picks servers in turn in proportion to their weights,
+// using the smooth weighted round robin algorithm of nginx to spread picks of the same server
+type WeightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func NewWeightedRoundRobin() LoadBalancer {
+	return &WeightedRoundRobin{
+		current: make(map[string]int),
+	}
+}
+
+func (lb *WeightedRoundRobin) Pick(ctx context.Context, servers []Server) Server {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	total := 0
+	best := -1
+	for i, server := range servers {
+		lb.current[server.BaseUrl] += server.weight()
+		total += server.weight()
+		if best < 0 || lb.current[server.BaseUrl] > lb.current[servers[best].BaseUrl] {
+			best = i
+		}
+	}
+	lb.current[servers[best].BaseUrl] -= total
+	if len(lb.current) > len(servers) {
+		lb.prune(servers)
+	}
+	return servers[best]
+}
+
+// prune forgets servers that have gone
+func (lb *WeightedRoundRobin) prune(servers []Server) {
+	alive := make(map[string]struct{}, len(servers))
+	for _, server := range servers {
+		alive[server.BaseUrl] = struct{}{}
+	}
+	for baseUrl := range lb.current {
+		if _, ok := alive[baseUrl]; !ok {
+			delete(lb.current, baseUrl)
+		}
+	}
+}
+
+// inflight counts in-flight requests of each server
+type inflight struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (f *inflight) count(server Server) int {
+	return f.counts[server.BaseUrl]
+}
+
+func (f *inflight) start(server Server) {
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+	}
+	f.counts[server.BaseUrl]++
+}
+
+func (f *inflight) Finish(server Server) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[server.BaseUrl] <= 1 {
+		delete(f.counts, server.BaseUrl)
+		return
+	}
+	f.counts[server.BaseUrl]--
+}
+
+// LeastOutstanding picks the server with the fewest in-flight requests, ties are broken in turn
+type LeastOutstanding struct {
+	inflight
+	next int
+}
+
+func NewLeastOutstanding() LoadBalancer {
+	return &LeastOutstanding{}
+}
+
+func (lb *LeastOutstanding) Pick(ctx context.Context, servers []Server) Server {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.next++
+	best := lb.next % len(servers)
+	for i := 1; i < len(servers); i++ {
+		j := (lb.next + i) % len(servers)
+		if lb.count(servers[j]) < lb.count(servers[best]) {
+			best = j
+		}
+	}
+	lb.start(servers[best])
+	return servers[best]
+}
+
+// RandomTwoChoices picks two servers at random and chooses the one with fewer in-flight requests.
+// It is cheaper than LeastOutstanding with many servers and avoids herding on the least loaded one
+type RandomTwoChoices struct {
+	inflight
+	rand *rand.Rand
+}
+
+func NewRandomTwoChoices() LoadBalancer {
+	return &RandomTwoChoices{
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (lb *RandomTwoChoices) Pick(ctx context.Context, servers []Server) Server {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	first := lb.rand.Intn(len(servers))
+	chosen := servers[first]
+	if len(servers) > 1 {
+		second := lb.rand.Intn(len(servers) - 1)
+		// skip the first choice so that two different servers are compared
+		if second == first {
+			second = len(servers) - 1
+		}
+		if lb.count(servers[second]) < lb.count(chosen) {
+			chosen = servers[second]
+		}
+	}
+	lb.start(chosen)
+	return chosen
+}
+
+// ConsistentHash picks servers by hash of the key set by WithHashKey on a hash ring,
+// so that most keys still go to the same servers when servers join or leave.
+// Requests without key are picked in turn
+type ConsistentHash struct {
+	replicas int
+	mu       sync.Mutex
+	members  string
+	ring     []uint32
+	owners   map[uint32]Server
+	fallback RoundRobin
+}
+
+// NewConsistentHash creates ConsistentHash with replicas virtual nodes for each server on the ring, 100 by default if not positive
+func NewConsistentHash(replicas int) LoadBalancer {
+	if replicas <= 0 {
+		replicas = 100
+	}
+	return &ConsistentHash{
+		replicas: replicas,
+	}
+}
+
+func (lb *ConsistentHash) Pick(ctx context.Context, servers []Server) Server {
+	key, ok := ctx.Value(hashKeyCtx{}).(string)
+	if !ok {
+		return lb.fallback.Pick(ctx, servers)
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.build(servers)
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(lb.ring), func(i int) bool {
+		return lb.ring[i] >= hash
+	})
+	if i == len(lb.ring) {
+		i = 0
+	}
+	return lb.owners[lb.ring[i]]
+}
+
+// build rebuilds the ring if servers changed
+func (lb *ConsistentHash) build(servers []Server) {
+	sorted := make([]Server, len(servers))
+	copy(sorted, servers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].BaseUrl < sorted[j].BaseUrl
+	})
+	urls := make([]string, 0, len(sorted))
+	for _, server := range sorted {
+		urls = append(urls, server.BaseUrl)
+	}
+	members := strings.Join(urls, ",")
+	if members == lb.members {
+		return
+	}
+	lb.members = members
+	lb.ring = make([]uint32, 0, len(sorted)*lb.replicas)
+	lb.owners = make(map[uint32]Server, len(sorted)*lb.replicas)
+	// servers are added in order so that all clients build the same ring even if hashes collide
+	for _, server := range sorted {
+		for i := 0; i < lb.replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + server.BaseUrl))
+			if _, ok := lb.owners[hash]; !ok {
+				lb.ring = append(lb.ring, hash)
+			}
+			lb.owners[hash] = server
+		}
+	}
+	sort.Slice(lb.ring, func(i, j int) bool {
+		return lb.ring[i] < lb.ring[j]
+	})
+}
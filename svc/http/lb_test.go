@@ -0,0 +1,213 @@
+package ddhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func testServers(n int) []Server {
+	var servers []Server
+	for i := 0; i < n; i++ {
+		servers = append(servers, Server{BaseUrl: fmt.Sprintf("http://10.0.0.%d:6060", i+1)})
+	}
+	return servers
+}
+
+func pickN(lb LoadBalancer, servers []Server, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[lb.Pick(context.Background(), servers).BaseUrl]++
+	}
+	return counts
+}
+
+func TestRoundRobin(t *testing.T) {
+	servers := testServers(3)
+	counts := pickN(NewRoundRobin(), servers, 30)
+	for _, server := range servers {
+		if counts[server.BaseUrl] != 10 {
+			t.Errorf("want 10 picks of each server, got %v", counts)
+		}
+	}
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	servers := testServers(3)
+	servers[0].Weight = 5
+	lb := NewWeightedRoundRobin()
+	var got []string
+	for i := 0; i < 7; i++ {
+		got = append(got, lb.Pick(context.Background(), servers).BaseUrl)
+	}
+	a, b, c := servers[0].BaseUrl, servers[1].BaseUrl, servers[2].BaseUrl
+	want := []string{a, a, b, a, c, a, a}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("picks should be smooth, got %v, want %v", got, want)
+		}
+	}
+
+	// removed servers are forgotten
+	counts := pickN(lb, servers[1:], 10)
+	if counts[b] != 5 || counts[c] != 5 {
+		t.Errorf("want 5 picks of each server, got %v", counts)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	servers := testServers(3)
+	lb := NewLeastOutstanding()
+	first := lb.Pick(context.Background(), servers)
+	second := lb.Pick(context.Background(), servers)
+	third := lb.Pick(context.Background(), servers)
+	if first == second || second == third || first == third {
+		t.Fatalf("servers without in-flight requests should be picked first, got %v %v %v", first, second, third)
+	}
+	lb.(RequestTracker).Finish(second)
+	if got := lb.Pick(context.Background(), servers); got != second {
+		t.Errorf("want %v which has the fewest in-flight requests, got %v", second, got)
+	}
+}
+
+func TestRandomTwoChoices(t *testing.T) {
+	servers := testServers(2)
+	lb := NewRandomTwoChoices()
+	busy := lb.Pick(context.Background(), servers)
+	// with two servers, both are compared in every pick, so the idle one always wins
+	for i := 0; i < 10; i++ {
+		got := lb.Pick(context.Background(), servers)
+		if got == busy {
+			t.Fatalf("want the server with fewer in-flight requests, got %v", got)
+		}
+		lb.(RequestTracker).Finish(got)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	servers := testServers(5)
+	lb := NewConsistentHash(0)
+	picked := make(map[string]Server)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user%d", i)
+		picked[key] = lb.Pick(WithHashKey(context.Background(), key), servers)
+		if got := lb.Pick(WithHashKey(context.Background(), key), servers); got != picked[key] {
+			t.Fatalf("key %s should be sent to the same server, got %v and %v", key, picked[key], got)
+		}
+	}
+	if counts := pickN(lb, servers, 5); len(counts) != 5 {
+		t.Errorf("requests without key should be picked in turn, got %v", counts)
+	}
+
+	// keys of remaining servers stay there after one server left
+	removed := servers[2]
+	remaining := append(append([]Server{}, servers[:2]...), servers[3:]...)
+	for key, server := range picked {
+		got := lb.Pick(WithHashKey(context.Background(), key), remaining)
+		if server != removed && got != server {
+			t.Errorf("key %s moved from %v to %v", key, server, got)
+		}
+		if got == removed {
+			t.Errorf("key %s should not be sent to removed server", key)
+		}
+	}
+}
+
+func TestLoadBalancer_Concurrent(t *testing.T) {
+	servers := testServers(4)
+	servers[0].Weight = 3
+	balancers := map[string]LoadBalancer{
+		"RoundRobin":         NewRoundRobin(),
+		"WeightedRoundRobin": NewWeightedRoundRobin(),
+		"LeastOutstanding":   NewLeastOutstanding(),
+		"RandomTwoChoices":   NewRandomTwoChoices(),
+		"ConsistentHash":     NewConsistentHash(10),
+	}
+	for name, lb := range balancers {
+		lb := lb
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						ctx := WithHashKey(context.Background(), fmt.Sprint(g*1000+i))
+						// servers change while picking
+						server := lb.Pick(ctx, servers[:2+i%3])
+						if tracker, ok := lb.(RequestTracker); ok {
+							tracker.Finish(server)
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+			if f, ok := lb.(interface{ count(Server) int }); ok {
+				for _, server := range servers {
+					if n := f.count(server); n != 0 {
+						t.Errorf("in-flight requests of %v should be 0, got %d", server, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+type staticLister struct {
+	roundRobin
+}
+
+func (p *staticLister) Servers() ([]Server, error) {
+	var servers []Server
+	for _, server := range p.servers {
+		servers = append(servers, Server{BaseUrl: server})
+	}
+	return servers, nil
+}
+
+func TestCaller_LoadBalancer(t *testing.T) {
+	hits := make([]int32, 3)
+	var servers []string
+	for i := range hits {
+		i := i
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+		}))
+		defer ts.Close()
+		servers = append(servers, ts.URL)
+	}
+	provider := &staticLister{roundRobin{servers: servers}}
+
+	var caller Caller
+	lb := NewLeastOutstanding()
+	caller.SetLoadBalancer(lb)
+	if _, err := caller.Call(provider, resty.New().R(), "GetUser", http.MethodGet, "/"); err != nil {
+		t.Fatal(err)
+	}
+	if n := lb.(*LeastOutstanding).count(Server{BaseUrl: servers[1]}); n != 0 {
+		t.Errorf("request should be finished, got %d in-flight", n)
+	}
+
+	caller.SetLoadBalancer(NewConsistentHash(0))
+	ctx := WithHashKey(context.Background(), "tenant1")
+	for i := 0; i < 6; i++ {
+		if _, err := caller.Call(provider, resty.New().R().SetContext(ctx), "GetUser", http.MethodGet, "/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var hit int
+	for i := range hits {
+		if atomic.LoadInt32(&hits[i]) >= 6 {
+			hit++
+		}
+	}
+	if hit != 1 || provider.next != 0 {
+		t.Errorf("requests with the same key should be sent to one server picked by the load balancer, got %v", hits)
+	}
+}
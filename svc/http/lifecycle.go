@@ -0,0 +1,121 @@
+package ddhttp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ShutdownPhase orders shutdown hooks, hooks of earlier phases run first
+type ShutdownPhase int
+
+const (
+	// PhaseNotReady fails readiness checks so that no more requests are routed to the service
+	PhaseNotReady ShutdownPhase = iota
+	// PhaseDeregister leaves service registries, e.g. memberlist
+	PhaseDeregister
+	// PhaseDrain waits for in-flight requests while the server still accepts requests from clients unaware of the shutdown
+	PhaseDrain
+	// PhaseServer shuts down the http server
+	PhaseServer
+	// PhaseCleanup closes resources like database connections and log files
+	PhaseCleanup
+)
+
+// ShutdownHook should return when ctx is done, whose deadline is set by GDD_GRACETIMEOUT
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	phase ShutdownPhase
+	name  string
+	fn    ShutdownHook
+}
+
+// Lifecycle runs shutdown hooks in order of phases, and in order of registration within the same phase
+type Lifecycle struct {
+	mu    sync.Mutex
+	hooks []shutdownHook
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+func (l *Lifecycle) OnShutdown(phase ShutdownPhase, name string, hook ShutdownHook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, shutdownHook{
+		phase: phase,
+		name:  name,
+		fn:    hook,
+	})
+}
+
+// Shutdown runs all hooks even if some of them fail, errors are logged
+func (l *Lifecycle) Shutdown(ctx context.Context) {
+	l.mu.Lock()
+	hooks := make([]shutdownHook, len(l.hooks))
+	copy(hooks, l.hooks)
+	l.mu.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].phase < hooks[j].phase
+	})
+	for _, hook := range hooks {
+		start := time.Now()
+		if err := hook.fn(ctx); err != nil {
+			logrus.Errorf("shutdown hook %s failed: %v", hook.name, err)
+			continue
+		}
+		logrus.Infof("shutdown hook %s done in %s", hook.name, time.Since(start))
+	}
+}
+
+// clone returns a copy of l, so that hooks of a server run don't leak into l
+func (l *Lifecycle) clone() *Lifecycle {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &Lifecycle{
+		hooks: append([]shutdownHook{}, l.hooks...),
+	}
+}
+
+var defaultLifecycle = NewLifecycle()
+
+// OnShutdown registers hook run by Run of DefaultHttpSrv and ChiHttpSrv when receiving SIGINT or SIGTERM,
+// along with built-in hooks marking the service not ready, waiting for in-flight requests, shutting down the server
+// and closing log files
+func OnShutdown(phase ShutdownPhase, name string, hook ShutdownHook) {
+	defaultLifecycle.OnShutdown(phase, name, hook)
+}
+
+// activeRequests counts in-flight requests
+type activeRequests struct {
+	count int64
+}
+
+func (a *activeRequests) track(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&a.count, 1)
+		defer atomic.AddInt64(&a.count, -1)
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// wait returns once there is no in-flight request or ctx is done
+func (a *activeRequests) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&a.count) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package ddhttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+)
+
+type steps struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (s *steps) hook(name string, err error) ShutdownHook {
+	return func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.names = append(s.names, name)
+		return err
+	}
+}
+
+func (s *steps) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.names...)
+}
+
+func assertSteps(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLifecycle_Shutdown(t *testing.T) {
+	s := &steps{}
+	l := NewLifecycle()
+	l.OnShutdown(PhaseCleanup, "database", s.hook("database", nil))
+	l.OnShutdown(PhaseServer, "server", s.hook("server", errors.New("timeout")))
+	l.OnShutdown(PhaseCleanup, "cache", s.hook("cache", nil))
+	l.OnShutdown(PhaseNotReady, "readiness", s.hook("readiness", nil))
+	l.OnShutdown(PhaseDeregister, "memberlist", s.hook("memberlist", nil))
+	l.Shutdown(context.Background())
+	assertSteps(t, s.get(), []string{"readiness", "memberlist", "server", "database", "cache"})
+}
+
+func TestActiveRequests(t *testing.T) {
+	active := &activeRequests{}
+	release := make(chan struct{})
+	handler := active.track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	go handler.ServeHTTP(nil, nil)
+	for atomic.LoadInt64(&active.count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := active.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait should time out, got %v", err)
+	}
+	close(release)
+	if err := active.wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRun_Shutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	setEnv(t, map[string]string{
+		config.GddPort.String():         strconv.Itoa(port),
+		config.GddGraceTimeout.String(): "5s",
+		config.GddBanner.String():       "off",
+	})
+	s := &steps{}
+	defer func(lifecycle *Lifecycle, notReady func()) {
+		defaultLifecycle = lifecycle
+		setShuttingDown = notReady
+	}(defaultLifecycle, setShuttingDown)
+	defaultLifecycle = NewLifecycle()
+	setShuttingDown = func() {
+		s.hook("readiness", nil)(nil)
+	}
+	OnShutdown(PhaseCleanup, "database", s.hook("database", nil))
+	OnShutdown(PhaseDeregister, "memberlist", s.hook("memberlist", nil))
+
+	received := make(chan struct{})
+	release := make(chan struct{})
+	srv := NewDefaultHttpSrv()
+	srv.AddRoute(model.Route{
+		Name:    "Slow",
+		Method:  "GET",
+		Pattern: "/slow",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			close(received)
+			<-release
+			s.hook("request", nil)(nil)
+		},
+	})
+	done := make(chan struct{})
+	go func() {
+		srv.Run()
+		close(done)
+	}()
+
+	url := "http://localhost:" + strconv.Itoa(port) + "/slow"
+	result := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			resp, err := http.Get(url)
+			if err != nil && i < 100 {
+				// wait for the server to listen
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+			result <- err
+			return
+		}
+	}()
+	<-received
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	// the request is still in flight after deregistration
+	for len(s.get()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if err := <-result; err != nil {
+		t.Errorf("in-flight request should succeed, got %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run should return after shutdown")
+	}
+	assertSteps(t, s.get(), []string{"readiness", "memberlist", "request", "database"})
+	if _, err := http.Get(url); err == nil {
+		t.Error("server should be shut down")
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	defer func(fn func()) {
+		setShuttingDown = fn
+	}(setShuttingDown)
+	var shuttingDown int32
+	setShuttingDown = func() {
+		atomic.StoreInt32(&shuttingDown, 1)
+	}
+	start := time.Now()
+	if err := notReady(50 * time.Millisecond)(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&shuttingDown) != 1 {
+		t.Error("readiness checks should fail")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("should hold for the delay after marking not ready, returned in %s", elapsed)
+	}
+
+	// the delay is bounded by the grace timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := notReady(time.Hour)(ctx); err != context.DeadlineExceeded {
+		t.Errorf("should return once ctx is done, got %v", err)
+	}
+
+	for value, want := range map[string]time.Duration{"": 0, "5s": 5 * time.Second, "oops": 0} {
+		setEnv(t, map[string]string{config.GddShutdownDelay.String(): value})
+		if got := shutdownDelay(); got != want {
+			t.Errorf("shutdownDelay() of %q = %s, want %s", value, got, want)
+		}
+	}
+}
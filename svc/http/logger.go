@@ -0,0 +1,350 @@
+package ddhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ascarter/requestid"
+	"github.com/felixge/httpsnoop"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/svc/tracing"
+)
+
+// DefaultLogBodyLimit is the max bytes of request and response bodies logged by HttpLogger
+const DefaultLogBodyLimit = 4 << 10
+
+const redacted = "***"
+
+const truncatedSuffix = "...(truncated)"
+
+// HttpLogger is a middleware logging requests and responses as HttpLog at debug level.
+// Bodies are copied while the handler reads and writes them, so streams and files are not buffered
+type HttpLogger struct {
+	logger    *logrus.Logger
+	bodyLimit int
+	headers   map[string]struct{}
+	fields    map[string]struct{}
+	sampling  float64
+}
+
+type LoggerOption func(*HttpLogger)
+
+// WithLogBodyLimit sets max bytes of request and response bodies to log, bodies are not logged if limit is negative
+func WithLogBodyLimit(limit int) LoggerOption {
+	return func(l *HttpLogger) {
+		l.bodyLimit = limit
+	}
+}
+
+// WithRedactedHeaders adds headers whose values are replaced with *** in logs,
+// Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-API-Key are redacted by default
+func WithRedactedHeaders(headers ...string) LoggerOption {
+	return func(l *HttpLogger) {
+		for _, header := range headers {
+			l.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+		}
+	}
+}
+
+// WithRedactedFields adds fields of json and form bodies and query parameters whose values are replaced with *** in logs,
+// field names are case insensitive and password, api_key and access_token are redacted by default
+func WithRedactedFields(fields ...string) LoggerOption {
+	return func(l *HttpLogger) {
+		for _, field := range fields {
+			l.fields[strings.ToLower(field)] = struct{}{}
+		}
+	}
+}
+
+// WithLogSampling logs the rate of requests, which is between 0 and 1, all requests are logged by default
+func WithLogSampling(rate float64) LoggerOption {
+	return func(l *HttpLogger) {
+		l.sampling = rate
+	}
+}
+
+// WithLogrusLogger sets logger to write logs, logrus.StandardLogger() by default
+func WithLogrusLogger(logger *logrus.Logger) LoggerOption {
+	return func(l *HttpLogger) {
+		l.logger = logger
+	}
+}
+
+// NewLogger creates HttpLogger
+func NewLogger(opts ...LoggerOption) *HttpLogger {
+	l := &HttpLogger{
+		logger:    logrus.StandardLogger(),
+		bodyLimit: DefaultLogBodyLimit,
+		headers:   make(map[string]struct{}),
+		fields:    make(map[string]struct{}),
+		sampling:  1,
+	}
+	WithRedactedHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key")(l)
+	WithRedactedFields("password", "api_key", "access_token")(l)
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+var defaultLogger = NewLogger()
+
+// Logger logs requests and responses by HttpLogger with default options
+func Logger(inner http.Handler) http.Handler {
+	return defaultLogger.Middleware(inner)
+}
+
+func (l *HttpLogger) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.logger.IsLevelEnabled(logrus.DebugLevel) || (l.sampling < 1 && rand.Float64() >= l.sampling) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		reqBody := newCappedBuffer(l.bodyLimit)
+		if r.Body != nil && r.Body != http.NoBody && isTextual(r.Header.Get("Content-Type")) {
+			r.Body = &teeBody{ReadCloser: r.Body, buf: reqBody}
+		}
+		respBody := newCappedBuffer(l.bodyLimit)
+		status := http.StatusOK
+		var wroteHeader bool
+		var written int64
+		// files and other binary contents are neither copied nor logged
+		textual := func() bool {
+			wroteHeader = true
+			return isTextual(w.Header().Get("Content-Type"))
+		}
+		ww := httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					if !wroteHeader {
+						status = code
+						wroteHeader = true
+					}
+					next(code)
+				}
+			},
+			Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+				return func(b []byte) (int, error) {
+					n, err := next(b)
+					if textual() {
+						respBody.Write(b[:n])
+					}
+					written += int64(n)
+					return n, err
+				}
+			},
+			ReadFrom: func(next httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+				return func(src io.Reader) (int64, error) {
+					tee := textual()
+					if tee && !respBody.full() {
+						src = io.TeeReader(src, respBody)
+					}
+					n, err := next(src)
+					if tee && n > 0 && respBody.full() {
+						respBody.truncated = true
+					}
+					written += n
+					return n, err
+				}
+			},
+		})
+		inner.ServeHTTP(ww, r)
+
+		rid, _ := requestid.FromContext(r.Context())
+		hlog := HttpLog{
+			ClientIp:          r.RemoteAddr,
+			HttpMethod:        r.Method,
+			Uri:               l.redactURI(r.URL),
+			Proto:             r.Proto,
+			Host:              r.Host,
+			ReqContentLength:  r.ContentLength,
+			ReqHeader:         l.redactHeader(r.Header),
+			RequestId:         rid,
+			ReqBody:           l.body(reqBody, r.Header.Get("Content-Type")),
+			RespBody:          l.body(respBody, w.Header().Get("Content-Type")),
+			StatusCode:        status,
+			RespHeader:        l.redactHeader(w.Header()),
+			RespContentLength: written,
+			ElapsedTime:       time.Since(start).String(),
+			Elapsed:           time.Since(start).Milliseconds(),
+		}
+		sc := tracing.SpanContextFromContext(r.Context())
+		if !sc.IsValid() {
+			sc, _ = tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader))
+		}
+		if sc.IsValid() {
+			hlog.TraceId = sc.TraceID.String()
+			hlog.SpanId = sc.SpanID.String()
+		}
+		l.logger.WithFields(hlog.fields()).Debugln("http request")
+	})
+}
+
+func (l HttpLog) fields() logrus.Fields {
+	var fields logrus.Fields
+	b, _ := json.Marshal(l)
+	json.Unmarshal(b, &fields)
+	return fields
+}
+
+func (l *HttpLogger) redactHeader(header http.Header) http.Header {
+	ret := header.Clone()
+	for key := range ret {
+		if _, ok := l.headers[key]; ok {
+			ret[key] = []string{redacted}
+		}
+	}
+	return ret
+}
+
+// redactURI returns request uri with values of query parameters in redacted fields replaced, order of parameters is kept
+func (l *HttpLogger) redactURI(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.RequestURI()
+	}
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		key := strings.SplitN(param, "=", 2)[0]
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if _, ok := l.fields[strings.ToLower(name)]; ok {
+			params[i] = key + "=" + redacted
+		}
+	}
+	ret := *u
+	ret.RawQuery = strings.Join(params, "&")
+	return ret.RequestURI()
+}
+
+func (l *HttpLogger) body(buf *cappedBuffer, contentType string) string {
+	if buf.Len() == 0 {
+		return ""
+	}
+	body := buf.Bytes()
+	if contentType == "" && !isTextual(http.DetectContentType(body)) {
+		return ""
+	}
+	if buf.truncated {
+		return string(l.redactFieldsByPattern(body)) + truncatedSuffix
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for key := range form {
+				if _, ok := l.fields[strings.ToLower(key)]; ok {
+					form[key] = []string{redacted}
+				}
+			}
+			return form.Encode()
+		}
+	case strings.HasSuffix(mediaType, "json"):
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			if redactedBody, err := json.Marshal(l.redactFields(value)); err == nil {
+				return string(redactedBody)
+			}
+		}
+	}
+	return string(l.redactFieldsByPattern(body))
+}
+
+func (l *HttpLogger) redactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, ok := l.fields[strings.ToLower(key)]; ok {
+				v[key] = redacted
+				continue
+			}
+			v[key] = l.redactFields(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = l.redactFields(item)
+		}
+	}
+	return value
+}
+
+var jsonStringField = regexp.MustCompile(`"([^"\\]+)"\s*:\s*"(?:[^"\\]|\\.)*"?`)
+
+// redactFieldsByPattern redacts string fields of truncated or invalid json bodies
+func (l *HttpLogger) redactFieldsByPattern(body []byte) []byte {
+	return jsonStringField.ReplaceAllFunc(body, func(match []byte) []byte {
+		key := jsonStringField.FindSubmatch(match)[1]
+		if _, ok := l.fields[strings.ToLower(string(key))]; ok {
+			return []byte(`"` + string(key) + `":"` + redacted + `"`)
+		}
+		return match
+	})
+}
+
+// isTextual reports whether bodies of the content type are worth logging, empty content type is treated as textual
+func isTextual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-www-form-urlencoded", "application/javascript":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// cappedBuffer keeps the first limit bytes written to it
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) full() bool {
+	return b.Len() >= b.limit
+}
+
+// Write never fails so that it can be used with io.TeeReader
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if left := b.limit - b.Len(); len(p) > left {
+		b.truncated = true
+		if left > 0 {
+			b.Buffer.Write(p[:left])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// teeBody copies the request body to buf while the handler reads it
+type teeBody struct {
+	io.ReadCloser
+	buf *cappedBuffer
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,190 @@
+package ddhttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger(opts ...LoggerOption) (*HttpLogger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.DebugLevel)
+	return NewLogger(append([]LoggerOption{WithLogrusLogger(logger)}, opts...)...), &buf
+}
+
+func parseLog(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log %q: %v", buf.String(), err)
+	}
+	return entry
+}
+
+func echo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.Header().Set("Set-Cookie", "session=secret")
+	w.WriteHeader(http.StatusCreated)
+	io.Copy(w, r.Body)
+}
+
+func TestHttpLogger(t *testing.T) {
+	logger, buf := newTestLogger()
+	req := httptest.NewRequest("POST", "/users?page=1&API_KEY=secret&access_token=secret&q=a%26b", strings.NewReader(`{"name":"jack","profile":{"Password":"123"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	logger.Middleware(http.HandlerFunc(echo)).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"Password":"123"`) {
+		t.Errorf("response should not be changed, got %d %s", rec.Code, rec.Body.String())
+	}
+	entry := parseLog(t, buf)
+	if entry["level"] != "debug" || entry["uri"] != "/users?page=1&API_KEY=***&access_token=***&q=a%26b" || entry["statusCode"] != float64(http.StatusCreated) {
+		t.Errorf("unexpected log %v", entry)
+	}
+	for _, body := range []string{"reqBody", "respBody"} {
+		if entry[body] != `{"name":"jack","profile":{"Password":"***"}}` {
+			t.Errorf("%s = %v", body, entry[body])
+		}
+	}
+	if entry["reqHeader"].(map[string]interface{})["Authorization"].([]interface{})[0] != "***" ||
+		entry["respHeader"].(map[string]interface{})["Set-Cookie"].([]interface{})[0] != "***" {
+		t.Errorf("headers should be redacted, got %v %v", entry["reqHeader"], entry["respHeader"])
+	}
+	if entry["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("traceId = %v", entry["traceId"])
+	}
+	if req.Header.Get("Authorization") != "Bearer token" {
+		t.Error("request header should not be changed")
+	}
+}
+
+func TestHttpLogger_Body(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []LoggerOption
+		contentType string
+		body        string
+		want        string
+	}{
+		{"truncated", []LoggerOption{WithLogBodyLimit(30)}, "application/json", `{"password":"123456","name":"jack"}`, `{"password":"***","name":"j...(truncated)`},
+		{"truncated in field", []LoggerOption{WithLogBodyLimit(16)}, "application/json", `{"password":"123456"}`, `{"password":"***"...(truncated)`},
+		{"no body", []LoggerOption{WithLogBodyLimit(-1)}, "application/json", `{"name":"jack"}`, ""},
+		{"form", []LoggerOption{WithRedactedFields("token")}, "application/x-www-form-urlencoded", "name=jack&token=abc", "name=jack&token=%2A%2A%2A"},
+		{"binary", nil, "application/octet-stream", "\x00\x01\x02", ""},
+		{"multipart", nil, "multipart/form-data; boundary=x", "--x--", ""},
+		{"sniffed", nil, "", "plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, buf := newTestLogger(tt.opts...)
+			req := httptest.NewRequest("POST", "/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			logger.Middleware(http.HandlerFunc(echo)).ServeHTTP(rec, req)
+			if rec.Body.String() != tt.body {
+				t.Errorf("response body %q should not be changed", rec.Body.String())
+			}
+			entry := parseLog(t, buf)
+			for _, key := range []string{"reqBody", "respBody"} {
+				got, _ := entry[key].(string)
+				if got != tt.want {
+					t.Errorf("%s = %q, want %q", key, got, tt.want)
+				}
+			}
+			if entry["respContentLength"] != float64(len(tt.body)) {
+				t.Errorf("respContentLength = %v", entry["respContentLength"])
+			}
+		})
+	}
+}
+
+func TestHttpLogger_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "report.pdf")
+	content := bytes.Repeat([]byte("%PDF"), 10000)
+	if err = ioutil.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	logger, buf := newTestLogger()
+	ts := httptest.NewServer(logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, _ := os.Open(file)
+		defer f.Close()
+		w.Header().Set("Content-Type", "application/pdf")
+		io.Copy(w, f)
+	})))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("got %d bytes, want %d", len(got), len(content))
+	}
+	ts.Close()
+	entry := parseLog(t, buf)
+	if _, ok := entry["respBody"]; ok || entry["respContentLength"] != float64(len(content)) {
+		t.Errorf("unexpected log %v", entry)
+	}
+}
+
+func TestHttpLogger_Stream(t *testing.T) {
+	logger, buf := newTestLogger()
+	next := make(chan struct{})
+	ts := httptest.NewServer(logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-next
+		w.Write([]byte("data: 2\n\n"))
+	})))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	// the first event arrives before the handler returns
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "data: 1\n" {
+		t.Fatalf("got %q %v", line, err)
+	}
+	close(next)
+	ioutil.ReadAll(reader)
+	ts.Close()
+	if entry := parseLog(t, buf); entry["respBody"] != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("respBody = %v", entry["respBody"])
+	}
+}
+
+func TestHttpLogger_Sampling(t *testing.T) {
+	logger, buf := newTestLogger(WithLogSampling(0))
+	logger.Middleware(http.HandlerFunc(echo)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	logger.logger.SetLevel(logrus.InfoLevel)
+	logger.sampling = 1
+	logger.Middleware(http.HandlerFunc(echo)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if buf.Len() > 0 {
+		t.Errorf("requests should not be logged, got %s", buf.String())
+	}
+}
@@ -1,16 +1,11 @@
 package ddhttp
 
 import (
-	"encoding/json"
-	"github.com/ascarter/requestid"
 	"github.com/felixge/httpsnoop"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/tracing"
 	"net/http"
-	"net/http/httptest"
-	"net/http/httputil"
-	"strings"
-	"time"
 )
 
 func Metrics(inner http.Handler) http.Handler {
@@ -28,61 +23,38 @@ func Metrics(inner http.Handler) http.Handler {
 	})
 }
 
-func Logger(inner http.Handler) http.Handler {
+func Rest(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rid, _ := requestid.FromContext(r.Context())
-		x, err := httputil.DumpRequest(r, true)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		rec := httptest.NewRecorder()
-		inner.ServeHTTP(rec, r)
-
-		rawReq := string(x)
-		if len(r.Header["Content-Type"]) > 0 && strings.Contains(r.Header["Content-Type"][0], "multipart/form-data") {
-			if err := r.ParseMultipartForm(32 << 20); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			rawReq = r.Form.Encode()
-		}
-
-		hlog := HttpLog{
-			ClientIp:          r.RemoteAddr,
-			HttpMethod:        r.Method,
-			Uri:               r.URL.RequestURI(),
-			Proto:             r.Proto,
-			Host:              r.Host,
-			ReqContentLength:  r.ContentLength,
-			ReqHeader:         r.Header,
-			RequestId:         rid,
-			RawReq:            rawReq,
-			RespBody:          rec.Body.String(),
-			StatusCode:        rec.Result().StatusCode,
-			RespHeader:        rec.Result().Header,
-			RespContentLength: rec.Body.Len(),
-			ElapsedTime:       time.Since(start).String(),
-			Elapsed:           time.Since(start).Milliseconds(),
-		}
-		log, _ := json.MarshalIndent(hlog, "", "    ")
-		logrus.Debugln(string(log))
-
-		header := rec.Result().Header
-		for k, v := range header {
-			w.Header()[k] = v
+		if stringutils.IsEmpty(w.Header().Get("Content-Type")) {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		}
-		w.WriteHeader(rec.Result().StatusCode)
-		rec.Body.WriteTo(w)
+		inner.ServeHTTP(w, r)
 	})
 }
 
-func Rest(inner http.Handler) http.Handler {
+// Tracing starts a server span named by route name for each request, as a child of the span in traceparent header if any.
+// The span is carried by ctx of the request, so generated clients called with the ctx propagate the trace to downstream services.
+// It should be added before Logger to log trace id
+func Tracing(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if stringutils.IsEmpty(w.Header().Get("Content-Type")) {
-			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		ctx := r.Context()
+		if sc, err := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); err == nil {
+			sc.TraceState = r.Header.Get(tracing.TracestateHeader)
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+		name := RouteName(r)
+		if stringutils.IsEmpty(name) {
+			name = r.Method + " " + r.URL.Path
+		}
+		ctx, span := tracing.Start(ctx, name, tracing.SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.RequestURI())
+		span.SetAttribute("http.client_ip", r.RemoteAddr)
+		m := httpsnoop.CaptureMetrics(inner, w, r.WithContext(ctx))
+		span.SetAttribute("http.status_code", m.Code)
+		if m.Code >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(m.Code))
 		}
-		inner.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,464 @@
+package onlinedoc
+
+// explorerHtml is a self-contained api explorer rendering openapi 3.0 docs listed by /go-doudou/docs.
+// All assets are inlined so that it works offline
+const explorerHtml = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>API Explorer</title>
+<style>
+* { box-sizing: border-box; }
+body { margin: 0; font: 14px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292e; background: #f6f8fa; }
+header { display: flex; align-items: center; gap: 12px; padding: 10px 24px; background: #1f2d3d; color: #fff; }
+header h1 { margin: 0; font-size: 18px; font-weight: 600; flex: 1; }
+header select, header input { padding: 5px 8px; border: 0; border-radius: 4px; font-size: 14px; }
+main { max-width: 1100px; margin: 0 auto; padding: 16px 24px 48px; }
+.info h2 { margin: 8px 0 0; }
+.info .version { color: #6a737d; font-size: 12px; }
+.info .desc { white-space: pre-wrap; }
+.tag { margin-top: 24px; font-size: 16px; font-weight: 600; border-bottom: 1px solid #e1e4e8; padding-bottom: 4px; }
+.op { margin: 8px 0; background: #fff; border: 1px solid #e1e4e8; border-radius: 4px; }
+.op > .summary { display: flex; align-items: center; gap: 10px; padding: 6px 10px; cursor: pointer; }
+.op > .detail { display: none; padding: 10px 14px; border-top: 1px solid #e1e4e8; }
+.op.open > .detail { display: block; }
+.method { min-width: 64px; padding: 2px 0; border-radius: 3px; color: #fff; font-weight: 600; font-size: 12px; text-align: center; text-transform: uppercase; }
+.get { background: #2f80ed; } .post { background: #27ae60; } .put { background: #f2994a; } .delete { background: #eb5757; } .patch { background: #9b51e0; } .head, .options { background: #828282; }
+.path { font-family: Menlo, Consolas, monospace; font-weight: 600; }
+.desc-inline { color: #6a737d; }
+h4 { margin: 14px 0 6px; font-size: 13px; text-transform: uppercase; color: #586069; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; vertical-align: top; padding: 4px 8px; border-bottom: 1px solid #eaecef; }
+th { font-size: 12px; color: #586069; }
+td input[type=text], td select { width: 100%; padding: 3px 6px; border: 1px solid #d1d5da; border-radius: 3px; }
+pre { margin: 0; padding: 8px; background: #f6f8fa; border-radius: 3px; overflow: auto; max-height: 400px; font: 12px/1.4 Menlo, Consolas, monospace; }
+textarea { width: 100%; min-height: 120px; font: 12px/1.4 Menlo, Consolas, monospace; border: 1px solid #d1d5da; border-radius: 3px; padding: 6px; }
+button { padding: 5px 14px; border: 0; border-radius: 3px; background: #1f2d3d; color: #fff; cursor: pointer; }
+.required { color: #eb5757; }
+.muted { color: #6a737d; }
+.status { font-weight: 600; }
+.error { color: #eb5757; }
+</style>
+</head>
+<body>
+<header>
+<h1>API Explorer</h1>
+<input id="filter" type="search" placeholder="Filter">
+<select id="docs"></select>
+</header>
+<main id="main"><p class="muted">Loading...</p></main>
+<script>
+(function () {
+  "use strict";
+  var root = location.pathname.replace(/\/go-doudou\/doc\/?$/, "");
+  var main = document.getElementById("main");
+  var select = document.getElementById("docs");
+  var filter = document.getElementById("filter");
+  var docs = [];
+  var spec = null;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") {
+        node.textContent = attrs[key];
+      } else if (key === "class") {
+        node.className = attrs[key];
+      } else if (key.indexOf("on") === 0) {
+        node.addEventListener(key.substring(2), attrs[key]);
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child) {
+        node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+      }
+    });
+    return node;
+  }
+
+  function resolve(schema) {
+    var depth = 0;
+    while (schema && schema.$ref && depth < 32) {
+      var name = schema.$ref.replace("#/components/schemas/", "");
+      schema = ((spec.components || {}).schemas || {})[name];
+      depth++;
+    }
+    return schema || {};
+  }
+
+  function refName(schema) {
+    return schema && schema.$ref ? schema.$ref.replace("#/components/schemas/", "") : "";
+  }
+
+  function typeOf(schema) {
+    var name = refName(schema);
+    if (name) {
+      return name;
+    }
+    if (schema.type === "array") {
+      return "[]" + typeOf(schema.items || {});
+    }
+    if (schema.type === "object" && schema.additionalProperties) {
+      return "map[string]" + typeOf(schema.additionalProperties);
+    }
+    return (schema.type || "any") + (schema.format ? "(" + schema.format + ")" : "");
+  }
+
+  // schemaText renders schema as an indented tree, seen stops recursive types
+  function schemaText(schema, indent, seen) {
+    var name = refName(schema);
+    var resolved = resolve(schema);
+    if (resolved.type === "array") {
+      return "[]" + schemaText(resolved.items || {}, indent, seen);
+    }
+    if (resolved.type !== "object" || !resolved.properties) {
+      return typeOf(schema) + (resolved.enum ? " enum(" + resolved.enum.join(", ") + ")" : "");
+    }
+    if (name && seen.indexOf(name) >= 0) {
+      return name + " {...}";
+    }
+    seen = seen.concat(name ? [name] : []);
+    var required = resolved.required || [];
+    var pad = new Array(indent + 2).join("  ");
+    var lines = [(name ? name + " " : "") + "{"];
+    Object.keys(resolved.properties).forEach(function (key) {
+      var prop = resolved.properties[key];
+      var line = pad + key + (required.indexOf(key) >= 0 ? "*" : "") + ": " + schemaText(prop, indent + 1, seen);
+      var desc = resolve(prop).description || prop.description;
+      lines.push(line + (desc ? "  // " + desc.replace(/\n/g, " ") : ""));
+    });
+    lines.push(new Array(indent + 1).join("  ") + "}");
+    return lines.join("\n");
+  }
+
+  function example(schema, seen) {
+    var name = refName(schema);
+    var resolved = resolve(schema);
+    if (resolved.example !== undefined) {
+      return resolved.example;
+    }
+    if (resolved.enum) {
+      return resolved.enum[0];
+    }
+    switch (resolved.type) {
+      case "object":
+        if (name && seen.indexOf(name) >= 0) {
+          return {};
+        }
+        var ret = {};
+        Object.keys(resolved.properties || {}).forEach(function (key) {
+          ret[key] = example(resolved.properties[key], seen.concat(name ? [name] : []));
+        });
+        return ret;
+      case "array":
+        return [example(resolved.items || {}, seen)];
+      case "integer":
+      case "number":
+        return 0;
+      case "boolean":
+        return false;
+      case "string":
+        return resolved.format === "date-time" ? new Date().toISOString() : "";
+    }
+    return null;
+  }
+
+  function isBinary(schema) {
+    var resolved = resolve(schema);
+    return resolved.format === "binary" || (resolved.type === "array" && resolve(resolved.items).format === "binary");
+  }
+
+  function paramsTable(params, inputs) {
+    var rows = params.map(function (param) {
+      var input = null;
+      if (inputs) {
+        var schema = resolve(param.schema);
+        if (schema.enum) {
+          input = el("select", {}, [el("option", {value: "", text: ""})].concat(schema.enum.map(function (v) {
+            return el("option", {value: v, text: v});
+          })));
+        } else {
+          input = el("input", {type: "text", placeholder: typeOf(param.schema || {})});
+        }
+        inputs.push({param: param, input: input});
+      }
+      return el("tr", {}, [
+        el("td", {}, [el("span", {class: "path", text: param.name}), param.required ? el("span", {class: "required", text: " *"}) : null]),
+        el("td", {text: param.in}),
+        el("td", {text: typeOf(param.schema || {})}),
+        el("td", {text: param.description || ""}),
+        inputs ? el("td", {}, [input]) : null
+      ]);
+    });
+    var head = ["Name", "In", "Type", "Description"].concat(inputs ? ["Value"] : []);
+    return el("table", {}, [el("tr", {}, head.map(function (h) {
+      return el("th", {text: h});
+    }))].concat(rows));
+  }
+
+  function renderBody(body, local) {
+    var nodes = [el("h4", {text: "Request body"})];
+    if (body.description) {
+      nodes.push(el("p", {text: body.description}));
+    }
+    var form = null;
+    Object.keys(body.content || {}).forEach(function (type) {
+      var schema = body.content[type].schema || {};
+      nodes.push(el("div", {class: "muted", text: type}));
+      nodes.push(el("pre", {text: schemaText(schema, 0, [])}));
+      if (!local || form) {
+        return;
+      }
+      if (type === "application/json" || type === "text/plain") {
+        var textarea = el("textarea", {});
+        textarea.value = type === "application/json" ? JSON.stringify(example(schema, []), null, 2) : "";
+        form = {type: type, textarea: textarea};
+        nodes.push(textarea);
+      } else {
+        var fields = [];
+        var props = resolve(schema).properties || {};
+        var rows = Object.keys(props).map(function (key) {
+          var input = isBinary(props[key]) ? el("input", {type: "file", multiple: "multiple"}) : el("input", {type: "text", placeholder: typeOf(props[key])});
+          fields.push({name: key, input: input});
+          return el("tr", {}, [el("td", {class: "path", text: key}), el("td", {}, [input])]);
+        });
+        form = {type: type, fields: fields};
+        nodes.push(el("table", {}, rows));
+      }
+    });
+    return {nodes: nodes, form: form};
+  }
+
+  function send(method, path, inputs, form, output) {
+    var url = path;
+    var query = [];
+    var headers = {};
+    var missing = [];
+    inputs.forEach(function (item) {
+      var value = item.input.value;
+      if (value === "") {
+        if (item.param.required) {
+          missing.push(item.param.name);
+        }
+        return;
+      }
+      switch (item.param.in) {
+        case "path":
+          url = url.replace("{" + item.param.name + "}", encodeURIComponent(value));
+          break;
+        case "query":
+          query.push(encodeURIComponent(item.param.name) + "=" + encodeURIComponent(value));
+          break;
+        case "header":
+          headers[item.param.name] = value;
+          break;
+      }
+    });
+    output.textContent = "";
+    if (missing.length) {
+      output.appendChild(el("span", {class: "error", text: "Required: " + missing.join(", ")}));
+      return;
+    }
+    var init = {method: method.toUpperCase(), headers: headers, credentials: "same-origin"};
+    if (form && form.textarea) {
+      init.body = form.textarea.value;
+      headers["Content-Type"] = form.type;
+    } else if (form && form.type === "multipart/form-data") {
+      var data = new FormData();
+      form.fields.forEach(function (field) {
+        if (field.input.type === "file") {
+          Array.prototype.forEach.call(field.input.files, function (file) {
+            data.append(field.name, file);
+          });
+        } else if (field.input.value !== "") {
+          data.append(field.name, field.input.value);
+        }
+      });
+      init.body = data;
+    } else if (form) {
+      init.body = form.fields.filter(function (field) {
+        return field.input.value !== "";
+      }).map(function (field) {
+        return encodeURIComponent(field.name) + "=" + encodeURIComponent(field.input.value);
+      }).join("&");
+      headers["Content-Type"] = form.type;
+    }
+    url = root + url + (query.length ? "?" + query.join("&") : "");
+    var start = Date.now();
+    var status = el("div", {class: "status", text: init.method + " " + url});
+    var pre = el("pre", {});
+    output.appendChild(status);
+    output.appendChild(pre);
+    fetch(url, init).then(function (resp) {
+      status.textContent = resp.status + " " + resp.statusText + " - " + (Date.now() - start) + "ms";
+      var type = resp.headers.get("Content-Type") || "";
+      if (type.indexOf("text/event-stream") >= 0 && resp.body) {
+        var reader = resp.body.getReader();
+        var decoder = new TextDecoder();
+        var read = function () {
+          return reader.read().then(function (chunk) {
+            if (!chunk.done) {
+              pre.textContent += decoder.decode(chunk.value, {stream: true});
+              return read();
+            }
+          });
+        };
+        return read();
+      }
+      if (type.indexOf("json") >= 0) {
+        return resp.text().then(function (text) {
+          try {
+            pre.textContent = JSON.stringify(JSON.parse(text), null, 2);
+          } catch (e) {
+            pre.textContent = text;
+          }
+        });
+      }
+      if (type === "" || type.indexOf("text/") === 0) {
+        return resp.text().then(function (text) {
+          pre.textContent = text;
+        });
+      }
+      return resp.blob().then(function (blob) {
+        pre.textContent = "";
+        pre.appendChild(el("a", {href: URL.createObjectURL(blob), download: "download", text: "Download " + blob.size + " bytes (" + type + ")"}));
+      });
+    }).catch(function (err) {
+      pre.appendChild(el("span", {class: "error", text: String(err)}));
+    });
+  }
+
+  function renderOp(path, method, op, local) {
+    var params = ((spec.paths[path].parameters || []).concat(op.parameters || [])).map(function (param) {
+      return param.$ref ? resolve(param) : param;
+    });
+    var detail = el("div", {class: "detail"});
+    if (op.description) {
+      detail.appendChild(el("p", {class: "desc", text: op.description}));
+    }
+    var inputs = local ? [] : null;
+    if (params.length) {
+      detail.appendChild(el("h4", {text: "Parameters"}));
+      detail.appendChild(paramsTable(params, inputs));
+    }
+    var form = null;
+    if (op.requestBody) {
+      var body = renderBody(op.requestBody, local);
+      body.nodes.forEach(function (node) {
+        detail.appendChild(node);
+      });
+      form = body.form;
+    }
+    detail.appendChild(el("h4", {text: "Responses"}));
+    var rows = Object.keys(op.responses || {}).map(function (code) {
+      var resp = op.responses[code];
+      var cells = [el("p", {text: resp.description || ""})];
+      Object.keys(resp.content || {}).forEach(function (type) {
+        cells.push(el("div", {class: "muted", text: type}));
+        cells.push(el("pre", {text: schemaText(resp.content[type].schema || {}, 0, [])}));
+      });
+      return el("tr", {}, [el("td", {class: "path", text: code}), el("td", {}, cells)]);
+    });
+    detail.appendChild(el("table", {}, rows));
+    if (local) {
+      var output = el("div", {});
+      detail.appendChild(el("h4", {text: "Try it out"}));
+      detail.appendChild(el("button", {onclick: function () {
+        send(method, path, inputs, form, output);
+      }, text: "Send"}));
+      detail.appendChild(output);
+    }
+    var node = el("div", {class: "op", "data-search": (method + " " + path + " " + (op.summary || "") + " " + (op.operationId || "")).toLowerCase()}, [
+      el("div", {class: "summary", onclick: function () {
+        node.classList.toggle("open");
+      }}, [
+        el("span", {class: "method " + method, text: method}),
+        el("span", {class: "path", text: path}),
+        el("span", {class: "desc-inline", text: op.summary || ""})
+      ]),
+      detail
+    ]);
+    return node;
+  }
+
+  function render(doc) {
+    main.textContent = "";
+    var info = spec.info || {};
+    main.appendChild(el("div", {class: "info"}, [
+      el("h2", {text: info.title || doc.name}),
+      el("div", {class: "version", text: (info.version || "") + (doc.local ? "" : " - read only, served by other service")}),
+      info.description ? el("p", {class: "desc", text: info.description}) : null
+    ]));
+    var groups = {};
+    var order = [];
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      ["get", "post", "put", "patch", "delete", "head", "options"].forEach(function (method) {
+        var op = spec.paths[path][method];
+        if (!op) {
+          return;
+        }
+        var tag = (op.tags && op.tags[0]) || path.split("/")[1] || "default";
+        if (!groups[tag]) {
+          groups[tag] = [];
+          order.push(tag);
+        }
+        groups[tag].push(renderOp(path, method, op, doc.local));
+      });
+    });
+    order.forEach(function (tag) {
+      main.appendChild(el("div", {class: "tag", text: tag}));
+      groups[tag].forEach(function (node) {
+        main.appendChild(node);
+      });
+    });
+    applyFilter();
+  }
+
+  function applyFilter() {
+    var keyword = filter.value.toLowerCase();
+    Array.prototype.forEach.call(main.querySelectorAll(".op"), function (node) {
+      node.style.display = node.getAttribute("data-search").indexOf(keyword) >= 0 ? "" : "none";
+    });
+  }
+
+  function load(index) {
+    var doc = docs[index];
+    main.textContent = "";
+    main.appendChild(el("p", {class: "muted", text: "Loading " + doc.name + "..."}));
+    fetch(doc.url, {credentials: "same-origin"}).then(function (resp) {
+      if (!resp.ok) {
+        throw new Error(resp.status + " " + resp.statusText);
+      }
+      return resp.json();
+    }).then(function (json) {
+      spec = json;
+      render(doc);
+    }).catch(function (err) {
+      main.textContent = "";
+      main.appendChild(el("p", {class: "error", text: "Failed to load api doc of " + doc.name + ": " + err.message}));
+    });
+  }
+
+  filter.addEventListener("input", applyFilter);
+  select.addEventListener("change", function () {
+    load(select.selectedIndex);
+  });
+  fetch("docs", {credentials: "same-origin"}).then(function (resp) {
+    return resp.json();
+  }).catch(function () {
+    return [{name: "local", url: "openapi.json", local: true}];
+  }).then(function (list) {
+    docs = list;
+    docs.forEach(function (doc) {
+      select.appendChild(el("option", {text: doc.name}));
+    });
+    load(0);
+  });
+})();
+</script>
+</body>
+</html>
+`
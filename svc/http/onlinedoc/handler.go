@@ -8,6 +8,8 @@ import (
 type OnlineDocHandler interface {
 	GetDoc(w http.ResponseWriter, r *http.Request)
 	GetOpenAPI(w http.ResponseWriter, r *http.Request)
+	GetDocs(w http.ResponseWriter, r *http.Request)
+	GetServiceOpenAPI(w http.ResponseWriter, r *http.Request)
 }
 
 func Routes() []model.Route {
@@ -25,5 +27,17 @@ func Routes() []model.Route {
 			"/go-doudou/openapi.json",
 			handler.GetOpenAPI,
 		},
+		{
+			"GetDocs",
+			"GET",
+			"/go-doudou/docs",
+			handler.GetDocs,
+		},
+		{
+			"GetServiceOpenAPI",
+			"GET",
+			"/go-doudou/openapi/{service}",
+			handler.GetServiceOpenAPI,
+		},
 	}
 }
@@ -1,22 +1,175 @@
 package onlinedoc
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/tlsutils"
 )
 
+// Oas is the openapi 3.0 json of the service, which is set by the generated _openapi3.go file
+// and replaced by the _openapi3.json file in working directory at startup if there is one
 var Oas string
 
+// Registry lists base urls of alive nodes of each service in the cluster, *registry.Node implements it
+type Registry interface {
+	Services() (map[string][]string, error)
+}
+
+var reg Registry
+
+// SetRegistry makes the api explorer list and proxy api docs of other services discovered by r,
+// e.g. onlinedoc.SetRegistry(node) in micro mode
+func SetRegistry(r Registry) {
+	reg = r
+}
+
+// Doc is an api doc listed in the api explorer
+type Doc struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	// Local is true for the doc of this service, api of which can be tried out in the explorer
+	Local bool `json:"local"`
+}
+
 type OnlineDocHandlerImpl struct {
+	client *http.Client
+	// verified is true if certificates of nodes are verified by GDD_TLS_CA, so that they can be trusted with credentials
+	verified bool
 }
 
 func (receiver *OnlineDocHandlerImpl) GetOpenAPI(_writer http.ResponseWriter, _req *http.Request) {
+	_writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	_writer.Write([]byte(Oas))
 }
 
 func (receiver *OnlineDocHandlerImpl) GetDoc(_writer http.ResponseWriter, _req *http.Request) {
-	_writer.Write([]byte("Greeting from doudou"))
+	_writer.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	_writer.Write([]byte(explorerHtml))
+}
+
+// GetDocs lists the api doc of this service, and docs of other services in micro mode
+func (receiver *OnlineDocHandlerImpl) GetDocs(_writer http.ResponseWriter, _req *http.Request) {
+	name := config.GddName.Load()
+	if stringutils.IsEmpty(name) {
+		name = "local"
+	}
+	docs := []Doc{
+		{
+			Name:  name,
+			Url:   "openapi.json",
+			Local: true,
+		},
+	}
+	if reg != nil {
+		services, err := reg.Services()
+		if err != nil {
+			logrus.Errorf("list services failed: %v", err)
+		}
+		var names []string
+		for service := range services {
+			if service != name {
+				names = append(names, service)
+			}
+		}
+		sort.Strings(names)
+		for _, service := range names {
+			docs = append(docs, Doc{
+				Name: service,
+				Url:  "openapi/" + service,
+			})
+		}
+	}
+	_writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(_writer).Encode(docs)
+}
+
+// GetServiceOpenAPI proxies the api doc of the service from its nodes. Base urls of nodes are learned from gossip
+// which is not authenticated, so the Authorization header of the caller is never forwarded. Proxy requests are
+// authenticated by GDD_MANAGE_USER and GDD_MANAGE_PASS of this service only for https nodes whose certificates
+// are verified by GDD_TLS_CA, so nodes of all services should share the same credentials and GDD_ROUTE_ROOT_PATH
+func (receiver *OnlineDocHandlerImpl) GetServiceOpenAPI(_writer http.ResponseWriter, _req *http.Request) {
+	service := mux.Vars(_req)["service"]
+	var urls []string
+	if reg != nil {
+		services, err := reg.Services()
+		if err != nil {
+			logrus.Errorf("list services failed: %v", err)
+		}
+		urls = services[service]
+	}
+	if len(urls) == 0 {
+		http.Error(_writer, "service "+service+" not found", http.StatusNotFound)
+		return
+	}
+	for _, url := range urls {
+		req, err := http.NewRequestWithContext(_req.Context(), http.MethodGet, url+config.GddRouteRootPath.Load()+"/go-doudou/openapi.json", nil)
+		if err != nil {
+			continue
+		}
+		if receiver.verified && strings.HasPrefix(url, "https://") && stringutils.IsNotEmpty(config.GddManageUser.Load()) {
+			req.SetBasicAuth(config.GddManageUser.Load(), config.GddManagePass.Load())
+		}
+		resp, err := receiver.client.Do(req)
+		if err != nil {
+			logrus.Warnf("get api doc of %s from %s failed: %v", service, url, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			logrus.Warnf("get api doc of %s from %s failed: %s", service, url, resp.Status)
+			resp.Body.Close()
+			continue
+		}
+		_writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		io.Copy(_writer, resp.Body)
+		resp.Body.Close()
+		return
+	}
+	http.Error(_writer, "api doc of service "+service+" is unavailable", http.StatusBadGateway)
+}
+
+// loadOas replaces Oas with the first _openapi3.json file found in working directory
+func loadOas() {
+	matches, _ := filepath.Glob("*_openapi3.json")
+	if len(matches) == 0 {
+		return
+	}
+	b, err := ioutil.ReadFile(matches[0])
+	if err != nil {
+		logrus.Warnf("load api doc from %s failed: %v", matches[0], err)
+		return
+	}
+	Oas = string(b)
 }
 
 func NewOnlineDocHandler() OnlineDocHandler {
-	return &OnlineDocHandlerImpl{}
+	loadOas()
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	// Docs of other services are proxied through https if they serve https
+	tlsConfig, err := tlsutils.ClientConfig()
+	if err != nil {
+		logrus.Errorf("configure tls of api doc proxy failed: %v", err)
+	}
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	}
+	return &OnlineDocHandlerImpl{
+		client:   client,
+		verified: tlsConfig != nil && stringutils.IsNotEmpty(config.GddTLSCA.Load()),
+	}
 }
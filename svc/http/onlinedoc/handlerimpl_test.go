@@ -0,0 +1,171 @@
+package onlinedoc
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+type fakeRegistry map[string][]string
+
+func (r fakeRegistry) Services() (map[string][]string, error) {
+	return r, nil
+}
+
+func TestGetDoc(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewOnlineDocHandler().GetDoc(rec, httptest.NewRequest("GET", "/go-doudou/doc", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "API Explorer") {
+		t.Errorf("unexpected response %v %s", rec.Header(), rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "http://") || strings.Contains(rec.Body.String(), "https://") {
+		t.Error("assets should not be loaded from network")
+	}
+}
+
+func TestLoadOas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onlinedoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	defer func(oas string) {
+		Oas = oas
+	}(Oas)
+
+	Oas = `{"openapi":"3.0.2","info":{"title":"embedded"}}`
+	NewOnlineDocHandler()
+	if !strings.Contains(Oas, "embedded") {
+		t.Error("embedded doc should be kept if there is no json file")
+	}
+	ioutil.WriteFile("usersvc_openapi3.json", []byte(`{"openapi":"3.0.2","info":{"title":"Usersvc"}}`), 0644)
+	rec := httptest.NewRecorder()
+	NewOnlineDocHandler().GetOpenAPI(rec, httptest.NewRequest("GET", "/go-doudou/openapi.json", nil))
+	if !strings.Contains(rec.Body.String(), "Usersvc") {
+		t.Errorf("doc should be loaded from json file, got %s", rec.Body.String())
+	}
+}
+
+func TestGetDocs(t *testing.T) {
+	os.Setenv(config.GddName.String(), "usersvc")
+	defer os.Unsetenv(config.GddName.String())
+	var auth string
+	ordersvc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/go-doudou/openapi.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"info":{"title":"Ordersvc"}}`))
+	}))
+	defer ordersvc.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	SetRegistry(fakeRegistry{
+		"usersvc":  {"http://localhost:6060"},
+		"ordersvc": {down.URL, ordersvc.URL},
+		"paysvc":   {down.URL},
+	})
+	defer SetRegistry(nil)
+	handler := NewOnlineDocHandler()
+
+	rec := httptest.NewRecorder()
+	handler.GetDocs(rec, httptest.NewRequest("GET", "/go-doudou/docs", nil))
+	var docs []Doc
+	if err := json.Unmarshal(rec.Body.Bytes(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	want := []Doc{
+		{Name: "usersvc", Url: "openapi.json", Local: true},
+		{Name: "ordersvc", Url: "openapi/ordersvc"},
+		{Name: "paysvc", Url: "openapi/paysvc"},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %+v, want %+v", docs, want)
+	}
+	for i := range want {
+		if docs[i] != want[i] {
+			t.Errorf("got %+v, want %+v", docs[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		service    string
+		wantStatus int
+		wantBody   string
+	}{
+		{"ordersvc", http.StatusOK, "Ordersvc"},
+		{"paysvc", http.StatusBadGateway, "unavailable"},
+		{"unknown", http.StatusNotFound, "not found"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/go-doudou/openapi/"+tt.service, nil)
+		req.SetBasicAuth("admin", "admin")
+		req = mux.SetURLVars(req, map[string]string{"service": tt.service})
+		rec := httptest.NewRecorder()
+		handler.GetServiceOpenAPI(rec, req)
+		if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: got %d %s", tt.service, rec.Code, rec.Body.String())
+		}
+	}
+	if auth != "" {
+		t.Errorf("authorization header should not be forwarded, got %q", auth)
+	}
+}
+
+func TestGetServiceOpenAPI_Credentials(t *testing.T) {
+	var auth string
+	ordersvc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"info":{"title":"Ordersvc"}}`))
+	}))
+	defer ordersvc.Close()
+	dir, err := ioutil.TempDir("", "onlinedoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ordersvc.Certificate().Raw}), 0644)
+	for k, v := range map[string]string{
+		config.GddManageUser.String(): "admin",
+		config.GddManagePass.String(): "secret",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	SetRegistry(fakeRegistry{"ordersvc": {ordersvc.URL}})
+	defer SetRegistry(nil)
+
+	get := func() int {
+		req := httptest.NewRequest("GET", "/go-doudou/openapi/ordersvc", nil)
+		req.SetBasicAuth("caller", "password")
+		req = mux.SetURLVars(req, map[string]string{"service": "ordersvc"})
+		rec := httptest.NewRecorder()
+		NewOnlineDocHandler().GetServiceOpenAPI(rec, req)
+		return rec.Code
+	}
+	// the certificate of the node cannot be verified without GDD_TLS_CA
+	if code := get(); code != http.StatusBadGateway || auth != "" {
+		t.Errorf("got %d %q", code, auth)
+	}
+	os.Setenv(config.GddTLSCA.String(), ca)
+	defer os.Unsetenv(config.GddTLSCA.String())
+	if code := get(); code != http.StatusOK || auth != "Basic YWRtaW46c2VjcmV0" {
+		t.Errorf("credentials of this service should be sent to verified nodes, got %d %q", code, auth)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"github.com/unionj-cloud/go-doudou/svc/http/model"
 )
 
+// Routes returns the route exposing metrics of the service, go runtime and the process
 func Routes() []model.Route {
+	register()
 	return []model.Route{
 		{
 			"Prometheus",
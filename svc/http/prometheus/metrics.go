@@ -0,0 +1,108 @@
+package prometheus
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+// SizeBuckets are buckets of request and response size histograms in bytes, from 100B to 10MB
+var SizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+
+var (
+	registerOnce sync.Once
+
+	inFlight      prometheus.Gauge
+	totalRequests *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	requestSize   *prometheus.HistogramVec
+	responseSize  *prometheus.HistogramVec
+
+	clientRequests *prometheus.CounterVec
+	clientDuration *prometheus.HistogramVec
+)
+
+// Buckets returns buckets of latency histograms in seconds set by GDD_PROMETHEUS_BUCKETS,
+// e.g. 0.01,0.05,0.1,0.5,1, or prometheus.DefBuckets if it is empty or invalid
+func Buckets() []float64 {
+	value := config.GddPrometheusBuckets.Load()
+	if stringutils.IsEmpty(value) {
+		return prometheus.DefBuckets
+	}
+	var buckets []float64
+	for _, item := range strings.Split(value, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil || (len(buckets) > 0 && bucket <= buckets[len(buckets)-1]) {
+			logrus.Warnf("Parse %s %s as increasing seconds failed, use default buckets instead.\n", "GDD_PROMETHEUS_BUCKETS", value)
+			return prometheus.DefBuckets
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// register creates and registers collectors once they are used, so that buckets are read from GDD_PROMETHEUS_BUCKETS
+// after .env file is loaded
+func register() {
+	registerOnce.Do(func() {
+		buckets := Buckets()
+		inFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of requests being served.",
+		})
+		totalRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of requests by route, method and status class.",
+		}, []string{"route", "method", "status"})
+		httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_time_seconds",
+			Help:    "Duration of HTTP requests.",
+			Buckets: buckets,
+		}, []string{"route", "method", "status"})
+		requestSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "Size of HTTP request bodies.",
+			Buckets: SizeBuckets,
+		}, []string{"route", "method"})
+		responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Size of HTTP response bodies.",
+			Buckets: SizeBuckets,
+		}, []string{"route", "method"})
+		clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "Number of requests sent by clients by target service, node, client method and status class or 'error'.",
+		}, []string{"service", "node", "method", "status"})
+		clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Duration of requests sent by clients.",
+			Buckets: buckets,
+		}, []string{"service", "node", "method"})
+		prometheus.MustRegister(inFlight, totalRequests, httpDuration, requestSize, responseSize, clientRequests, clientDuration)
+	})
+}
+
+// StatusClass returns status class of code like 2xx
+func StatusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// ObserveClientRequest records a request sent to node of service by client method. Requests failed without responses,
+// e.g. connection refused or open circuit, are counted with status 'error'
+func ObserveClientRequest(service, node, method string, code int, err error, duration time.Duration) {
+	register()
+	status := "error"
+	if err == nil {
+		status = StatusClass(code)
+	}
+	clientRequests.WithLabelValues(service, node, method, status).Inc()
+	if duration > 0 {
+		clientDuration.WithLabelValues(service, node, method).Observe(duration.Seconds())
+	}
+}
@@ -4,68 +4,50 @@ package prometheus
 // Many thanks to TannerGabriel https://github.com/TannerGabriel
 // Post link https://gabrieltanner.org/blog/collecting-prometheus-metrics-in-golang written by TannerGabriel
 import (
-	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"io"
 	"net/http"
-	"strconv"
-)
-
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func NewResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, http.StatusOK}
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
 
-var totalRequests = prometheus.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "http_requests_total",
-		Help: "Number of get requests.",
-	},
-	[]string{"path"},
+	"github.com/felixge/httpsnoop"
 )
 
-var responseStatus = prometheus.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "response_status",
-		Help: "Status of HTTP response",
-	},
-	[]string{"status"},
-)
-
-var httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
-	Name: "http_response_time_seconds",
-	Help: "Duration of HTTP requests.",
-}, []string{"path"})
-
-func PrometheusMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		route := mux.CurrentRoute(r)
-		path, _ := route.GetPathTemplate()
-
-		timer := prometheus.NewTimer(httpDuration.WithLabelValues(path))
-		rw := NewResponseWriter(w)
-		next.ServeHTTP(rw, r)
-
-		statusCode := rw.statusCode
-
-		responseStatus.WithLabelValues(strconv.Itoa(statusCode)).Inc()
-		totalRequests.WithLabelValues(path).Inc()
+// countingBody counts bytes read from request bodies without Content-Length
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
 
-		timer.ObserveDuration()
-	})
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
 }
 
-func init() {
-	prometheus.Register(totalRequests)
-	prometheus.Register(responseStatus)
-	prometheus.Register(httpDuration)
+// Middleware records in-flight requests, and counts, duration, request and response size of requests
+// labeled by route, method and status class like 2xx. routeName is called after the request is served,
+// so it can get the route matched by routers like go-chi/chi
+func Middleware(routeName func(r *http.Request) string) func(http.Handler) http.Handler {
+	register()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Inc()
+			defer inFlight.Dec()
+			var body *countingBody
+			if r.ContentLength < 0 && r.Body != nil {
+				body = &countingBody{ReadCloser: r.Body}
+				r.Body = body
+			}
+			m := httpsnoop.CaptureMetrics(next, w, r)
+
+			route := routeName(r)
+			status := StatusClass(m.Code)
+			totalRequests.WithLabelValues(route, r.Method, status).Inc()
+			httpDuration.WithLabelValues(route, r.Method, status).Observe(m.Duration.Seconds())
+			size := r.ContentLength
+			if body != nil {
+				size = body.n
+			}
+			requestSize.WithLabelValues(route, r.Method).Observe(float64(size))
+			responseSize.WithLabelValues(route, r.Method).Observe(float64(m.Written))
+		})
+	}
 }
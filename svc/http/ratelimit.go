@@ -0,0 +1,253 @@
+package ddhttp
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit allows Requests requests in each Window
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+	// Burst is capacity of the token bucket, the same as Requests by default
+	Burst int
+	// SlidingWindow counts requests in the sliding window instead of refilling the token bucket,
+	// which is stricter for bursts across window boundaries
+	SlidingWindow bool
+}
+
+// RateLimitStore keeps states of rate limits. Implement it with a shared backend like redis
+// to limit requests across all instances of the service
+type RateLimitStore interface {
+	// Allow reports whether a request with key is allowed by limit, if not, retryAfter is the time to wait for the next one
+	Allow(key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimitKey returns the key which requests are counted by
+type RateLimitKey func(r *http.Request) string
+
+// ByClientIP counts requests by ip of r.RemoteAddr. Use handlers.ProxyHeaders before the rate limiter if the service is behind proxies
+func ByClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByHeader counts requests by value of the header
+func ByHeader(name string) RateLimitKey {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// ByAPIKey counts requests by X-API-Key header, or api_key query parameter if the header is absent
+func ByAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// RateLimiter is a middleware limiting requests of each route and client
+type RateLimiter struct {
+	store  RateLimitStore
+	key    RateLimitKey
+	limit  *RateLimit
+	routes map[string]RateLimit
+}
+
+type RateLimiterOption func(*RateLimiter)
+
+// WithRateLimitStore sets store of the rate limiter, MemoryRateLimitStore by default
+func WithRateLimitStore(store RateLimitStore) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.store = store
+	}
+}
+
+// WithRateLimitKey sets how the rate limiter identifies clients, ByClientIP by default
+func WithRateLimitKey(key RateLimitKey) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.key = key
+	}
+}
+
+// WithDefaultRateLimit limits routes without their own limits set by WithRouteRateLimit, each route is counted separately
+func WithDefaultRateLimit(limit RateLimit) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.limit = &limit
+	}
+}
+
+// WithRouteRateLimit limits the routes named as RouteName returns
+func WithRouteRateLimit(limit RateLimit, routes ...string) RateLimiterOption {
+	return func(l *RateLimiter) {
+		for _, route := range routes {
+			l.routes[route] = limit
+		}
+	}
+}
+
+// NewRateLimiter creates RateLimiter. Requests are not limited if neither WithDefaultRateLimit nor WithRouteRateLimit is used
+func NewRateLimiter(opts ...RateLimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		key:    ByClientIP,
+		routes: make(map[string]RateLimit),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.store == nil {
+		l.store = NewMemoryRateLimitStore()
+	}
+	return l
+}
+
+func (l *RateLimiter) limitOf(route string) (RateLimit, bool) {
+	if limit, ok := l.routes[route]; ok {
+		return limit, true
+	}
+	if l.limit != nil {
+		return *l.limit, true
+	}
+	return RateLimit{}, false
+}
+
+// Middleware responds 429 with Retry-After header when requests exceed the limit.
+// Requests are let through if the store fails
+func (l *RateLimiter) Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := RouteName(r)
+		limit, ok := l.limitOf(route)
+		if !ok {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		allowed, retryAfter, err := l.store.Allow(route+":"+l.key(r), limit)
+		if err != nil {
+			logrus.Errorf("rate limit of route %s failed: %v", route, err)
+		}
+		if err != nil || allowed {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		HandleError(w, NewHttpError(http.StatusTooManyRequests, http.StatusTooManyRequests, "too many requests"))
+	})
+}
+
+type rateState struct {
+	// tokens left in the token bucket, or count of requests in the previous window for sliding window
+	tokens float64
+	// requests in the current window for sliding window
+	count int
+	// last is the time of last refill for token bucket, or start of the current window for sliding window
+	last   time.Time
+	window time.Duration
+}
+
+// MemoryRateLimitStore keeps states of rate limits in memory of the service instance
+type MemoryRateLimitStore struct {
+	mu     sync.Mutex
+	states map[string]*rateState
+	swept  time.Time
+	now    func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		states: make(map[string]*rateState),
+		swept:  time.Now(),
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Allow(key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit.Window <= 0 {
+		limit.Window = time.Second
+	}
+	now := s.now()
+	s.sweep(now)
+	state, ok := s.states[key]
+	if !ok {
+		state = &rateState{
+			last:   now,
+			window: limit.Window,
+		}
+		if !limit.SlidingWindow {
+			state.tokens = float64(burstOf(limit))
+		}
+		s.states[key] = state
+	}
+	if limit.SlidingWindow {
+		return slidingWindow(state, limit, now)
+	}
+	return tokenBucket(state, limit, now)
+}
+
+// sweep drops states which have been idle for two windows every minute, so that memory is not held by clients gone
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+	for key, state := range s.states {
+		if now.Sub(state.last) > 2*state.window {
+			delete(s.states, key)
+		}
+	}
+}
+
+func burstOf(limit RateLimit) int {
+	if limit.Burst > 0 {
+		return limit.Burst
+	}
+	return limit.Requests
+}
+
+func tokenBucket(state *rateState, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	rate := float64(limit.Requests) / float64(limit.Window)
+	state.tokens = math.Min(float64(burstOf(limit)), state.tokens+float64(now.Sub(state.last))*rate)
+	state.last = now
+	if state.tokens >= 1 {
+		state.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration(math.Ceil((1 - state.tokens) / rate)), nil
+}
+
+// slidingWindow estimates requests in the last window by weighting count of the previous window
+// with its overlap, as the sliding window counter algorithm does
+func slidingWindow(state *rateState, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	if elapsed := now.Sub(state.last); elapsed >= limit.Window {
+		windows := elapsed / limit.Window
+		state.tokens = 0
+		if windows == 1 {
+			state.tokens = float64(state.count)
+		}
+		state.count = 0
+		state.last = state.last.Add(windows * limit.Window)
+	}
+	overlap := 1 - float64(now.Sub(state.last))/float64(limit.Window)
+	if state.tokens*overlap+float64(state.count) < float64(limit.Requests) {
+		state.count++
+		return true, 0, nil
+	}
+	// wait until enough of the previous window slides out, or the current window ends
+	retryAfter := state.last.Add(limit.Window).Sub(now)
+	if state.tokens > 0 && float64(state.count) < float64(limit.Requests) {
+		need := (state.tokens*overlap + float64(state.count) - float64(limit.Requests) + 1) / state.tokens
+		retryAfter = time.Duration(math.Ceil(need * float64(limit.Window)))
+	}
+	return false, retryAfter, nil
+}
@@ -0,0 +1,189 @@
+package ddhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	store.swept = clock.now
+	return store, clock
+}
+
+type step struct {
+	after      time.Duration
+	allowed    bool
+	retryAfter time.Duration
+}
+
+func runSteps(t *testing.T, limit RateLimit, steps []step) {
+	store, clock := newTestStore()
+	for i, s := range steps {
+		clock.now = clock.now.Add(s.after)
+		allowed, retryAfter, err := store.Allow("GetUser:127.0.0.1", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != s.allowed || retryAfter != s.retryAfter {
+			t.Errorf("step %d: got allowed %v retry after %s, want %v %s", i, allowed, retryAfter, s.allowed, s.retryAfter)
+		}
+	}
+}
+
+func TestMemoryRateLimitStore_TokenBucket(t *testing.T) {
+	runSteps(t, RateLimit{Requests: 2, Window: time.Second}, []step{
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 250 * time.Millisecond},
+		{250 * time.Millisecond, true, 0},
+		{10 * time.Second, true, 0},
+		{0, true, 0},
+		{0, false, 500 * time.Millisecond},
+	})
+	runSteps(t, RateLimit{Requests: 1, Window: time.Second, Burst: 3}, []step{
+		{0, true, 0},
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, time.Second},
+	})
+}
+
+func TestMemoryRateLimitStore_SlidingWindow(t *testing.T) {
+	runSteps(t, RateLimit{Requests: 2, Window: time.Second, SlidingWindow: true}, []step{
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, time.Second},
+		// previous window fully overlaps
+		{time.Second, false, 500 * time.Millisecond},
+		// half of the previous window has slid out
+		{500 * time.Millisecond, true, 0},
+		{0, false, 500 * time.Millisecond},
+		// the previous window is too old
+		{3 * time.Second, true, 0},
+	})
+}
+
+func TestMemoryRateLimitStore_Sweep(t *testing.T) {
+	store, clock := newTestStore()
+	store.Allow("a", RateLimit{Requests: 1, Window: time.Second})
+	clock.now = clock.now.Add(2 * time.Minute)
+	store.Allow("b", RateLimit{Requests: 1, Window: time.Second})
+	if _, ok := store.states["a"]; ok || len(store.states) != 1 {
+		t.Error("idle states should be dropped")
+	}
+}
+
+type brokenStore struct{}
+
+func (brokenStore) Allow(key string, limit RateLimit) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimiter(t *testing.T) {
+	srvs := map[string]func() Srv{
+		"gorilla": NewDefaultHttpSrv,
+		"chi":     NewChiHttpSrv,
+	}
+	for name, newSrv := range srvs {
+		t.Run(name, func(t *testing.T) {
+			limiter := NewRateLimiter(
+				WithRouteRateLimit(RateLimit{Requests: 1, Window: time.Minute}, "GetUser"),
+				WithRateLimitKey(ByHeader("X-Tenant")),
+			)
+			srv := newSrv()
+			srv.AddMiddleware(limiter.Middleware)
+			srv.AddRoute(testRoutes()...)
+			handler := srv.(http.Handler)
+			serve := func(method, path, tenant string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(method, path, nil)
+				req.Header.Set("X-Tenant", tenant)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+			if rec := serve("GET", "/users/1", "a"); rec.Code != http.StatusOK {
+				t.Errorf("first request should be allowed, got %d", rec.Code)
+			}
+			rec := serve("GET", "/users/2", "a")
+			if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" ||
+				!strings.Contains(rec.Body.String(), `"code":429`) {
+				t.Errorf("second request should be limited, got %d %v %s", rec.Code, rec.Header(), rec.Body.String())
+			}
+			if rec := serve("GET", "/users/1", "b"); rec.Code != http.StatusOK {
+				t.Errorf("requests of other clients should be allowed, got %d", rec.Code)
+			}
+			for i := 0; i < 3; i++ {
+				if rec := serve("POST", "/users", "a"); rec.Code != http.StatusOK {
+					t.Errorf("routes without limit should be allowed, got %d", rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Default(t *testing.T) {
+	limiter := NewRateLimiter(
+		WithDefaultRateLimit(RateLimit{Requests: 1, Window: time.Second}),
+		WithRouteRateLimit(RateLimit{Requests: 2, Window: time.Second}, "PageUsers"),
+	)
+	srv := NewChiHttpSrv()
+	srv.AddMiddleware(limiter.Middleware)
+	srv.AddRoute(testRoutes()...)
+	handler := srv.(http.Handler)
+	var codes []int
+	for _, path := range []string{"/users/1", "/users/1", "/users/1"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		codes = append(codes, rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/users", nil))
+		codes = append(codes, rec.Code)
+	}
+	want := []int{200, 429, 429, 200, 200, 429}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("got %v, want %v", codes, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewRateLimiter(WithDefaultRateLimit(RateLimit{Requests: 1}), WithRateLimitStore(brokenStore{})).
+		Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("requests should be let through if store fails, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users?api_key=q", nil)
+	req.RemoteAddr = "10.0.0.1:52345"
+	if got := ByClientIP(req); got != "10.0.0.1" {
+		t.Errorf("ByClientIP() = %s", got)
+	}
+	if got := ByAPIKey(req); got != "q" {
+		t.Errorf("ByAPIKey() = %s", got)
+	}
+	req.Header.Set("X-API-Key", "h")
+	if got := ByAPIKey(req); got != "h" {
+		t.Errorf("ByAPIKey() = %s", got)
+	}
+}
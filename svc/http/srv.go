@@ -1,18 +1,26 @@
 package ddhttp
 
 import (
+	"context"
 	"fmt"
+	"github.com/common-nighthawk/go-figure"
+	"github.com/gorilla/mux"
 	"github.com/olekukonko/tablewriter"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/pathutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
 	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/health"
 	"github.com/unionj-cloud/go-doudou/svc/http/model"
+	"github.com/unionj-cloud/go-doudou/svc/http/tlsutils"
+	"github.com/unionj-cloud/go-doudou/svc/tracing"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -21,18 +29,186 @@ type Srv interface {
 	Run()
 	// Register routes
 	AddRoute(route ...model.Route)
-	// Use middleware
+	// Use middleware, e.g. Middleware of HttpLogger, Auth, Cache, Idempotency, BodyLimit and RateLimiter
 	AddMiddleware(mwf ...func(http.Handler) http.Handler)
 }
 
-func newServer(router http.Handler) *http.Server {
-	port := config.GddPort.Load()
+// NewHttpSrv creates Srv based on router chosen by GDD_ROUTER environment variable,
+// ChiHttpSrv for 'chi', otherwise DefaultHttpSrv based on gorilla/mux
+func NewHttpSrv() Srv {
+	if config.GddRouter.Load() == "chi" {
+		return NewChiHttpSrv()
+	}
+	return NewDefaultHttpSrv()
+}
+
+// WithPrefix returns copy of routes whose patterns are prefixed with prefix, so that routes of a service interface
+// can be mounted under its own prefix, e.g. srv.AddRoute(ddhttp.WithPrefix("/admin", httpsrv.AdminsvcRoutes(handler))...)
+func WithPrefix(prefix string, routes []model.Route) []model.Route {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return routes
+	}
+	var ret []model.Route
+	for _, item := range routes {
+		item.Pattern = prefix + item.Pattern
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+type routeNameCtx struct{}
+
+func withRouteName(name string) func(http.Handler) http.Handler {
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inner.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeNameCtx{}, name)))
+		})
+	}
+}
+
+// RouteName returns name of the route matched by r, i.e. model.Route.Name. Generated routes are named after methods in svc.go
+// without Get, Post, Put or Delete prefix, e.g. User for both GetUser and DeleteUser. Route names are what options of Auth,
+// BodyLimit, Cache, Idempotency and RateLimiter refer to. It is available in middlewares added by AddMiddleware of both
+// DefaultHttpSrv and ChiHttpSrv
+func RouteName(r *http.Request) string {
+	if name, ok := r.Context().Value(routeNameCtx{}).(string); ok {
+		return name
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName()
+	}
+	return ""
+}
+
+// setShuttingDown is replaced in tests, as readiness of the default health registry cannot be restored
+var setShuttingDown = health.SetShuttingDown
+
+// run starts http server with router, and shuts it down gracefully by shutdown hooks when receives SIGINT or SIGTERM
+func run(router http.Handler, routes []model.Route) {
+	start := time.Now()
+	var logptr *string
+	logpath, isSet := os.LookupEnv(config.GddLogPath.String())
+	if isSet {
+		logptr = &logpath
+	}
+	var loglevel config.LogLevel
+	(&loglevel).Decode(config.GddLogLevel.Load())
+
+	logFile := configureLogger(logrus.StandardLogger(), logptr, logrus.Level(loglevel), config.GddLogFormat.Load())
+
+	lifecycle := defaultLifecycle.clone()
+	lifecycle.OnShutdown(PhaseNotReady, "readiness", notReady(shutdownDelay()))
+	if closer := configureTracing(config.GddTracingOutput.Load()); closer != nil {
+		lifecycle.OnShutdown(PhaseCleanup, "tracing exporter", func(ctx context.Context) error {
+			return closer.Close()
+		})
+	}
+	if logFile != nil {
+		lifecycle.OnShutdown(PhaseCleanup, "log file", func(ctx context.Context) error {
+			logrus.SetOutput(os.Stdout)
+			return logFile.Close()
+		})
+	}
+
+	var bannerSwitch config.Switch
+	(&bannerSwitch).Decode(config.GddBanner.Load())
+	if bannerSwitch {
+		banner := config.GddBannerText.Load()
+		if stringutils.IsEmpty(banner) {
+			banner = "Go-doudou"
+		}
+		figure.NewColorFigure(banner, "doom", "green", true).Print()
+	}
+
+	printRoutes(routes)
+
+	active := &activeRequests{}
+	server := newServer(active.track(router))
+	lifecycle.OnShutdown(PhaseDrain, "in-flight requests", active.wait)
+	lifecycle.OnShutdown(PhaseServer, "http server", func(ctx context.Context) error {
+		// Doesn't block if no connections, but will otherwise wait until the timeout deadline,
+		// then connections still active like streams are closed
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			return err
+		}
+		return nil
+	})
+
+	logrus.Infof("Started in %s\n", time.Since(start))
+
+	c := make(chan os.Signal, 1)
+	// We'll accept graceful shutdowns when quit via SIGINT (Ctrl+C) or SIGTERM sent by kubernetes.
+	// SIGKILL or SIGQUIT (Ctrl+/) will not be caught.
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	// Block until we receive our signal.
+	<-c
+	logrus.Infoln("shutting down")
+
+	// Create a deadline to wait for.
+	grace, err := time.ParseDuration(config.GddGraceTimeout.Load())
+	if err != nil {
+		logrus.Warnf("Parse %s %s as time.Duration failed: %s, use default 15s instead.\n", "GDD_GRACETIMEOUT",
+			config.GddGraceTimeout.Load(), err.Error())
+		grace = 15 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	lifecycle.Shutdown(ctx)
+}
+
+// shutdownDelay returns GDD_SHUTDOWN_DELAY, or 0 if it is empty or invalid
+func shutdownDelay() time.Duration {
+	value := config.GddShutdownDelay.Load()
+	if stringutils.IsEmpty(value) {
+		return 0
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Warnf("Parse %s %s as time.Duration failed: %s, shut down without delay.\n", "GDD_SHUTDOWN_DELAY",
+			value, err.Error())
+		return 0
+	}
+	return delay
+}
+
+// notReady returns the shutdown hook failing readiness checks, which then holds for delay or until ctx is done,
+// as in-flight requests may be drained at once while load balancers haven't seen the service not ready yet
+func notReady(delay time.Duration) ShutdownHook {
+	return func(ctx context.Context) error {
+		setShuttingDown()
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// writeTimeout returns GDD_WRITETIMEOUT, or 15s if it is invalid
+func writeTimeout() time.Duration {
 	write, err := time.ParseDuration(config.GddWriteTimeout.Load())
 	if err != nil {
 		logrus.Warnf("Parse %s %s as time.Duration failed: %s, use default 15s instead.\n", "GDD_WRITETIMEOUT",
 			config.GddWriteTimeout.Load(), err.Error())
 		write = 15 * time.Second
 	}
+	return write
+}
+
+func newServer(router http.Handler) *http.Server {
+	port := config.GddPort.Load()
+	write := writeTimeout()
 
 	read, err := time.ParseDuration(config.GddReadTimeout.Load())
 	if err != nil {
@@ -48,19 +224,58 @@ func newServer(router http.Handler) *http.Server {
 		idle = 60 * time.Second
 	}
 
+	// zero ReadHeaderTimeout falls back to ReadTimeout
+	var readHeader time.Duration
+	if value := config.GddReadHeaderTimeout.Load(); stringutils.IsNotEmpty(value) {
+		if readHeader, err = time.ParseDuration(value); err != nil {
+			logrus.Warnf("Parse %s %s as time.Duration failed: %s, use GDD_READTIMEOUT instead.\n", "GDD_READ_HEADER_TIMEOUT",
+				value, err.Error())
+			readHeader = 0
+		}
+	}
+
+	maxHeaderBytes := http.DefaultMaxHeaderBytes
+	if value := config.GddMaxHeaderBytes.Load(); stringutils.IsNotEmpty(value) {
+		var size config.ByteSize
+		if err = size.Decode(value); err != nil || size == 0 {
+			logrus.Warnf("Parse %s %s as byte size failed, use default 1MB instead.\n", "GDD_MAX_HEADER_BYTES", value)
+		} else {
+			maxHeaderBytes = int(size)
+		}
+	}
+
 	server := &http.Server{
 		Addr: strings.Join([]string{"", port}, ":"),
 		// Good practice to set timeouts to avoid Slowloris attacks.
-		WriteTimeout: write,
-		ReadTimeout:  read,
-		IdleTimeout:  idle,
-		Handler:      router, // Pass our instance of gorilla/mux in.
+		WriteTimeout:      write,
+		ReadTimeout:       read,
+		ReadHeaderTimeout: readHeader,
+		IdleTimeout:       idle,
+		MaxHeaderBytes:    maxHeaderBytes,
+		Handler:           router, // Pass our instance of gorilla/mux in.
+		ConnContext:       withConn,
+	}
+
+	tlsConfig, err := tlsutils.ServerConfig()
+	if err != nil {
+		// exit instead of running without listener, which looks healthy to supervisors but serves nothing
+		logrus.Fatalf("Configure tls failed: %v, http server is not started.\n", err)
+		return server
 	}
+	server.TLSConfig = tlsConfig
 
 	// Run our server in a goroutine so that it doesn't block.
 	go func() {
-		logrus.Infof("Http server is listening on %s\n", server.Addr)
-		if err := server.ListenAndServe(); err != nil {
+		var err error
+		if tlsConfig != nil {
+			logrus.Infof("Https server is listening on %s\n", server.Addr)
+			// Certificates are loaded by tlsConfig
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logrus.Infof("Http server is listening on %s\n", server.Addr)
+			err = server.ListenAndServe()
+		}
+		if err != nil {
 			logrus.Println(err)
 		}
 	}()
@@ -68,11 +283,17 @@ func newServer(router http.Handler) *http.Server {
 	return server
 }
 
-func configureLogger(logger *logrus.Logger, logptr *string, level logrus.Level) *os.File {
-	formatter := new(logrus.TextFormatter)
-	formatter.TimestampFormat = "2006-01-02 15:04:05"
-	formatter.FullTimestamp = true
-	logger.SetFormatter(formatter)
+func configureLogger(logger *logrus.Logger, logptr *string, level logrus.Level, format string) *os.File {
+	if format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	} else {
+		formatter := new(logrus.TextFormatter)
+		formatter.TimestampFormat = "2006-01-02 15:04:05"
+		formatter.FullTimestamp = true
+		logger.SetFormatter(formatter)
+	}
 	logger.SetLevel(level)
 
 	if logptr != nil {
@@ -105,6 +326,25 @@ func configureLogger(logger *logrus.Logger, logptr *string, level logrus.Level)
 	return nil
 }
 
+// configureTracing sets exporter of the default tracer by output, which is 'stdout' or a file path.
+// The returned io.Closer is not nil if a file is opened
+func configureTracing(output string) io.Closer {
+	switch output {
+	case "":
+		return nil
+	case "stdout":
+		tracing.SetExporter(tracing.NewWriterExporter(os.Stdout))
+		return nil
+	}
+	exporter, err := tracing.NewFileExporter(output)
+	if err != nil {
+		logrus.Errorf("export spans to %s failed: %v", output, err)
+		return nil
+	}
+	tracing.SetExporter(exporter)
+	return exporter
+}
+
 func printRoutes(routes []model.Route) {
 	logrus.Infoln("================ Registered Routes ================")
 	data := [][]string{}
@@ -0,0 +1,310 @@
+package ddhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+	"github.com/unionj-cloud/go-doudou/svc/http/prometheus"
+)
+
+func setEnv(t *testing.T, kv map[string]string) {
+	for k, v := range kv {
+		old, isSet := os.LookupEnv(k)
+		os.Setenv(k, v)
+		k := k
+		t.Cleanup(func() {
+			if isSet {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func testRoutes() []model.Route {
+	return []model.Route{
+		{
+			Name:    "GetUser",
+			Method:  "GET",
+			Pattern: "/users/{id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("user " + mux.Vars(r)["id"]))
+			},
+		},
+		{
+			Name:    "PageUsers",
+			Method:  "POST",
+			Pattern: "/users",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("users"))
+			},
+		},
+	}
+}
+
+func TestSrv(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddManage.String():        "true",
+		config.GddManageUser.String():    "admin",
+		config.GddManagePass.String():    "secret",
+		config.GddRouteRootPath.String(): "/api",
+	})
+	srvs := map[string]func() Srv{
+		"gorilla": NewDefaultHttpSrv,
+		"chi":     NewChiHttpSrv,
+	}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		auth       bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"path variable", "GET", "/api/users/1", false, http.StatusOK, "user 1"},
+		{"post", "POST", "/api/users", false, http.StatusOK, "users"},
+		{"not match pattern", "GET", "/api/users/abc", false, http.StatusNotFound, ""},
+		{"without root path", "GET", "/users/1", false, http.StatusNotFound, ""},
+		{"method not allowed", "GET", "/api/users", false, http.StatusMethodNotAllowed, ""},
+		{"trailing slash", "GET", "/api/users/1/", false, http.StatusMovedPermanently, ""},
+		{"manage api without auth", "GET", "/api/go-doudou/openapi.json", false, http.StatusUnauthorized, ""},
+		{"manage api", "GET", "/api/go-doudou/prometheus", true, http.StatusOK, "http_requests_total"},
+		{"health without auth", "GET", "/api/go-doudou/health", false, http.StatusUnauthorized, ""},
+		{"health", "GET", "/api/go-doudou/health", true, http.StatusOK, `"status":"up"`},
+		{"liveness probe", "GET", "/api/go-doudou/livez", false, http.StatusOK, `"status":"up"`},
+		{"readiness probe", "GET", "/api/go-doudou/readyz", false, http.StatusOK, `"status":"up"`},
+	}
+	for name, newSrv := range srvs {
+		srv := newSrv()
+		srv.AddMiddleware(func(inner http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Test", "middleware")
+				inner.ServeHTTP(w, r)
+			})
+		})
+		srv.AddRoute(testRoutes()...)
+		handler := srv.(http.Handler)
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				if tt.auth {
+					req.SetBasicAuth("admin", "secret")
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+				}
+				if !strings.Contains(rec.Body.String(), tt.wantBody) {
+					t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+				}
+				if rec.Code == http.StatusOK && rec.Header().Get("X-Test") != "middleware" {
+					t.Error("middleware should be applied")
+				}
+			})
+		}
+	}
+}
+
+func TestPrometheusMiddleware(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddManage.String():     "true",
+		config.GddManageUser.String(): "",
+		config.GddManagePass.String(): "",
+	})
+	srvs := map[string]func() Srv{
+		"gorilla": NewDefaultHttpSrv,
+		"chi":     NewChiHttpSrv,
+	}
+	for name, newSrv := range srvs {
+		t.Run(name, func(t *testing.T) {
+			srv := newSrv()
+			srv.AddMiddleware()
+			routes := testRoutes()
+			for i := range routes {
+				routes[i].Name = name + routes[i].Name
+			}
+			srv.AddRoute(routes...)
+			handler := srv.(http.Handler)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/2", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users", strings.NewReader(`{"page":1}`)))
+			metrics := scrape()
+			for _, want := range []string{
+				`http_requests_total{method="GET",route="` + name + `GetUser",status="2xx"} 1`,
+				`http_response_time_seconds_count{method="POST",route="` + name + `PageUsers",status="2xx"} 1`,
+				`http_request_size_bytes_sum{method="POST",route="` + name + `PageUsers"} 10`,
+				`http_response_size_bytes_sum{method="GET",route="` + name + `GetUser"} 6`,
+				`http_requests_in_flight 0`,
+			} {
+				if !strings.Contains(metrics, want) {
+					t.Errorf("metrics should contain %s, got %s", want, metrics)
+				}
+			}
+		})
+	}
+}
+
+func TestSrv_AddMiddlewareOrder(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddManage.String():     "true",
+		config.GddManageUser.String(): "",
+		config.GddManagePass.String(): "",
+	})
+	header := func(key string) func(http.Handler) http.Handler {
+		return func(inner http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(key, "true")
+				inner.ServeHTTP(w, r)
+			})
+		}
+	}
+	tests := []struct {
+		name       string
+		setup      func(srv Srv, routes []model.Route)
+		wantHeader []string
+	}{
+		{"add middleware twice", func(srv Srv, routes []model.Route) {
+			srv.AddMiddleware(header("X-First"))
+			srv.AddMiddleware(header("X-Second"))
+			srv.AddRoute(routes...)
+		}, []string{"X-First", "X-Second"}},
+		{"add route before middleware", func(srv Srv, routes []model.Route) {
+			srv.AddRoute(routes...)
+			srv.AddMiddleware(header("X-First"))
+		}, []string{"X-First"}},
+		{"add route between middlewares", func(srv Srv, routes []model.Route) {
+			srv.AddMiddleware(header("X-First"))
+			srv.AddRoute(routes...)
+			srv.AddMiddleware(header("X-Second"))
+		}, []string{"X-First", "X-Second"}},
+	}
+	for _, router := range []string{"chi", ""} {
+		for i, tt := range tests {
+			t.Run(router+"/"+tt.name, func(t *testing.T) {
+				setEnv(t, map[string]string{config.GddRouter.String(): router})
+				srv := NewHttpSrv()
+				routes := testRoutes()[:1]
+				routes[0].Name = "Order" + router + strconv.Itoa(i)
+				assert.NotPanics(t, func() {
+					tt.setup(srv, routes)
+				})
+				handler := srv.(http.Handler)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+				if rec.Code != http.StatusOK || rec.Body.String() != "user 1" {
+					t.Errorf("got %d %s", rec.Code, rec.Body.String())
+				}
+				for _, key := range tt.wantHeader {
+					assert.Equal(t, "true", rec.Header().Get(key), "middleware %s should be applied to routes", key)
+				}
+				rec = httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest("GET", "/go-doudou/livez", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("probe should be served, got %d", rec.Code)
+				}
+				for _, key := range tt.wantHeader {
+					assert.Equal(t, "true", rec.Header().Get(key), "middleware %s should be applied to probes", key)
+				}
+				rec = httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest("GET", "/go-doudou/health", nil))
+				for _, key := range tt.wantHeader {
+					assert.Equal(t, "true", rec.Header().Get(key), "middleware %s should be applied to /go-doudou routes", key)
+				}
+				want := `http_requests_total{method="GET",route="` + routes[0].Name + `",status="2xx"} 1`
+				if metrics := scrape(); !strings.Contains(metrics, want) {
+					t.Errorf("metrics should contain %s once", want)
+				}
+			})
+		}
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []float64
+	}{
+		{"", prom.DefBuckets},
+		{"0.01, 0.1,1", []float64{0.01, 0.1, 1}},
+		{"0.1,0.01", prom.DefBuckets},
+		{"1s", prom.DefBuckets},
+	}
+	for _, tt := range tests {
+		setEnv(t, map[string]string{config.GddPrometheusBuckets.String(): tt.value})
+		if got := prometheus.Buckets(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Buckets() of %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewHttpSrv(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddRouter.String(): "chi",
+	})
+	if _, ok := NewHttpSrv().(*ChiHttpSrv); !ok {
+		t.Error("NewHttpSrv() should return *ChiHttpSrv for GDD_ROUTER=chi")
+	}
+	os.Setenv(config.GddRouter.String(), "")
+	if _, ok := NewHttpSrv().(*DefaultHttpSrv); !ok {
+		t.Error("NewHttpSrv() should return *DefaultHttpSrv by default")
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddPort.String():              "0",
+		config.GddReadHeaderTimeout.String(): "5s",
+		config.GddMaxHeaderBytes.String():    "64KB",
+	})
+	server := newServer(http.NotFoundHandler())
+	defer server.Close()
+	if server.ReadHeaderTimeout != 5*time.Second || server.MaxHeaderBytes != 64<<10 {
+		t.Errorf("got ReadHeaderTimeout %v and MaxHeaderBytes %d", server.ReadHeaderTimeout, server.MaxHeaderBytes)
+	}
+}
+
+// exited reports whether fn exits the process by logrus.Fatal
+func exited(fn func()) (exited bool) {
+	logger := logrus.StandardLogger()
+	exit := logger.ExitFunc
+	logger.ExitFunc = func(int) {
+		panic("exit")
+	}
+	defer func() {
+		logger.ExitFunc = exit
+		exited = recover() == "exit"
+	}()
+	fn()
+	return false
+}
+
+func TestInvalidTLS(t *testing.T) {
+	setEnv(t, map[string]string{
+		config.GddPort.String():    "0",
+		config.GddTLSCert.String(): "notexist.crt",
+		config.GddTLSKey.String():  "notexist.key",
+	})
+	if !exited(func() {
+		newServer(http.NotFoundHandler())
+	}) {
+		t.Error("server should exit if tls config is invalid")
+	}
+	if !exited(func() {
+		NewClient()
+	}) {
+		t.Error("client should exit if tls config is invalid")
+	}
+}
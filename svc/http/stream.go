@@ -0,0 +1,174 @@
+package ddhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+// StartEventStream sets headers of server-sent events response and writes 200 status code,
+// then values can be written by WriteEvent
+func StartEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+}
+
+// WriteEvent writes v as json encoded data of a server-sent event, e.g. data: {"id":1}, and flushes it to the client
+func WriteEvent(w http.ResponseWriter, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+// WriteStream writes r to w as chunked application/octet-stream response, flushing each chunk to the client as soon as it is read.
+// If r is also io.Closer, it will be closed after written
+func WriteStream(w http.ResponseWriter, r io.Reader) error {
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			flush(w)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReadEvents reads server-sent events from r and calls fn with data of each event.
+// Multiple data lines of an event are joined by line break, and comments and other fields are ignored.
+// It returns when r reaches EOF, fails or fn returns false
+func ReadEvents(r io.Reader, fn func(data []byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var data [][]byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if len(data) > 0 && !fn(bytes.Join(data, []byte("\n"))) {
+				return nil
+			}
+			data = nil
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("data:")) {
+			value := bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))
+			data = append(data, append([]byte(nil), value...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		fn(bytes.Join(data, []byte("\n")))
+	}
+	return nil
+}
+
+type connCtx struct{}
+
+// withConn is ConnContext of the http server, by which NewStreamWriter gets the connection of requests
+func withConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connCtx{}, c)
+}
+
+// streamWriter extends write deadline of the connection by timeout before each write, or clears it if timeout is 0
+type streamWriter struct {
+	http.ResponseWriter
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (s *streamWriter) extend() {
+	var deadline time.Time
+	if s.timeout > 0 {
+		deadline = time.Now().Add(s.timeout)
+	}
+	s.conn.SetWriteDeadline(deadline)
+}
+
+func (s *streamWriter) WriteHeader(code int) {
+	s.extend()
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.extend()
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *streamWriter) Flush() {
+	s.extend()
+	flush(s.ResponseWriter)
+}
+
+// NewStreamWriter returns writer of event and byte streams, which lets each write take GDD_STREAM_TIMEOUT instead of
+// cutting off the whole response at GDD_WRITETIMEOUT, so long lived streams are not closed while slow clients still time out.
+// w is returned as it is for http/2 requests, which are still limited by GDD_WRITETIMEOUT
+func NewStreamWriter(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	conn, ok := r.Context().Value(connCtx{}).(net.Conn)
+	if !ok || r.ProtoMajor != 1 || writeTimeout() <= 0 {
+		return w
+	}
+	return &streamWriter{
+		ResponseWriter: w,
+		conn:           conn,
+		timeout:        streamTimeout(),
+	}
+}
+
+// streamTimeout returns GDD_STREAM_TIMEOUT, or GDD_WRITETIMEOUT if it is empty or invalid
+func streamTimeout() time.Duration {
+	value := config.GddStreamTimeout.Load()
+	if stringutils.IsEmpty(value) {
+		return writeTimeout()
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Warnf("Parse %s %s as time.Duration failed: %s, use GDD_WRITETIMEOUT instead.\n", "GDD_STREAM_TIMEOUT",
+			value, err.Error())
+		return writeTimeout()
+	}
+	return timeout
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,169 @@
+package ddhttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+func TestWriteEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	StartEventStream(rec)
+	if err := WriteEvent(rec, map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteEvent(rec, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %s, want text/event-stream", got)
+	}
+	if !rec.Flushed {
+		t.Error("events should be flushed")
+	}
+	want := "data: {\"id\":1}\n\ndata: \"done\"\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if err := WriteEvent(rec, func() {}); err == nil {
+		t.Error("WriteEvent() should return error for value which cannot be encoded to json")
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": comment\n" +
+		"data: {\"id\":1}\n\n" +
+		"event: update\n" +
+		"data: line1\n" +
+		"data:line2\n\n" +
+		"\n" +
+		"data: last"
+	var got []string
+	if err := ReadEvents(strings.NewReader(stream), func(data []byte) bool {
+		got = append(got, string(data))
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"id":1}`, "line1\nline2", "last"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadEvents() got = %q, want %q", got, want)
+	}
+
+	got = nil
+	ReadEvents(strings.NewReader(stream), func(data []byte) bool {
+		got = append(got, string(data))
+		return false
+	})
+	if len(got) != 1 {
+		t.Errorf("ReadEvents() should stop when fn returns false, got %q", got)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+type closeRecorder struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriteStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &closeRecorder{Reader: bytes.NewReader([]byte("a,b\n1,2\n"))}
+	if err := WriteStream(rec, r); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Body.String() != "a,b\n1,2\n" || rec.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("WriteStream() wrote %q with headers %v", rec.Body.String(), rec.Header())
+	}
+	if !r.closed {
+		t.Error("WriteStream() should close reader")
+	}
+	if err := WriteStream(httptest.NewRecorder(), errReader{}); err == nil {
+		t.Error("WriteStream() should return error of reader")
+	}
+}
+
+func TestNewStreamWriter(t *testing.T) {
+	setEnv(t, map[string]string{config.GddWriteTimeout.String(): "100ms"})
+	tests := []struct {
+		name       string
+		stream     bool
+		wantEvents int
+	}{
+		{"cut off by write timeout", false, 1},
+		{"stream writer", true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.stream {
+					w = NewStreamWriter(w, r)
+				}
+				StartEventStream(w)
+				for i := 0; i < 4; i++ {
+					if i > 0 {
+						time.Sleep(60 * time.Millisecond)
+					}
+					if err := WriteEvent(w, i); err != nil {
+						return
+					}
+				}
+			}))
+			ts.Config.WriteTimeout = writeTimeout()
+			ts.Config.ConnContext = withConn
+			ts.Start()
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var events int
+			ReadEvents(resp.Body, func(data []byte) bool {
+				events++
+				return true
+			})
+			if events < tt.wantEvents || (!tt.stream && events == 4) {
+				t.Errorf("got %d events, want %d", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorOfRawBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HandleError(w, NewHttpError(http.StatusNotFound, 10404, "topic not found"))
+	}))
+	defer ts.Close()
+	resp, err := resty.New().R().SetDoNotParseResponse(true).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var herr *HttpError
+	if !errors.As(DecodeError(resp), &herr) || herr.Code != 10404 || herr.Status != http.StatusNotFound {
+		t.Errorf("DecodeError() = %v", herr)
+	}
+	if _, err = ioutil.ReadAll(resp.RawBody()); err == nil {
+		t.Error("raw body should be closed")
+	}
+}
@@ -0,0 +1,224 @@
+// Package tlsutils builds tls configs of servers and clients from GDD_TLS_CERT, GDD_TLS_KEY, GDD_TLS_CA
+// and GDD_TLS_CLIENT_AUTH, certificates are reloaded once their files change
+package tlsutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+// CheckInterval is the minimum interval between checks of certificate files for changes
+var CheckInterval = 10 * time.Second
+
+// Enabled reports whether the server serves https, i.e. both GDD_TLS_CERT and GDD_TLS_KEY are set
+func Enabled() bool {
+	return stringutils.IsNotEmpty(config.GddTLSCert.Load()) && stringutils.IsNotEmpty(config.GddTLSKey.Load())
+}
+
+// Scheme returns 'https' if Enabled, otherwise 'http'
+func Scheme() string {
+	if Enabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// ServerConfig returns tls config of the server, or nil if not Enabled.
+// Client certificates are required or verified by GDD_TLS_CA according to GDD_TLS_CLIENT_AUTH
+func ServerConfig() (*tls.Config, error) {
+	if !Enabled() {
+		return nil, nil
+	}
+	var clientAuth tls.ClientAuthType
+	switch auth := config.GddTLSClientAuth.Load(); auth {
+	case "":
+		clientAuth = tls.NoClientCert
+	case "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	case "verify":
+		clientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, errors.Errorf("not support %s as %s, accept 'require' or 'verify'", auth, config.GddTLSClientAuth)
+	}
+	if clientAuth != tls.NoClientCert && stringutils.IsEmpty(config.GddTLSCA.Load()) {
+		return nil, errors.Errorf("%s is required to verify client certificates", config.GddTLSCA)
+	}
+	r, err := newReloader(config.GddTLSCert.Load(), config.GddTLSKey.Load(), config.GddTLSCA.Load())
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// GetCertificate is never called as GetConfigForClient takes precedence, it is set for http.Server.ServeTLS
+		// of go versions which don't take GetConfigForClient as a certificate source
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.get()
+			return cert, nil
+		},
+		// A new config per handshake picks up reloaded certificates including the CA
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reload()
+			cert, pool := r.get()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}, nil
+}
+
+// ClientConfig returns tls config of clients, or nil if none of GDD_TLS_CERT, GDD_TLS_KEY and GDD_TLS_CA is set.
+// Server certificates are verified by GDD_TLS_CA if set, otherwise by system roots. The client certificate is
+// GDD_TLS_CERT, which is reloaded once changed, while GDD_TLS_CA is loaded only once
+func ClientConfig() (*tls.Config, error) {
+	certFile, keyFile, caFile := config.GddTLSCert.Load(), config.GddTLSKey.Load(), config.GddTLSCA.Load()
+	if stringutils.IsEmpty(certFile) && stringutils.IsEmpty(keyFile) && stringutils.IsEmpty(caFile) {
+		return nil, nil
+	}
+	if stringutils.IsEmpty(certFile) != stringutils.IsEmpty(keyFile) {
+		return nil, errors.Errorf("both %s and %s should be set", config.GddTLSCert, config.GddTLSKey)
+	}
+	r, err := newReloader(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+	_, pool := r.get()
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    pool,
+	}
+	if stringutils.IsNotEmpty(certFile) {
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.reload()
+			cert, _ := r.get()
+			return cert, nil
+		}
+	}
+	return conf, nil
+}
+
+// reloader keeps the certificate and the CA pool loaded from files, and reloads them if any of the files is modified
+type reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+	checked time.Time
+}
+
+func newReloader(certFile, keyFile, caFile string) (*reloader, error) {
+	r := &reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err = r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reloader) files() []string {
+	var files []string
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if stringutils.IsNotEmpty(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, errors.Wrap(err, "stat certificate file failed")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *reloader) load(modTime time.Time) error {
+	var cert *tls.Certificate
+	if stringutils.IsNotEmpty(r.certFile) {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return errors.Wrap(err, "load certificate failed")
+		}
+		cert = &pair
+	}
+	var pool *x509.CertPool
+	if stringutils.IsNotEmpty(r.caFile) {
+		pem, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return errors.Wrap(err, "read CA certificate failed")
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no CA certificate found in %s", r.caFile)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.pool = pool
+	r.modTime = modTime
+	return nil
+}
+
+// reload checks files at most once per CheckInterval, and keeps the loaded certificates if failed to reload,
+// e.g. when the certificate file has been written but the key file not yet
+func (r *reloader) reload() {
+	r.mu.Lock()
+	if time.Since(r.checked) < CheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checked = time.Now()
+	loaded := r.modTime
+	r.mu.Unlock()
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		logrus.Errorf("reload certificates failed: %v", err)
+		return
+	}
+	if !modTime.After(loaded) {
+		return
+	}
+	if err = r.load(modTime); err != nil {
+		logrus.Errorf("reload certificates failed: %v", err)
+		return
+	}
+	logrus.Infof("certificates reloaded from %v", r.files())
+}
+
+func (r *reloader) get() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
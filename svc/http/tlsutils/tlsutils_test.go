@@ -0,0 +1,266 @@
+package tlsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/unionj-cloud/go-doudou/svc/config"
+)
+
+func setEnv(t *testing.T, kv map[string]string) {
+	for k, v := range kv {
+		old, isSet := os.LookupEnv(k)
+		os.Setenv(k, v)
+		k := k
+		t.Cleanup(func() {
+			if isSet {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+type ca struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T) *ca {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &ca{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue writes a certificate for both server and client auth signed by c, and returns paths of the certificate and the key
+func (c *ca) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, file string, data []byte) {
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startServer serves https with ServerConfig, which reads env set by setEnv
+func startServer(t *testing.T) *httptest.Server {
+	tlsConfig, err := ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get requests url by client with ClientConfig, and returns common name of the server certificate and the response
+func get(t *testing.T, url string) (string, string, error) {
+	tlsConfig, err := ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, string(body), nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	c := newCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, c.pem)
+	certFile, keyFile := c.issue(t, dir, "server", 2)
+	setEnv(t, map[string]string{
+		config.GddTLSCert.String():       certFile,
+		config.GddTLSKey.String():        keyFile,
+		config.GddTLSCA.String():         caFile,
+		config.GddTLSClientAuth.String(): "require",
+	})
+	srv := startServer(t)
+
+	clientCert, clientKey := c.issue(t, dir, "client", 3)
+	setEnv(t, map[string]string{
+		config.GddTLSCert.String(): clientCert,
+		config.GddTLSKey.String():  clientKey,
+	})
+	server, client, err := get(t, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server != "server" || client != "client" {
+		t.Errorf("got server certificate %s and client certificate %s", server, client)
+	}
+
+	setEnv(t, map[string]string{
+		config.GddTLSCert.String(): "",
+		config.GddTLSKey.String():  "",
+	})
+	if _, _, err = get(t, srv.URL); err == nil {
+		t.Error("request without client certificate should fail")
+	}
+}
+
+func TestReload(t *testing.T) {
+	defer func(interval time.Duration) {
+		CheckInterval = interval
+	}(CheckInterval)
+	CheckInterval = 0
+	dir := t.TempDir()
+	c := newCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, c.pem)
+	certFile, keyFile := c.issue(t, dir, "server", 2)
+	setEnv(t, map[string]string{
+		config.GddTLSCert.String(): certFile,
+		config.GddTLSKey.String():  keyFile,
+		config.GddTLSCA.String():   caFile,
+	})
+	srv := startServer(t)
+	if server, _, err := get(t, srv.URL); err != nil || server != "server" {
+		t.Fatalf("got server certificate %s, error %v", server, err)
+	}
+
+	renewedCert, renewedKey := c.issue(t, dir, "renewed", 3)
+	for _, item := range [][2]string{{renewedCert, certFile}, {renewedKey, keyFile}} {
+		if err := os.Rename(item[0], item[1]); err != nil {
+			t.Fatal(err)
+		}
+		// make sure the modification time changes on file systems of coarse time resolution
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(item[1], future, future)
+	}
+	if server, _, err := get(t, srv.URL); err != nil || server != "renewed" {
+		t.Errorf("got server certificate %s, error %v, want renewed certificate", server, err)
+	}
+
+	// broken files don't replace the loaded certificate
+	writeFile(t, keyFile, []byte("broken"))
+	future := time.Now().Add(2 * time.Minute)
+	os.Chtimes(keyFile, future, future)
+	setEnv(t, map[string]string{
+		config.GddTLSCert.String(): "",
+		config.GddTLSKey.String():  "",
+	})
+	if server, _, err := get(t, srv.URL); err != nil || server != "renewed" {
+		t.Errorf("got server certificate %s, error %v, want renewed certificate", server, err)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	c := newCA(t)
+	certFile, keyFile := c.issue(t, dir, "server", 2)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantNil bool
+		wantErr bool
+	}{
+		{"disabled", map[string]string{config.GddTLSCert.String(): certFile}, true, false},
+		{"enabled", map[string]string{config.GddTLSCert.String(): certFile, config.GddTLSKey.String(): keyFile}, false, false},
+		{"client auth without ca", map[string]string{config.GddTLSCert.String(): certFile, config.GddTLSKey.String(): keyFile,
+			config.GddTLSClientAuth.String(): "require"}, true, true},
+		{"invalid client auth", map[string]string{config.GddTLSCert.String(): certFile, config.GddTLSKey.String(): keyFile,
+			config.GddTLSClientAuth.String(): "always"}, true, true},
+		{"missing file", map[string]string{config.GddTLSCert.String(): filepath.Join(dir, "none.crt"),
+			config.GddTLSKey.String(): keyFile}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, map[string]string{
+				config.GddTLSCert.String():       "",
+				config.GddTLSKey.String():        "",
+				config.GddTLSCA.String():         "",
+				config.GddTLSClientAuth.String(): "",
+			})
+			setEnv(t, tt.env)
+			got, err := ServerConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ServerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("ServerConfig() = %v, wantNil %v", got, tt.wantNil)
+			}
+			if got != nil && got.MinVersion != tls.VersionTLS12 {
+				t.Error("ServerConfig() should accept tls 1.2 at least")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package ddhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+	"github.com/unionj-cloud/go-doudou/svc/tracing"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) named(name string) tracing.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return tracing.SpanData{}
+}
+
+func TestTracing(t *testing.T) {
+	rec := &spanRecorder{}
+	tracing.SetExporter(rec)
+	defer tracing.SetExporter(nil)
+
+	var downstream string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get(tracing.TraceparentHeader)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	srv := NewChiHttpSrv()
+	srv.AddMiddleware(Tracing)
+	srv.AddRoute(model.Route{
+		Name:    "GetUser",
+		Method:  "GET",
+		Pattern: "/users/{id}",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			var caller Caller
+			_, err := caller.Call(&roundRobin{servers: []string{ts.URL}}, resty.New().R().SetContext(r.Context()), "GetProfile", "GET", "/profile")
+			if err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	})
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.(http.Handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	server, client := rec.named("GetUser"), rec.named("GetProfile")
+	if server.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID != "00f067aa0ba902b7" || server.Kind != tracing.SpanKindServer {
+		t.Errorf("server span %+v should continue the upstream trace", server)
+	}
+	if server.Attributes["http.status_code"] != http.StatusBadGateway || server.StatusCode != tracing.StatusError {
+		t.Errorf("unexpected status of server span %+v", server)
+	}
+	if client.TraceID != server.TraceID || client.ParentSpanID != server.SpanID || client.Kind != tracing.SpanKindClient {
+		t.Errorf("client span %+v should be a child of server span %+v", client, server)
+	}
+	if client.Attributes["http.url"] != ts.URL+"/profile" || client.StatusCode != tracing.StatusError {
+		t.Errorf("unexpected attributes of client span %+v", client)
+	}
+	if want := "00-" + client.TraceID + "-" + client.SpanID + "-01"; downstream != want {
+		t.Errorf("got traceparent %s, want %s", downstream, want)
+	}
+}
+
+func TestTracing_Root(t *testing.T) {
+	rec := &spanRecorder{}
+	tracing.SetExporter(rec)
+	defer tracing.SetExporter(nil)
+
+	var sc tracing.SpanContext
+	Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc = tracing.SpanContextFromContext(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+
+	span := rec.named("GET /users/1")
+	if !sc.IsValid() || span.TraceID != sc.TraceID.String() || span.ParentSpanID != "" {
+		t.Errorf("unexpected root span %+v", span)
+	}
+}
@@ -21,11 +21,13 @@ type HttpLog struct {
 	ReqContentLength  int64       `json:"reqContentLength,omitempty"`
 	ReqHeader         http.Header `json:"reqHeader,omitempty"`
 	RequestId         string      `json:"requestId,omitempty"`
-	RawReq            string      `json:"rawReq,omitempty"`
+	TraceId           string      `json:"traceId,omitempty"`
+	SpanId            string      `json:"spanId,omitempty"`
+	ReqBody           string      `json:"reqBody,omitempty"`
 	RespBody          string      `json:"respBody,omitempty"`
 	StatusCode        int         `json:"statusCode,omitempty"`
 	RespHeader        http.Header `json:"respHeader,omitempty"`
-	RespContentLength int         `json:"respContentLength,omitempty"`
+	RespContentLength int64       `json:"respContentLength,omitempty"`
 	ElapsedTime       string      `json:"elapsedTime,omitempty"`
 	// in ms
 	Elapsed int64 `json:"elapsed,omitempty"`
@@ -0,0 +1,220 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/validate"
+)
+
+const (
+	annotationHeader     = "@header"
+	annotationCookie     = "@cookie"
+	annotationValidate   = "@validate"
+	annotationPublic     = "@public"
+	annotationRole       = "@role"
+	annotationCache      = "@cache"
+	annotationIdempotent = "@idempotent"
+	annotationMaxBody    = "@maxbody"
+)
+
+// ParamBinding binds a method parameter to a http header or cookie
+type ParamBinding struct {
+	// Param is name of the method parameter
+	Param string
+	// Key is name of the http header or cookie
+	Key string
+}
+
+// annotationArgs returns parameters of each annotation with the name.
+// For example, annotation "@header tenantId X-Tenant-Id" will return [["tenantId", "X-Tenant-Id"]] for name "@header"
+func annotationArgs(annotations []astutils.Annotation, name string) [][]string {
+	var ret [][]string
+	for _, item := range annotations {
+		if item.Name != name {
+			continue
+		}
+		ret = append(ret, item.Params)
+	}
+	return ret
+}
+
+// docOf joins comments except annotations as description
+func docOf(comments []string) string {
+	var docs []string
+	for _, comment := range comments {
+		if strings.HasPrefix(strings.TrimSpace(comment), "@") {
+			continue
+		}
+		docs = append(docs, comment)
+	}
+	return strings.Join(docs, "\n")
+}
+
+// PathVars returns names of parameters declared as path variables by @route or @path annotation in method comments.
+// For example, method GetUser(ctx context.Context, userId int) of service Usersvc with comment "@path userId"
+// will be routed to GET /usersvc/user/{userId}
+func PathVars(method astutils.MethodMeta) []string {
+	var ret []string
+	for _, item := range method.PathVars {
+		ret = append(ret, item.Name)
+	}
+	return ret
+}
+
+func isPathVar(method astutils.MethodMeta, param string) bool {
+	return sliceutils.StringContains(PathVars(method), param)
+}
+
+func bindingsOf(method astutils.MethodMeta, name string) []ParamBinding {
+	var ret []ParamBinding
+	for _, args := range annotationArgs(method.Annotations, name) {
+		if len(args) == 0 {
+			continue
+		}
+		key := args[0]
+		if len(args) > 1 {
+			key = args[1]
+		}
+		ret = append(ret, ParamBinding{
+			Param: args[0],
+			Key:   key,
+		})
+	}
+	return ret
+}
+
+// HeaderVars returns parameters bound to http headers by @header annotation in method comments.
+// For example, comment "@header tenantId X-Tenant-Id" binds parameter tenantId to X-Tenant-Id header.
+// Header name is the same as parameter name if omitted.
+func HeaderVars(method astutils.MethodMeta) []ParamBinding {
+	return bindingsOf(method, annotationHeader)
+}
+
+// CookieVars returns parameters bound to cookies by @cookie annotation in method comments.
+// For example, comment "@cookie session SESSIONID" binds parameter session to SESSIONID cookie.
+// Cookie name is the same as parameter name if omitted.
+func CookieVars(method astutils.MethodMeta) []ParamBinding {
+	return bindingsOf(method, annotationCookie)
+}
+
+// headerOf returns header name bound to the parameter, or empty string if not bound
+func headerOf(method astutils.MethodMeta, param string) string {
+	for _, item := range HeaderVars(method) {
+		if item.Param == param {
+			return item.Key
+		}
+	}
+	return ""
+}
+
+// cookieOf returns cookie name bound to the parameter, or empty string if not bound
+func cookieOf(method astutils.MethodMeta, param string) string {
+	for _, item := range CookieVars(method) {
+		if item.Param == param {
+			return item.Key
+		}
+	}
+	return ""
+}
+
+// IsPublic reports whether the method is declared public by @public annotation in method comments,
+// so that its route lets anonymous requests through ddhttp.Auth middleware
+func IsPublic(method astutils.MethodMeta) bool {
+	return len(annotationArgs(method.Annotations, annotationPublic)) > 0
+}
+
+// RolesOf returns roles declared by @role annotations in method comments, any of which is required to call the method.
+// For example, comments "@role admin" and "@role editor auditor" return ["admin", "editor", "auditor"]
+func RolesOf(method astutils.MethodMeta) []string {
+	var ret []string
+	for _, args := range annotationArgs(method.Annotations, annotationRole) {
+		ret = append(ret, args...)
+	}
+	return ret
+}
+
+// CacheControlOf returns Cache-Control directives declared by @cache annotation in method comments,
+// or empty string if not declared. For example, comment "@cache public max-age=60" returns "public, max-age=60"
+func CacheControlOf(method astutils.MethodMeta) string {
+	var directives []string
+	for _, args := range annotationArgs(method.Annotations, annotationCache) {
+		for _, arg := range args {
+			for _, item := range strings.Split(arg, ",") {
+				if item = strings.TrimSpace(item); stringutils.IsNotEmpty(item) {
+					directives = append(directives, item)
+				}
+			}
+		}
+	}
+	return strings.Join(directives, ", ")
+}
+
+// IsCached reports whether the method has @cache annotation
+func IsCached(method astutils.MethodMeta) bool {
+	return len(annotationArgs(method.Annotations, annotationCache)) > 0
+}
+
+// IsIdempotent reports whether the method is declared idempotent by @idempotent annotation in method comments,
+// so that ddhttp.Idempotency middleware deduplicates its requests with the same Idempotency-Key header
+func IsIdempotent(method astutils.MethodMeta) bool {
+	return len(annotationArgs(method.Annotations, annotationIdempotent)) > 0
+}
+
+// MaxBodyOf returns max size of request bodies in bytes declared by @maxbody annotation in method comments,
+// or 0 if not declared. For example, comment "@maxbody 100MB" returns 104857600
+func MaxBodyOf(method astutils.MethodMeta) (int64, error) {
+	args := annotationArgs(method.Annotations, annotationMaxBody)
+	if len(args) == 0 {
+		return 0, nil
+	}
+	if len(args) > 1 || len(args[0]) != 1 {
+		return 0, errors.Errorf("method %s should have one @maxbody annotation with a size, e.g. @maxbody 100MB", method.Name)
+	}
+	var size config.ByteSize
+	if err := size.Decode(args[0][0]); err != nil || size == 0 {
+		return 0, errors.Errorf("@maxbody annotation of method %s requires a positive size, e.g. @maxbody 100MB", method.Name)
+	}
+	return int64(size), nil
+}
+
+// ValidateVars returns validation rules of parameters declared by @validate annotation in method comments.
+// For example, comment "@validate size min=1,max=100" declares rules "min=1,max=100" of parameter size.
+// Param of ParamBinding is parameter name and Key is the rules
+func ValidateVars(method astutils.MethodMeta) []ParamBinding {
+	var ret []ParamBinding
+	for _, args := range annotationArgs(method.Annotations, annotationValidate) {
+		if len(args) == 0 {
+			continue
+		}
+		ret = append(ret, ParamBinding{
+			Param: args[0],
+			Key:   strings.Join(args[1:], " "),
+		})
+	}
+	return ret
+}
+
+// rulesOf returns validation rules of the parameter, or empty string if not declared
+func rulesOf(method astutils.MethodMeta, param string) string {
+	for _, item := range ValidateVars(method) {
+		if item.Param == param {
+			return item.Key
+		}
+	}
+	return ""
+}
+
+// withRules returns copy of the parameter with validation rules declared by @validate annotation as `validate` struct tag,
+// so that they can be reflected in schema by v3.SchemaOf
+func withRules(method astutils.MethodMeta, param astutils.FieldMeta) astutils.FieldMeta {
+	if rules := rulesOf(method, param.Name); stringutils.IsNotEmpty(rules) {
+		param.Tag = validate.TagName + ":" + strconv.Quote(rules)
+	}
+	return param
+}
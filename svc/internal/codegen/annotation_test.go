@@ -0,0 +1,195 @@
+package codegen
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+func Test_annotationArgs(t *testing.T) {
+	type args struct {
+		annotations []astutils.Annotation
+		name        string
+	}
+	tests := []struct {
+		name string
+		args args
+		want [][]string
+	}{
+		{
+			name: "1",
+			args: args{
+				annotations: astutils.NewAnnotations([]string{"comment1", "@header userId", "@header photo X-Photo"}),
+				name:        "@header",
+			},
+			want: [][]string{{"userId"}, {"photo", "X-Photo"}},
+		},
+		{
+			name: "2",
+			args: args{
+				annotations: astutils.NewAnnotations([]string{"comment1", "@headerx userId"}),
+				name:        "@header",
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := annotationArgs(tt.args.annotations, tt.args.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("annotationArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_docOf(t *testing.T) {
+	got := docOf([]string{"comment1", "@path userId", "comment2"})
+	want := "comment1\ncomment2"
+	if got != want {
+		t.Errorf("docOf() = %v, want %v", got, want)
+	}
+}
+
+func TestPathVars(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name: "GetUser",
+		PathVars: []astutils.FieldMeta{
+			{
+				Name: "userId",
+				Type: "int",
+			},
+			{
+				Name: "photo",
+				Type: "string",
+			},
+		},
+	}
+	want := []string{"userId", "photo"}
+	if got := PathVars(method); !reflect.DeepEqual(got, want) {
+		t.Errorf("PathVars() = %v, want %v", got, want)
+	}
+	if !isPathVar(method, "photo") {
+		t.Error("isPathVar() = false, want true")
+	}
+}
+
+func TestHeaderVars(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "GetUser",
+		Annotations: astutils.NewAnnotations([]string{"@header tenantId X-Tenant-Id", "@header token"}),
+	}
+	want := []ParamBinding{
+		{
+			Param: "tenantId",
+			Key:   "X-Tenant-Id",
+		},
+		{
+			Param: "token",
+			Key:   "token",
+		},
+	}
+	if got := HeaderVars(method); !reflect.DeepEqual(got, want) {
+		t.Errorf("HeaderVars() = %v, want %v", got, want)
+	}
+	if got := headerOf(method, "tenantId"); got != "X-Tenant-Id" {
+		t.Errorf("headerOf() = %v, want %v", got, "X-Tenant-Id")
+	}
+	if got := headerOf(method, "userId"); got != "" {
+		t.Errorf("headerOf() = %v, want empty string", got)
+	}
+}
+
+func TestCookieVars(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "GetUser",
+		Annotations: astutils.NewAnnotations([]string{"@cookie session SESSIONID"}),
+	}
+	if got := cookieOf(method, "session"); got != "SESSIONID" {
+		t.Errorf("cookieOf() = %v, want %v", got, "SESSIONID")
+	}
+}
+
+func TestValidateVars(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "GetUser",
+		Annotations: astutils.NewAnnotations([]string{"@validate size min=1,max=100", "@validate name pattern=^a b$"}),
+	}
+	want := []ParamBinding{
+		{
+			Param: "size",
+			Key:   "min=1,max=100",
+		},
+		{
+			Param: "name",
+			Key:   "pattern=^a b$",
+		},
+	}
+	if got := ValidateVars(method); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateVars() = %v, want %v", got, want)
+	}
+	param := withRules(method, astutils.FieldMeta{Name: "size", Type: "int"})
+	if got := reflect.StructTag(param.Tag).Get("validate"); got != "min=1,max=100" {
+		t.Errorf("withRules() = %v, want %v", got, "min=1,max=100")
+	}
+	if got := rulesOf(method, "page"); got != "" {
+		t.Errorf("rulesOf() = %v, want empty string", got)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "GetUser",
+		Annotations: astutils.NewAnnotations([]string{"comment1", "@public"}),
+	}
+	if !IsPublic(method) {
+		t.Error("IsPublic() = false, want true")
+	}
+	if IsPublic(astutils.MethodMeta{Name: "GetUser"}) {
+		t.Error("IsPublic() = true, want false")
+	}
+}
+
+func TestRolesOf(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "DeleteUser",
+		Annotations: astutils.NewAnnotations([]string{"@role admin", "@role editor auditor"}),
+	}
+	want := []string{"admin", "editor", "auditor"}
+	if got := RolesOf(method); !reflect.DeepEqual(got, want) {
+		t.Errorf("RolesOf() = %v, want %v", got, want)
+	}
+}
+
+func TestCacheControlOf(t *testing.T) {
+	tests := []struct {
+		comments []string
+		want     string
+	}{
+		{[]string{"@cache public max-age=60"}, "public, max-age=60"},
+		{[]string{"@cache public, max-age=60,s-maxage=10"}, "public, max-age=60, s-maxage=10"},
+		{[]string{"comment1"}, ""},
+	}
+	for _, tt := range tests {
+		method := astutils.MethodMeta{
+			Name:        "GetUser",
+			Annotations: astutils.NewAnnotations(tt.comments),
+		}
+		if got := CacheControlOf(method); got != tt.want {
+			t.Errorf("CacheControlOf() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "CreateOrder",
+		Annotations: astutils.NewAnnotations([]string{"@idempotent"}),
+	}
+	if !IsIdempotent(method) {
+		t.Error("IsIdempotent() = false, want true")
+	}
+	if IsIdempotent(astutils.MethodMeta{Name: "CreateOrder"}) {
+		t.Error("IsIdempotent() = true, want false")
+	}
+}
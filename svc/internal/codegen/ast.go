@@ -16,6 +16,7 @@ import (
 // Support anonymous struct type
 // as struct field type in vo package
 // or as parameter type in method signature in svc.go file besides context.Context, multipart.FileHeader, os.File
+// Support receive-only channel and io.Reader as streaming result type in method signature in svc.go file
 // when go-doudou command line flag doc is true
 func ExprStringP(expr ast.Expr) string {
 	switch _expr := expr.(type) {
@@ -28,7 +29,8 @@ func ExprStringP(expr ast.Expr) string {
 		if !strings.HasPrefix(result, "vo.") &&
 			result != "context.Context" &&
 			result != "multipart.FileHeader" &&
-			result != "os.File" {
+			result != "os.File" &&
+			result != "io.Reader" {
 			panic(fmt.Errorf("not support %s in svc.go file and vo package", result))
 		}
 		return result
@@ -54,6 +56,9 @@ func ExprStringP(expr ast.Expr) string {
 	case *ast.FuncType:
 		panic("not support function as struct field type in vo package and as parameter in method signature in svc.go file")
 	case *ast.ChanType:
+		if _expr.Dir == ast.RECV {
+			return "<-chan " + ExprStringP(_expr.Value)
+		}
 		panic("not support channel as struct field type in vo package and as parameter in method signature in svc.go file")
 	default:
 		panic(fmt.Errorf("not support expression as struct field type in vo package and in method signature in svc.go file: %+v", expr))
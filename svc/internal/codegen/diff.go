@@ -0,0 +1,149 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is number of unchanged lines around changes in each hunk
+const diffContext = 3
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// unifiedDiff returns changes from original to modified in unified diff format, or empty string if no change
+func unifiedDiff(file string, original, modified []byte) string {
+	if string(original) == string(modified) {
+		return ""
+	}
+	lines := diffLines(splitLines(string(original)), splitLines(string(modified)))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", file, file))
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffEqual {
+			i++
+			continue
+		}
+		// find end of the hunk, changes separated by no more than 2*diffContext unchanged lines are in the same hunk
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != diffEqual {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+		var oldStart, newStart, oldLines, newLines int
+		for _, line := range lines[:start] {
+			if line.op != diffInsert {
+				oldStart++
+			}
+			if line.op != diffDelete {
+				newStart++
+			}
+		}
+		var body strings.Builder
+		for _, line := range lines[start:stop] {
+			prefix := " "
+			switch line.op {
+			case diffDelete:
+				prefix = "-"
+				oldLines++
+			case diffInsert:
+				prefix = "+"
+				newLines++
+			default:
+				oldLines++
+				newLines++
+			}
+			body.WriteString(prefix + line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart+1, oldLines, newStart+1, newLines))
+		sb.WriteString(body.String())
+		i = stop
+	}
+	return sb.String()
+}
+
+// diffLines returns line changes from a to b based on longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	var prefix, suffix []diffLine
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, diffLine{diffEqual, a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]diffLine{{diffEqual, a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	// lcs[i][j] is length of longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ret := prefix
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			ret = append(ret, diffLine{diffEqual, a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			ret = append(ret, diffLine{diffDelete, a[i]})
+			i++
+		} else {
+			ret = append(ret, diffLine{diffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ret = append(ret, diffLine{diffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ret = append(ret, diffLine{diffInsert, b[j]})
+	}
+	return append(ret, suffix...)
+}
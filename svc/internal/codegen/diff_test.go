@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		want     string
+	}{
+		{
+			name:     "no change",
+			original: "a\nb\n",
+			modified: "a\nb\n",
+			want:     "",
+		},
+		{
+			name:     "change",
+			original: "a\nb\nc\nd\ne\nf\ng\nh\n",
+			modified: "a\nb\nc\nd\nE\nf\ng\nh\ni\n",
+			want: `--- a/svcimpl.go
++++ b/svcimpl.go
+@@ -2,7 +2,8 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
++i
+`,
+		},
+		{
+			name:     "separate hunks",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			modified: "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			want: `--- a/svcimpl.go
++++ b/svcimpl.go
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -7,4 +8,3 @@
+ 7
+ 8
+ 9
+-10
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("svcimpl.go", []byte(tt.original), []byte(tt.modified)); got != tt.want {
+				t.Errorf("unifiedDiff() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -65,32 +65,69 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 	var ret v3.Operation
 	var params []v3.Parameter
 
-	ret.Summary = strings.Join(method.Comments, "\n")
+	ret.Summary = docOf(method.Comments)
+
+	// Parameters annotated by @path will be put into url as path variables,
+	// parameters annotated by @header and @cookie will be put into http headers and cookies
+	var others []astutils.FieldMeta
+	for _, item := range method.Params {
+		var param v3.Parameter
+		if isPathVar(method, item.Name) {
+			param = v3.Parameter{
+				Name:     item.Name,
+				In:       v3.InPath,
+				Required: true,
+			}
+		} else if header := headerOf(method, item.Name); stringutils.IsNotEmpty(header) {
+			param = v3.Parameter{
+				Name: header,
+				In:   v3.InHeader,
+			}
+		} else if cookie := cookieOf(method, item.Name); stringutils.IsNotEmpty(cookie) {
+			param = v3.Parameter{
+				Name: cookie,
+				In:   v3.InCookie,
+			}
+		} else {
+			others = append(others, item)
+			continue
+		}
+		if v3.IsRequired(withRules(method, item)) {
+			param.Required = true
+		}
+		pschema := v3.CopySchema(withRules(method, item))
+		param.Description = strings.Join(item.Comments, "\n")
+		param.Schema = &pschema
+		params = append(params, param)
+	}
 
 	// If http method is "POST" and each parameters' type is one of v3.Int, v3.Int64, v3.Bool, v3.String, v3.Float32, v3.Float64,
 	// then we use application/x-www-form-urlencoded as Content-type and we make one ref schema from them as request body.
 	// Note: unionj-generator project hasn't support application/x-www-form-urlencoded yet
 	var simpleCnt int
-	for _, item := range method.Params {
+	for _, item := range others {
 		if v3.IsBuiltin(item) || item.Type == "context.Context" {
 			simpleCnt++
 		}
 	}
-	if httpMethod == post && simpleCnt == len(method.Params) {
+	if httpMethod == post && simpleCnt == len(others) {
 		title := method.Name + "Req"
 		reqSchema := v3.Schema{
 			Type:       v3.ObjectT,
 			Title:      title,
 			Properties: make(map[string]*v3.Schema),
 		}
-		for _, item := range method.Params {
+		for _, item := range others {
 			if item.Type == "context.Context" {
 				continue
 			}
 			key := item.Name
-			pschema := v3.CopySchema(item)
+			pschema := v3.CopySchema(withRules(method, item))
 			pschema.Description = strings.Join(item.Comments, "\n")
 			reqSchema.Properties[strcase.ToLowerCamel(key)] = &pschema
+			if v3.IsRequired(withRules(method, item)) {
+				reqSchema.Required = append(reqSchema.Required, strcase.ToLowerCamel(key))
+			}
 		}
 		v3.Schemas[title] = reqSchema
 		mt := &v3.MediaType{
@@ -110,12 +147,12 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 		// Complex parameters such as structs in vo package, map and corresponding slice/array type
 		// will be put into request body as json content type.
 		// File and file array parameter will be put into request body as multipart/form-data content type.
-		for _, item := range method.Params {
+		for _, item := range others {
 			if item.Type == "context.Context" {
 				continue
 			}
 			pschemaType := v3.SchemaOf(item)
-			pschema := v3.CopySchema(item)
+			pschema := v3.CopySchema(withRules(method, item))
 			pschema.Description = strings.Join(item.Comments, "\n")
 			if reflect.DeepEqual(pschemaType, v3.FileArray) || pschemaType == v3.File {
 				var content v3.Content
@@ -129,9 +166,10 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 				}
 			} else if v3.IsBuiltin(item) {
 				params = append(params, v3.Parameter{
-					Name:   strcase.ToLowerCamel(item.Name),
-					In:     v3.InQuery,
-					Schema: &pschema,
+					Name:     strcase.ToLowerCamel(item.Name),
+					In:       v3.InQuery,
+					Schema:   &pschema,
+					Required: v3.IsRequired(withRules(method, item)),
 				})
 			} else {
 				var content v3.Content
@@ -152,7 +190,7 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 	var hasFile bool
 	var fileDoc string
 	for _, item := range method.Results {
-		if item.Type == "*os.File" {
+		if item.Type == "*os.File" || IsByteStream(item.Type) {
 			hasFile = true
 			fileDoc = strings.Join(item.Comments, "\n")
 			break
@@ -166,6 +204,16 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 				Description: fileDoc,
 			},
 		}
+	} else if stream := StreamOf(method); stream != nil {
+		// Each value received from the channel is sent as json encoded data of a server-sent event,
+		// so the schema describes data of a single event
+		eschema := v3.CopySchema(astutils.FieldMeta{
+			Type: eventOf(stream.Type),
+		})
+		eschema.Description = strings.Join(stream.Comments, "\n")
+		respContent.EventStream = &v3.MediaType{
+			Schema: &eschema,
+		}
 	} else {
 		title := method.Name + "Resp"
 		respSchema := v3.Schema{
@@ -193,28 +241,60 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 		Resp200: &v3.Response{
 			Content: &respContent,
 		},
+		Resp400: errorResponse("Bad request"),
+		Default: errorResponse("Error returned by service, http status code and error code are decided by service implementation"),
 	}
 	return ret
 }
 
+// errorResponse returns response with ErrorEnvelope json body written by ddhttp.HandleError
+func errorResponse(description string) *v3.Response {
+	return &v3.Response{
+		Description: description,
+		Content: &v3.Content{
+			Json: &v3.MediaType{
+				Schema: &v3.Schema{
+					Ref: "#/components/schemas/" + v3.ErrorEnvelope.Title,
+				},
+			},
+		},
+	}
+}
+
 func pathOf(method astutils.MethodMeta) v3.Path {
 	var ret v3.Path
-	hm := httpMethod(method.Name)
+	hm := HttpMethodOf(method)
 	op := operationOf(method, hm)
 	reflect.ValueOf(&ret).Elem().FieldByName(strings.Title(strings.ToLower(hm))).Set(reflect.ValueOf(&op))
 	return ret
 }
 
+// mergePath merges operations of the same path with different http methods
+func mergePath(dst, src v3.Path) v3.Path {
+	dv := reflect.ValueOf(&dst).Elem()
+	sv := reflect.ValueOf(src)
+	for i := 0; i < sv.NumField(); i++ {
+		if sv.Field(i).Kind() == reflect.Ptr && !sv.Field(i).IsNil() {
+			dv.Field(i).Set(sv.Field(i))
+		}
+	}
+	return dst
+}
+
 func pathsOf(ic astutils.InterfaceCollector) map[string]v3.Path {
 	if len(ic.Interfaces) == 0 {
 		return nil
 	}
 	pathmap := make(map[string]v3.Path)
-	inter := ic.Interfaces[0]
-	for _, method := range inter.Methods {
-		v3path := pathOf(method)
-		endpoint := fmt.Sprintf("/%s/%s", strings.ToLower(inter.Name), pattern(method.Name))
-		pathmap[endpoint] = v3path
+	for _, inter := range ic.Interfaces {
+		for _, method := range inter.Methods {
+			ep := astutils.TrimPathVarPattern(Endpoint(inter.Name, method))
+			v3path := pathOf(method)
+			if existing, exists := pathmap[ep]; exists {
+				v3path = mergePath(existing, v3path)
+			}
+			pathmap[ep] = v3path
+		}
 	}
 	return pathmap
 }
@@ -277,6 +357,7 @@ func GenDoc(dir string, ic astutils.InterfaceCollector) {
 	for _, item := range vos {
 		v3.Schemas[item.Title] = item
 	}
+	v3.Schemas[v3.ErrorEnvelope.Title] = *v3.ErrorEnvelope
 	paths = pathsOf(ic)
 	api = v3.Api{
 		Openapi: "3.0.2",
@@ -0,0 +1,269 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+var protoTmpl = `syntax = "proto3";
+
+package {{.Package}};
+
+option go_package = "{{.GoPackage}}";
+{{- range $s := .Services }}
+
+{{ comments $s.Meta.Comments "" }}service {{$s.Meta.Name}} {
+{{- range $m := $s.Methods }}
+{{ comments $m.Meta.Comments "  " }}  rpc {{$m.Meta.Name}}({{$m.Request.Name}}) returns ({{$m.Response.Name}});
+{{- end }}
+}
+{{- range $m := $s.Methods }}
+
+{{ template "message" $m.Request }}
+
+{{ template "message" $m.Response }}
+{{- end }}
+{{- end }}
+{{- range $m := .Messages }}
+
+{{ comments $m.Comments "" }}{{ template "message" $m }}
+{{- end }}
+`
+
+var protoMessageTmpl = `{{- define "message" }}message {{.Name}} {
+{{- range $f := .Fields }}
+{{ comments $f.Comments "  " }}  {{$f.Decl}}
+{{- end }}
+}
+{{- end }}`
+
+var grpcConvertTmpl = `package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative {{.ProtoFile}}
+
+import (
+	"encoding/json"
+	"{{.VoPackage}}"
+)
+
+{{- range $m := .Messages }}
+
+// {{$m.Name}}ToPb converts vo.{{$m.Name}} to {{$m.Name}}
+func {{$m.Name}}ToPb(_v vo.{{$m.Name}}) (_ret *{{$m.Name}}, _err error) {
+	_ret = &{{$m.Name}}{}
+	{{- range $f := $m.Fields }}
+	{{ toPb (printf "_ret.%s" $f.PbName) (printf "_v.%s" $f.GoName) $f.Type }}
+	{{- end }}
+	return
+}
+
+// {{$m.Name}}FromPb converts {{$m.Name}} to vo.{{$m.Name}}
+func {{$m.Name}}FromPb(_p *{{$m.Name}}) (_ret vo.{{$m.Name}}, _err error) {
+	if _p == nil {
+		return
+	}
+	{{- range $f := $m.Fields }}
+	{{ fromPb (printf "_ret.%s" $f.GoName) (printf "_p.%s" $f.PbName) $f.Type }}
+	{{- end }}
+	return
+}
+{{- end }}
+
+{{- range $s := .Services }}
+{{- range $m := $s.Methods }}
+{{- template "convert" (args $s $m.Meta $m.Request (rpcParams $m.Meta) "parameters") }}
+{{- template "convert" (args $s $m.Meta $m.Response (rpcResults $m.Meta) "results") }}
+{{- end }}
+{{- end }}
+
+{{- define "convert" }}
+
+// New{{.Message.Name}} creates {{.Message.Name}} from {{.Kind}} of {{.Service.Meta.Name}}.{{.Method.Name}}
+func New{{.Message.Name}}({{- range $i, $f := .Fields }}{{- if $i}}, {{end}}{{$f.Name}} {{$f.Type}}{{- end }}) (_ret *{{.Message.Name}}, _err error) {
+	_ret = &{{.Message.Name}}{}
+	{{- range $f := .Message.Fields }}
+	{{ toPb (printf "_ret.%s" $f.PbName) $f.GoName $f.Type }}
+	{{- end }}
+	return
+}
+
+// {{.Message.Name}}{{.Kind | toCamel}} returns {{.Kind}} of {{.Service.Meta.Name}}.{{.Method.Name}} from {{.Message.Name}}
+func {{.Message.Name}}{{.Kind | toCamel}}(_p *{{.Message.Name}}) ({{- range $f := .Fields }}{{$f.Name}} {{$f.Type}}, {{ end }}_err error) {
+	if _p == nil {
+		return
+	}
+	{{- range $f := .Message.Fields }}
+	{{ fromPb $f.GoName (printf "_p.%s" $f.PbName) $f.Type }}
+	{{- end }}
+	return
+}
+{{- end }}
+`
+
+// toPbCode returns go statements converting src of go type to dst of protobuf type.
+// Depth is used for naming loop variables of nested repeated and map fields
+func toPbCode(dst, src string, t protoType, depth int) string {
+	onErr := "; _err != nil {\nreturn\n}"
+	switch t.kind {
+	case kindScalar:
+		if t.ptr {
+			return fmt.Sprintf("if %s != nil {\n%s = new(%s)\n*%s = %s(*%s)\n}", src, dst, t.pbType, dst, t.pbType, src)
+		}
+		return fmt.Sprintf("%s = %s(%s)", dst, t.pbType, src)
+	case kindMessage:
+		if t.ptr {
+			return fmt.Sprintf("if %s != nil {\nif %s, _err = %sToPb(*%s)%s\n}", src, dst, t.name, src, onErr)
+		}
+		return fmt.Sprintf("if %s, _err = %sToPb(%s)%s", dst, t.name, src, onErr)
+	case kindRepeated, kindMap:
+		key, val := fmt.Sprintf("_i%d", depth), fmt.Sprintf("_v%d", depth)
+		if t.kind == kindMap {
+			key = fmt.Sprintf("_k%d", depth)
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s, %s := range %s {\n%s\n}\n}",
+			src, dst, t.pbGoType(), src, key, val, src, toPbCode(dst+"["+key+"]", val, *t.elem, depth+1))
+	default:
+		return fmt.Sprintf("if %s, _err = json.Marshal(%s)%s", dst, src, onErr)
+	}
+}
+
+// fromPbCode returns go statements converting src of protobuf type to dst of go type
+func fromPbCode(dst, src string, t protoType, depth int) string {
+	onErr := "; _err != nil {\nreturn\n}"
+	switch t.kind {
+	case kindScalar:
+		if t.ptr {
+			return fmt.Sprintf("if %s != nil {\n%s = new(%s)\n*%s = %s(*%s)\n}", src, dst, t.goType, dst, t.goType, src)
+		}
+		return fmt.Sprintf("%s = %s(%s)", dst, t.goType, src)
+	case kindMessage:
+		if t.ptr {
+			return fmt.Sprintf("if %s != nil {\n%s = new(%s)\nif *%s, _err = %sFromPb(%s)%s\n}", src, dst, t.goType, dst, t.name, src, onErr)
+		}
+		return fmt.Sprintf("if %s, _err = %sFromPb(%s)%s", dst, t.name, src, onErr)
+	case kindRepeated, kindMap:
+		key, val := fmt.Sprintf("_i%d", depth), fmt.Sprintf("_v%d", depth)
+		if t.kind == kindMap {
+			key = fmt.Sprintf("_k%d", depth)
+		}
+		return fmt.Sprintf("if %s != nil {\n%s = make(%s, len(%s))\nfor %s, %s := range %s {\n%s\n}\n}",
+			src, dst, t.goType, src, key, val, src, fromPbCode(dst+"["+key+"]", val, *t.elem, depth+1))
+	default:
+		return fmt.Sprintf("if len(%s) > 0 {\nif _err = json.Unmarshal(%s, &%s)%s\n}", src, src, dst, onErr)
+	}
+}
+
+// protoComments returns comments in protobuf file with indent, each line ends with line break. Annotations are excluded
+func protoComments(comments []string, indent string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(docOf(comments), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sb.WriteString(indent + "// " + line + "\n")
+	}
+	return sb.String()
+}
+
+// convertArgs are arguments of "convert" template in grpcConvertTmpl
+type convertArgs struct {
+	Service protoService
+	Method  astutils.MethodMeta
+	Message protoMessage
+	Fields  []astutils.FieldMeta
+	// Kind is either parameters or results
+	Kind string
+}
+
+// protoFileOf returns name of .proto file which is lower-cased name of the main service interface
+func protoFileOf(ic astutils.InterfaceCollector) string {
+	return strings.ToLower(ic.Interfaces[0].Name) + ".proto"
+}
+
+// GenGrpcProto generates .proto file from all interfaces in svc.go and exported structs in vo package
+// into transport/grpcsrv/pb directory. It is always overwritten.
+// Fields are numbered in the order of declaration, so append new fields to the end of structs
+// and parameter lists to keep compatibility with deployed clients
+func GenGrpcProto(dir string, ic astutils.InterfaceCollector) {
+	pbDir := filepath.Join(dir, "transport", "grpcsrv", "pb")
+	if err := os.MkdirAll(pbDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+	protofile := filepath.Join(pbDir, protoFileOf(ic))
+	if _, err := os.Stat(protofile); err == nil {
+		logrus.Warningf("file %s will be overwrited\n", protoFileOf(ic))
+	}
+	r := newProtoResolver(dir)
+	funcMap := make(map[string]interface{})
+	funcMap["comments"] = protoComments
+	tpl := template.Must(template.New("proto.tmpl").Funcs(funcMap).Parse(protoTmpl))
+	template.Must(tpl.Parse(protoMessageTmpl))
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, struct {
+		Package   string
+		GoPackage string
+		Services  []protoService
+		Messages  []protoMessage
+	}{
+		Package:   strings.ToLower(ic.Interfaces[0].Name),
+		GoPackage: modNameOf(dir) + "/transport/grpcsrv/pb",
+		Services:  r.services(ic),
+		Messages:  r.messages(),
+	}); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(protofile, buf.Bytes(), os.ModePerm); err != nil {
+		panic(err)
+	}
+}
+
+// GenGrpcConvert generates functions converting between vo structs, method parameters and results
+// and protobuf messages into transport/grpcsrv/pb/convert.go. It is always overwritten
+func GenGrpcConvert(dir string, ic astutils.InterfaceCollector) {
+	pbDir := filepath.Join(dir, "transport", "grpcsrv", "pb")
+	if err := os.MkdirAll(pbDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+	convertfile := filepath.Join(pbDir, "convert.go")
+	if _, err := os.Stat(convertfile); err == nil {
+		logrus.Warningf("file %s will be overwrited\n", "convert.go")
+	}
+	r := newProtoResolver(dir)
+	funcMap := make(map[string]interface{})
+	funcMap["toPb"] = func(dst, src string, t protoType) string {
+		return toPbCode(dst, src, t, 0)
+	}
+	funcMap["fromPb"] = func(dst, src string, t protoType) string {
+		return fromPbCode(dst, src, t, 0)
+	}
+	funcMap["rpcParams"] = rpcParams
+	funcMap["rpcResults"] = rpcResults
+	funcMap["toCamel"] = strings.Title
+	funcMap["args"] = func(service protoService, method astutils.MethodMeta, message protoMessage, fields []astutils.FieldMeta, kind string) convertArgs {
+		return convertArgs{service, method, message, fields, kind}
+	}
+	tpl := template.Must(template.New("convert.go.tmpl").Funcs(funcMap).Parse(grpcConvertTmpl))
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, struct {
+		ProtoFile string
+		VoPackage string
+		Services  []protoService
+		Messages  []protoMessage
+	}{
+		ProtoFile: protoFileOf(ic),
+		VoPackage: modNameOf(dir) + "/vo",
+		Services:  r.services(ic),
+		Messages:  r.messages(),
+	}); err != nil {
+		panic(err)
+	}
+	astutils.FixImport([]byte(strings.TrimSpace(buf.String())), convertfile)
+}
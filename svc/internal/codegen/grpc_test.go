@@ -0,0 +1,170 @@
+package codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+func grpcTestDir(t *testing.T) (string, astutils.InterfaceCollector) {
+	dir := filepath.Join(testDir, "grpc")
+	t.Cleanup(func() {
+		os.RemoveAll(filepath.Join(dir, "transport"))
+		os.RemoveAll(filepath.Join(dir, "client"))
+	})
+	return dir, astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), ExprStringP)
+}
+
+func TestGenGrpcProto(t *testing.T) {
+	dir, ic := grpcTestDir(t)
+	GenGrpcProto(dir, ic)
+	f, err := ioutil.ReadFile(filepath.Join(dir, "transport", "grpcsrv", "pb", "ordersvc.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `syntax = "proto3";
+
+package ordersvc;
+
+option go_package = "testgrpc/transport/grpcsrv/pb";
+
+// Ordersvc is order service
+service Ordersvc {
+  // CreateOrder creates order
+  rpc CreateOrder(CreateOrderRequest) returns (CreateOrderResponse);
+  rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);
+}
+
+message CreateOrderRequest {
+  Order order = 1;
+}
+
+message CreateOrderResponse {
+  int64 id = 1;
+}
+
+message ListOrdersRequest {
+  optional int64 status = 1;
+  repeated string tags = 2;
+}
+
+message ListOrdersResponse {
+  repeated Order orders = 1;
+  int64 total = 2;
+}
+
+// Ordersvcadmin is admin api of order service
+service Ordersvcadmin {
+  rpc Stat(StatRequest) returns (StatResponse);
+}
+
+message StatRequest {
+}
+
+message StatResponse {
+  map<string, Item> stat = 1;
+}
+
+message Base {
+  string created_by = 1;
+}
+
+message Item {
+  string name = 1;
+  double price = 2;
+}
+
+// Order is order
+message Order {
+  // Id is order id
+  int64 id = 1;
+  int64 status = 2;
+  optional string remark = 3;
+  repeated Item items = 4;
+  map<string, Item> extra = 5;
+  bytes matrix = 6; // json encoded
+  bytes data = 7; // json encoded
+  string created_by = 8;
+}
+`
+	if string(f) != expect {
+		t.Errorf("want %s, got %s\n", expect, string(f))
+	}
+}
+
+func TestGenGrpcGoFiles(t *testing.T) {
+	dir, ic := grpcTestDir(t)
+	GenGrpcConvert(dir, ic)
+	GenGrpcServer(dir, ic)
+	GenGrpcClient(dir, ic)
+	files := []string{
+		filepath.Join(dir, "transport", "grpcsrv", "pb", "convert.go"),
+		filepath.Join(dir, "transport", "grpcsrv", "server.go"),
+		filepath.Join(dir, "transport", "grpcsrv", "ordersvcadminserver.go"),
+		filepath.Join(dir, "client", "grpcclient.go"),
+		filepath.Join(dir, "client", "ordersvcadmingrpcclient.go"),
+	}
+	for _, file := range files {
+		if _, err := parser.ParseFile(token.NewFileSet(), file, nil, 0); err != nil {
+			t.Errorf("%s: %s", file, err)
+		}
+	}
+}
+
+func TestProtoResolver_typeOf(t *testing.T) {
+	r := newProtoResolver(filepath.Join(testDir, "grpc"))
+	tests := []struct {
+		goType string
+		decl   string
+		pbGo   string
+	}{
+		{"int", "int64", "int64"},
+		{"*string", "optional string", "*string"},
+		{"[]byte", "bytes", "[]byte"},
+		{"vo.Status", "int64", "int64"},
+		{"*vo.Status", "optional int64", "*int64"},
+		{"vo.Order", "Order", "*Order"},
+		{"*vo.Order", "Order", "*Order"},
+		{"[]*vo.Item", "repeated Item", "[]*Item"},
+		{"map[string]vo.Item", "map<string, Item>", "map[string]*Item"},
+		{"[][]int", "bytes", "[]byte"},
+		{"map[string]*int", "bytes", "[]byte"},
+		{"interface{}", "bytes", "[]byte"},
+		{"map[int]string", "bytes", "[]byte"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goType, func(t *testing.T) {
+			got := r.typeOf(tt.goType)
+			if got.decl() != tt.decl {
+				t.Errorf("decl() = %s, want %s", got.decl(), tt.decl)
+			}
+			if got.pbGoType() != tt.pbGo {
+				t.Errorf("pbGoType() = %s, want %s", got.pbGoType(), tt.pbGo)
+			}
+		})
+	}
+}
+
+func TestProtoField_PbName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"created_by", "CreatedBy"},
+		{"id", "Id"},
+		{"page2_size", "Page2Size"},
+		{"string", "String_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (protoField{Name: tt.name}).PbName(); got != tt.want {
+				t.Errorf("PbName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package codegen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+var grpcClientTmpl = `package client
+
+import (
+	"context"
+	"{{.ModName}}/transport/grpcsrv/pb"
+	"{{.ModName}}/vo"
+	"net/http"
+	"github.com/pkg/errors"
+	ddgrpc "github.com/unionj-cloud/go-doudou/svc/grpc"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"google.golang.org/grpc"
+)
+
+// {{.Meta.Name}}GrpcClient implements {{.Meta.Name}} by calling grpc server, so it can replace {{.Meta.Name}}Client transparently
+type {{.Meta.Name}}GrpcClient struct {
+	client pb.{{.Meta.Name}}Client
+	opts   []grpc.CallOption
+}
+
+{{- range $m := .Meta.Methods }}
+{{- $pm := methodOf $m }}
+
+func (receiver *{{$.Meta.Name}}GrpcClient) {{$m.Name}}({{- range $i, $p := $m.Params}}
+	{{- if $i}}, {{end}}
+	{{- $p.Name}} {{$p.Type}}
+	{{- end }}) ({{- range $i, $r := $m.Results}}
+	{{- if $i}}, {{end}}
+	{{- $r.Name}} {{$r.Type}}
+	{{- end }}) {
+	{{- if not $pm }}
+	{{errorOf $m}} = ddhttp.NewHttpError(http.StatusNotImplemented, http.StatusNotImplemented, "method {{$m.Name}} is not supported by grpc")
+	return
+	{{- else }}
+	{{- $ctx := "context.Background()" }}
+	{{- range $p := $m.Params }}
+	{{- if eq $p.Type "context.Context" }}
+	{{- $ctx = $p.Name }}
+	{{- end }}
+	{{- end }}
+	_req, _err := pb.New{{$pm.Request.Name}}({{- range $i, $p := rpcParams $m }}{{- if $i}}, {{end}}{{$p.Name}}{{- end }})
+	if _err != nil {
+		{{errorOf $m}} = errors.Wrap(_err, "")
+		return
+	}
+	_resp, _err := receiver.client.{{$m.Name}}({{$ctx}}, _req, receiver.opts...)
+	if _err != nil {
+		{{errorOf $m}} = ddgrpc.FromStatus(_err)
+		return
+	}
+	{{ range $r := rpcResults $m }}{{$r.Name}}, {{ end }}{{errorOf $m}} = pb.{{$pm.Response.Name}}Results(_resp)
+	if {{errorOf $m}} != nil {
+		{{errorOf $m}} = errors.Wrap({{errorOf $m}}, "")
+	}
+	return
+	{{- end }}
+}
+{{- end }}
+
+// New{{.Meta.Name}}Grpc creates {{.Meta.Name}}GrpcClient with grpc connection and call options applied to each call,
+// e.g. conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+func New{{.Meta.Name}}Grpc(conn grpc.ClientConnInterface, opts ...grpc.CallOption) *{{.Meta.Name}}GrpcClient {
+	return &{{.Meta.Name}}GrpcClient{
+		client: pb.New{{.Meta.Name}}Client(conn),
+		opts:   opts,
+	}
+}
+`
+
+// GenGrpcClient generates go grpc client for each interface in svc.go into client directory.
+// The clients implement the same interfaces as the services, methods not supported by grpc return *ddhttp.HttpError with 501 status code.
+// The files are always overwritten
+func GenGrpcClient(dir string, ic astutils.InterfaceCollector) {
+	r := newProtoResolver(dir)
+	for i, service := range r.services(ic) {
+		genGrpcClient(dir, service, fileOf("grpcclient.go", i, service.Meta))
+	}
+}
+
+func genGrpcClient(dir string, service protoService, file string) {
+	clientDir := filepath.Join(dir, "client")
+	if err := os.MkdirAll(clientDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+	clientfile := filepath.Join(clientDir, file)
+	if _, err := os.Stat(clientfile); err == nil {
+		logrus.Warningf("file %s will be overwrited\n", file)
+	}
+	funcMap := make(map[string]interface{})
+	funcMap["rpcParams"] = rpcParams
+	funcMap["rpcResults"] = rpcResults
+	funcMap["errorOf"] = errorOf
+	funcMap["methodOf"] = func(method astutils.MethodMeta) *protoMethod {
+		for _, item := range service.Methods {
+			if item.Meta.Name == method.Name {
+				return &item
+			}
+		}
+		return nil
+	}
+	tpl := template.Must(template.New("grpcclient.go.tmpl").Funcs(funcMap).Parse(grpcClientTmpl))
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, struct {
+		ModName string
+		Meta    astutils.InterfaceMeta
+	}{
+		ModName: modNameOf(dir),
+		Meta:    service.Meta,
+	}); err != nil {
+		panic(err)
+	}
+	astutils.FixImport([]byte(strings.TrimSpace(buf.String())), clientfile)
+}
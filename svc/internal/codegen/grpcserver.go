@@ -0,0 +1,126 @@
+package codegen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/iancoleman/strcase"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+var grpcServerTmpl = `package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	service "{{.ServicePackage}}"
+	"{{.ServicePackage}}/transport/grpcsrv/pb"
+	ddgrpc "github.com/unionj-cloud/go-doudou/svc/grpc"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"github.com/unionj-cloud/go-doudou/validate"
+)
+
+// {{.Meta.Name}}GrpcServer implements pb.{{.Meta.Name}}Server by delegating to {{.Meta.Name}}
+type {{.Meta.Name}}GrpcServer struct {
+	pb.Unimplemented{{.Meta.Name}}Server
+	{{.Meta.Name | toLowerCamel}} service.{{.Meta.Name}}
+}
+
+{{- range $pm := .Methods }}
+{{- $m := $pm.Meta }}
+
+func (receiver *{{$.Meta.Name}}GrpcServer) {{$m.Name}}(_ctx context.Context, _req *pb.{{$pm.Request.Name}}) (*pb.{{$pm.Response.Name}}, error) {
+	{{- with rpcParams $m }}
+	{{ range $i, $p := . }}{{- if $i}}, {{end}}{{$p.Name}}{{- end }}, _err := pb.{{$pm.Request.Name}}Parameters(_req)
+	if _err != nil {
+		return nil, ddgrpc.ToStatus(ddhttp.NewHttpError(http.StatusBadRequest, http.StatusBadRequest, _err.Error()))
+	}
+	{{- end }}
+	{{- if needValidate $m }}
+	var _verrs validate.Errors
+	{{- range $p := $m.Params }}
+	{{- with rulesOf $m $p.Name }}
+	_verrs = append(_verrs, validate.Var("{{$p.Name}}", {{$p.Name}}, {{printf "%q" .}})...)
+	{{- end }}
+	{{- if isJsonBody $p }}
+	_verrs = append(_verrs, validate.Struct({{$p.Name}})...)
+	{{- end }}
+	{{- end }}
+	if len(_verrs) > 0 {
+		return nil, ddgrpc.ToStatus(ddhttp.NewHttpError(http.StatusBadRequest, http.StatusBadRequest, "validation failed").WithDetails(_verrs))
+	}
+	{{- end }}
+	{{ range $i, $r := $m.Results }}{{- if $i}}, {{end}}{{- $r.Name }}{{- end }} := receiver.{{$.Meta.Name | toLowerCamel}}.{{$m.Name}}(
+		{{- range $i, $p := $m.Params }}
+		{{- if $i}}, {{end}}
+		{{- if eq $p.Type "context.Context" }}_ctx{{else}}{{ $p.Name }}{{end}}
+		{{- end }})
+	if {{errorOf $m}} != nil {
+		return nil, ddgrpc.ToStatus({{errorOf $m}})
+	}
+	_resp, _err := pb.New{{$pm.Response.Name}}({{- range $i, $r := rpcResults $m }}{{- if $i}}, {{end}}{{$r.Name}}{{- end }})
+	if _err != nil {
+		return nil, ddgrpc.ToStatus(_err)
+	}
+	return _resp, nil
+}
+{{- end }}
+
+func New{{.Meta.Name}}GrpcServer({{.Meta.Name | toLowerCamel}} service.{{.Meta.Name}}) *{{.Meta.Name}}GrpcServer {
+	return &{{.Meta.Name}}GrpcServer{
+		{{.Meta.Name | toLowerCamel}}: {{.Meta.Name | toLowerCamel}},
+	}
+}
+`
+
+// errorOf returns name of the last result of the method which should be error
+func errorOf(method astutils.MethodMeta) string {
+	return method.Results[len(method.Results)-1].Name
+}
+
+// GenGrpcServer generates grpc server for each interface in svc.go into transport/grpcsrv directory,
+// which implements the server interface generated by protoc-gen-go-grpc by delegating to the service implementation.
+// The files are always overwritten
+func GenGrpcServer(dir string, ic astutils.InterfaceCollector) {
+	r := newProtoResolver(dir)
+	for i, service := range r.services(ic) {
+		genGrpcServer(dir, service, fileOf("server.go", i, service.Meta))
+	}
+}
+
+func genGrpcServer(dir string, service protoService, file string) {
+	grpcDir := filepath.Join(dir, "transport", "grpcsrv")
+	if err := os.MkdirAll(grpcDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+	serverfile := filepath.Join(grpcDir, file)
+	if _, err := os.Stat(serverfile); err == nil {
+		logrus.Warningf("file %s will be overwrited\n", file)
+	}
+	funcMap := make(map[string]interface{})
+	funcMap["toLowerCamel"] = strcase.ToLowerCamel
+	funcMap["rpcParams"] = rpcParams
+	funcMap["rpcResults"] = rpcResults
+	funcMap["errorOf"] = errorOf
+	funcMap["needValidate"] = needValidate
+	funcMap["rulesOf"] = rulesOf
+	funcMap["isJsonBody"] = isJsonBody
+	tpl := template.Must(template.New("grpcserver.go.tmpl").Funcs(funcMap).Parse(grpcServerTmpl))
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, struct {
+		ServicePackage string
+		Meta           astutils.InterfaceMeta
+		Methods        []protoMethod
+	}{
+		ServicePackage: modNameOf(dir),
+		Meta:           service.Meta,
+		Methods:        service.Methods,
+	}); err != nil {
+		panic(err)
+	}
+	astutils.FixImport([]byte(strings.TrimSpace(buf.String())), serverfile)
+}
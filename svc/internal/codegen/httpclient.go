@@ -8,6 +8,7 @@ import (
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/stringutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -34,6 +36,7 @@ import (
 )
 
 type {{.Meta.Name}}Client struct {
+	ddhttp.Caller
 	provider ddhttp.IServiceProvider
 	client   *resty.Client
 }
@@ -54,18 +57,6 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
                      {{- if $i}},{{end}}
                      {{- $r.Name}} {{$r.Type}}
                      {{- end }}) {
-		var (
-			_server string
-			_err error
-		)
-		if _server, _err = receiver.provider.SelectServer(); _err != nil {
-			{{- range $r := $m.Results }}
-				{{- if eq $r.Type "error" }}
-					{{ $r.Name }} = errors.Wrap(_err, "")
-				{{- end }}
-			{{- end }}
-			return
-		}
 		_urlValues := url.Values{}
 		_req := receiver.client.R()
 		{{- range $p := $m.Params }}
@@ -97,6 +88,15 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 		{{- end}}
 		{{- else if eq $p.Type "context.Context" }}
 		_req.SetContext({{$p.Name}})
+		{{- else if isPathVar $m $p.Name }}
+		_req.SetPathParam("{{$p.Name}}", fmt.Sprintf("%v", {{$p.Name}}))
+		{{- else if headerOf $m $p.Name }}
+		_req.SetHeader("{{headerOf $m $p.Name}}", fmt.Sprintf("%v", {{$p.Name}}))
+		{{- else if cookieOf $m $p.Name }}
+		_req.SetCookie(&http.Cookie{
+			Name:  "{{cookieOf $m $p.Name}}",
+			Value: fmt.Sprintf("%v", {{$p.Name}}),
+		})
 		{{- else if not (isBuiltin $p)}}
 		_req.SetBody({{$p.Name}})
 		{{- else if contains $p.Type "["}}
@@ -108,23 +108,23 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 		{{- end }}
 		{{- end }}
 
+		{{- $call := "Call" }}
 		{{- range $r := $m.Results }}
-			{{- if eq $r.Type "*os.File" }}
-				_req.SetDoNotParseResponse(true)
+			{{- if or (eq $r.Type "*os.File") (isStream $r.Type) }}
+				{{- $call = "CallStream" }}
 			{{- end }}
 		{{- end }}
 
-		{{- if eq ($m.Name | httpMethod) "GET" }}
-		_resp, _err := _req.SetQueryParamsFromValues(_urlValues).
-			Get(_server + "/{{$.Meta.Name | lower}}/{{$m.Name | pattern}}")
+		{{- if eq ($m | httpMethodOf) "GET" }}
+		_req.SetQueryParamsFromValues(_urlValues)
 		{{- else }}
 		if _req.Body != nil {
 			_req.SetQueryParamsFromValues(_urlValues)
 		} else {
 			_req.SetFormDataFromValues(_urlValues)
 		}
-		_resp, _err := _req.{{$m.Name | restyMethod}}(_server + "/{{$.Meta.Name | lower}}/{{$m.Name | pattern}}")
 		{{- end }}
+		_resp, _err := receiver.{{$call}}(receiver.provider, _req, "{{$m.Name}}", "{{$m | httpMethodOf}}", "{{endpoint $.Meta.Name $m | trimPattern}}")
 		if _err != nil {
 			{{- range $r := $m.Results }}
 				{{- if eq $r.Type "error" }}
@@ -136,12 +136,16 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 		if _resp.IsError() {
 			{{- range $r := $m.Results }}
 				{{- if eq $r.Type "error" }}
-					{{ $r.Name }} = errors.New(_resp.String())
+					{{ $r.Name }} = ddhttp.DecodeError(_resp)
 				{{- end }}
 			{{- end }}
 			return
 		}
 		{{- $done := false }}
+		{{- if eq ($m | httpMethodOf) "HEAD" }}
+			return
+			{{- $done = true }}
+		{{- end }}
 		{{- range $r := $m.Results }}
 			{{- if eq $r.Type "*os.File" }}
 				_disp := _resp.Header().Get("Content-Disposition")
@@ -182,6 +186,35 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 				{{ $r.Name }} = _outFile
 				return
 				{{- $done = true }}	
+			{{- else if isEventStream $r.Type }}
+				_body := _resp.RawBody()
+				_ch := make(chan {{ eventOf $r.Type }})
+				go func() {
+					defer close(_ch)
+					defer _body.Close()
+					if _err := ddhttp.ReadEvents(_body, func(_data []byte) bool {
+						var _event {{ eventOf $r.Type }}
+						if _err := json.Unmarshal(_data, &_event); _err != nil {
+							logrus.Errorf("decode event of {{$m.Name}} failed: %v", _err)
+							return false
+						}
+						select {
+						case _ch <- _event:
+							return true
+						case <-{{ctxOf $m}}.Done():
+							return false
+						}
+					}); _err != nil {
+						logrus.Errorf("read events of {{$m.Name}} failed: %v", _err)
+					}
+				}()
+				{{ $r.Name }} = _ch
+				return
+				{{- $done = true }}
+			{{- else if isByteStream $r.Type }}
+				{{ $r.Name }} = _resp.RawBody()
+				return
+				{{- $done = true }}
 			{{- end }}
 		{{- end }}
 		{{- if not $done }}
@@ -236,11 +269,20 @@ func New{{.Meta.Name}}(opts ...ddhttp.DdClientOption) *{{.Meta.Name}}Client {
 }
 `
 
-func restyMethod(method string) string {
-	return strings.Title(strings.ToLower(httpMethod(method)))
+// GenGoClient generates go http client for each interface in svc.go.
+// All interfaces are served by the same service, so clients of the other interfaces
+// share the service provider of the first one if env is empty
+func GenGoClient(dir string, ic astutils.InterfaceCollector, env string) {
+	for i, inter := range ic.Interfaces {
+		clientEnv := env
+		if i > 0 && stringutils.IsEmpty(clientEnv) {
+			clientEnv = strings.ToUpper(ic.Interfaces[0].Name)
+		}
+		genGoClient(dir, inter, fileOf("client.go", i, inter), clientEnv)
+	}
 }
 
-func GenGoClient(dir string, ic astutils.InterfaceCollector, env string) {
+func genGoClient(dir string, inter astutils.InterfaceMeta, file string, env string) {
 	var (
 		err        error
 		clientfile string
@@ -261,20 +303,20 @@ func GenGoClient(dir string, ic astutils.InterfaceCollector, env string) {
 		panic(err)
 	}
 
-	clientfile = filepath.Join(clientDir, "client.go")
+	clientfile = filepath.Join(clientDir, file)
 	fi, err = os.Stat(clientfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
-		logrus.Warningln("file client.go will be overwrited")
+		logrus.Warningf("file %s will be overwrited\n", file)
 	}
 	if f, err = os.Create(clientfile); err != nil {
 		panic(err)
 	}
 	defer f.Close()
 
-	err = copier.DeepCopy(ic.Interfaces[0], &meta)
+	err = copier.DeepCopy(inter, &meta)
 	if err != nil {
 		panic(err)
 	}
@@ -292,13 +334,20 @@ func GenGoClient(dir string, ic astutils.InterfaceCollector, env string) {
 	funcMap := make(map[string]interface{})
 	funcMap["toLowerCamel"] = strcase.ToLowerCamel
 	funcMap["toCamel"] = strcase.ToCamel
-	funcMap["httpMethod"] = httpMethod
-	funcMap["pattern"] = pattern
-	funcMap["lower"] = strings.ToLower
+	funcMap["httpMethodOf"] = HttpMethodOf
+	funcMap["endpoint"] = Endpoint
+	funcMap["trimPattern"] = astutils.TrimPathVarPattern
+	funcMap["isPathVar"] = isPathVar
+	funcMap["headerOf"] = headerOf
+	funcMap["cookieOf"] = cookieOf
 	funcMap["contains"] = strings.Contains
 	funcMap["isBuiltin"] = v3.IsBuiltin
-	funcMap["restyMethod"] = restyMethod
 	funcMap["toUpper"] = strings.ToUpper
+	funcMap["isStream"] = IsStream
+	funcMap["isEventStream"] = IsEventStream
+	funcMap["isByteStream"] = IsByteStream
+	funcMap["eventOf"] = eventOf
+	funcMap["ctxOf"] = CtxOf
 	if tpl, err = template.New("client.go.tmpl").Funcs(funcMap).Parse(tmpl); err != nil {
 		panic(err)
 	}
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,61 +11,136 @@ import (
 	"github.com/iancoleman/strcase"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
 )
 
 var httpHandlerTmpl = `package httpsrv
 
 import (
 	"github.com/unionj-cloud/go-doudou/svc/config"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
 	ddmodel "github.com/unionj-cloud/go-doudou/svc/http/model"
 	"net/http"
 	"os"
 )
 
-type {{.Name}}Handler interface {
-{{- range $m := .Methods }}
+type {{.Meta.Name}}Handler interface {
+{{- range $m := .Meta.Methods }}
 	{{$m.Name}}(w http.ResponseWriter, r *http.Request)
 {{- end }}
 }
 
-func Routes(handler {{.Name}}Handler) []ddmodel.Route {
+func {{.RoutesFunc}}(handler {{.Meta.Name}}Handler) []ddmodel.Route {
 	return []ddmodel.Route{
-		{{- range $m := .Methods }}
+		{{- range $m := .Meta.Methods }}
 		{
 			"{{$m.Name | routeName}}",
-			"{{$m.Name | httpMethod}}",
-			"/{{$.Name | lower}}/{{$m.Name | pattern}}",
+			"{{$m | httpMethodOf}}",
+			"{{endpoint $.Meta.Name $m}}",
 			handler.{{$m.Name}},
 		},
 		{{- end }}
 	}
 }
+
+// {{.AuthOptionsFunc}} returns options of ddhttp.NewAuth declared by @public and @role annotations in svc.go
+func {{.AuthOptionsFunc}}() []ddhttp.AuthOption {
+	return []ddhttp.AuthOption{
+		{{- range $m := .Meta.Methods }}
+		{{- if isPublic $m }}
+		ddhttp.WithPublicRoutes("{{$m.Name | routeName}}"),
+		{{- end }}
+		{{- with rolesOf $m }}
+		ddhttp.WithRouteRoles("{{$m.Name | routeName}}"{{- range . }}, "{{.}}"{{- end }}),
+		{{- end }}
+		{{- end }}
+	}
+}
+
+// {{.CacheOptionsFunc}} returns options of ddhttp.NewCache declared by @cache annotations in svc.go
+func {{.CacheOptionsFunc}}() []ddhttp.CacheOption {
+	return []ddhttp.CacheOption{
+		{{- range $m := .Meta.Methods }}
+		{{- with cacheControlOf $m }}
+		ddhttp.WithRouteCacheControl("{{$m.Name | routeName}}", "{{.}}"),
+		{{- end }}
+		{{- end }}
+	}
+}
+
+// {{.IdempotencyOptionsFunc}} returns options of ddhttp.NewIdempotency declared by @idempotent annotations in svc.go
+func {{.IdempotencyOptionsFunc}}() []ddhttp.IdempotencyOption {
+	return []ddhttp.IdempotencyOption{
+		{{- range $m := .Meta.Methods }}
+		{{- if isIdempotent $m }}
+		ddhttp.WithIdempotentRoutes("{{$m.Name | routeName}}"),
+		{{- end }}
+		{{- end }}
+	}
+}
+
+// {{.BodyLimitOptionsFunc}} returns options of ddhttp.NewBodyLimit declared by @maxbody annotations in svc.go
+func {{.BodyLimitOptionsFunc}}() []ddhttp.BodyLimitOption {
+	return []ddhttp.BodyLimitOption{
+		{{- range $m := .Meta.Methods }}
+		{{- with maxBodyOf $m }}
+		ddhttp.WithRouteMaxBody("{{$m.Name | routeName}}", {{.}}),
+		{{- end }}
+		{{- end }}
+	}
+}
 `
 
-func pattern(method string) string {
+func routeName(method string) string {
 	httpMethods := []string{"GET", "POST", "PUT", "DELETE"}
 	snake := strcase.ToSnake(method)
 	splits := strings.Split(snake, "_")
 	head := strings.ToUpper(splits[0])
 	for _, m := range httpMethods {
 		if head == m {
-			return strings.ToLower(method[len(m):])
+			return method[len(m):]
 		}
 	}
-	return strings.ToLower(method)
+	return method
 }
 
-func routeName(method string) string {
+// RouteNameOf returns name of the generated route of method
+func RouteNameOf(method astutils.MethodMeta) string {
+	return routeName(method.Name)
+}
+
+func pattern(method string) string {
 	httpMethods := []string{"GET", "POST", "PUT", "DELETE"}
 	snake := strcase.ToSnake(method)
 	splits := strings.Split(snake, "_")
 	head := strings.ToUpper(splits[0])
 	for _, m := range httpMethods {
 		if head == m {
-			return method[len(m):]
+			return strings.ToLower(method[len(m):])
 		}
 	}
-	return method
+	return strings.ToLower(method)
+}
+
+// Endpoint returns route pattern of the method. It is the path declared by @route annotation,
+// or made from service name and method name with path variables declared by @path annotation appended.
+func Endpoint(svcname string, method astutils.MethodMeta) string {
+	if stringutils.IsNotEmpty(method.Path) {
+		return method.Path
+	}
+	ep := fmt.Sprintf("/%s/%s", strings.ToLower(svcname), pattern(method.Name))
+	for _, item := range method.PathVars {
+		ep += "/{" + item.Name + "}"
+	}
+	return ep
+}
+
+// HttpMethodOf returns http method declared by @route annotation, or inferred from method name if not declared
+func HttpMethodOf(method astutils.MethodMeta) string {
+	if stringutils.IsNotEmpty(method.HttpMethod) {
+		return method.HttpMethod
+	}
+	return httpMethod(method.Name)
 }
 
 func httpMethod(method string) string {
@@ -80,7 +156,16 @@ func httpMethod(method string) string {
 	return "POST"
 }
 
+// GenHttpHandler generates handler interface and routes for each interface in svc.go
 func GenHttpHandler(dir string, ic astutils.InterfaceCollector) {
+	for i, meta := range ic.Interfaces {
+		genHttpHandler(dir, meta, fileOf("handler.go", i, meta), routesFuncOf(i, meta), authOptionsFuncOf(i, meta), cacheOptionsFuncOf(i, meta),
+			idempotencyOptionsFuncOf(i, meta), bodyLimitOptionsFuncOf(i, meta))
+	}
+}
+
+func genHttpHandler(dir string, meta astutils.InterfaceMeta, file string, routesFunc string, authOptionsFunc string, cacheOptionsFunc string,
+	idempotencyOptionsFunc string, bodyLimitOptionsFunc string) {
 	var (
 		err         error
 		handlerfile string
@@ -96,13 +181,13 @@ func GenHttpHandler(dir string, ic astutils.InterfaceCollector) {
 		panic(err)
 	}
 
-	handlerfile = filepath.Join(httpDir, "handler.go")
+	handlerfile = filepath.Join(httpDir, file)
 	fi, err = os.Stat(handlerfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
-		logrus.Warningln("file handler.go will be overwrited")
+		logrus.Warningf("file %s will be overwrited", file)
 	}
 	if f, err = os.Create(handlerfile); err != nil {
 		panic(err)
@@ -110,14 +195,32 @@ func GenHttpHandler(dir string, ic astutils.InterfaceCollector) {
 	defer f.Close()
 
 	funcMap := make(map[string]interface{})
-	funcMap["httpMethod"] = httpMethod
 	funcMap["routeName"] = routeName
-	funcMap["pattern"] = pattern
-	funcMap["lower"] = strings.ToLower
+	funcMap["httpMethodOf"] = HttpMethodOf
+	funcMap["endpoint"] = Endpoint
+	funcMap["isPublic"] = IsPublic
+	funcMap["rolesOf"] = RolesOf
+	funcMap["cacheControlOf"] = CacheControlOf
+	funcMap["isIdempotent"] = IsIdempotent
+	funcMap["maxBodyOf"] = MaxBodyOf
 	if tpl, err = template.New("handler.go.tmpl").Funcs(funcMap).Parse(httpHandlerTmpl); err != nil {
 		panic(err)
 	}
-	if err = tpl.Execute(&sqlBuf, ic.Interfaces[0]); err != nil {
+	if err = tpl.Execute(&sqlBuf, struct {
+		Meta                   astutils.InterfaceMeta
+		RoutesFunc             string
+		AuthOptionsFunc        string
+		CacheOptionsFunc       string
+		IdempotencyOptionsFunc string
+		BodyLimitOptionsFunc   string
+	}{
+		Meta:                   meta,
+		RoutesFunc:             routesFunc,
+		AuthOptionsFunc:        authOptionsFunc,
+		CacheOptionsFunc:       cacheOptionsFunc,
+		IdempotencyOptionsFunc: idempotencyOptionsFunc,
+		BodyLimitOptionsFunc:   bodyLimitOptionsFunc,
+	}); err != nil {
 		panic(err)
 	}
 	source = strings.TrimSpace(sqlBuf.String())
@@ -75,6 +75,63 @@ func Test_routeName(t *testing.T) {
 	}
 }
 
+func TestEndpoint(t *testing.T) {
+	type args struct {
+		svcname string
+		method  astutils.MethodMeta
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "1",
+			args: args{
+				svcname: "Usersvc",
+				method: astutils.MethodMeta{
+					Name: "GetBooks",
+				},
+			},
+			want: "/usersvc/books",
+		},
+		{
+			name: "2",
+			args: args{
+				svcname: "Usersvc",
+				method: astutils.MethodMeta{
+					Name: "GetUser",
+					PathVars: []astutils.FieldMeta{
+						{
+							Name: "userId",
+						},
+					},
+				},
+			},
+			want: "/usersvc/user/{userId}",
+		},
+		{
+			name: "3",
+			args: args{
+				svcname: "Ordersvc",
+				method: astutils.MethodMeta{
+					Name:       "UpdateOrder",
+					HttpMethod: "PATCH",
+					Path:       "/orders/{id}",
+				},
+			},
+			want: "/orders/{id}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Endpoint(tt.args.svcname, tt.args.method); got != tt.want {
+				t.Errorf("Endpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGenHttpHandler(t *testing.T) {
 	dir := testDir + "httphandler"
 	InitSvc(dir)
@@ -86,6 +143,7 @@ func TestGenHttpHandler(t *testing.T) {
 import (
 	"net/http"
 
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
 	ddmodel "github.com/unionj-cloud/go-doudou/svc/http/model"
 )
 
@@ -103,6 +161,26 @@ func Routes(handler TestfileshttphandlerHandler) []ddmodel.Route {
 		},
 	}
 }
+
+// AuthOptions returns options of ddhttp.NewAuth declared by @public and @role annotations in svc.go
+func AuthOptions() []ddhttp.AuthOption {
+	return []ddhttp.AuthOption{}
+}
+
+// CacheOptions returns options of ddhttp.NewCache declared by @cache annotations in svc.go
+func CacheOptions() []ddhttp.CacheOption {
+	return []ddhttp.CacheOption{}
+}
+
+// IdempotencyOptions returns options of ddhttp.NewIdempotency declared by @idempotent annotations in svc.go
+func IdempotencyOptions() []ddhttp.IdempotencyOption {
+	return []ddhttp.IdempotencyOption{}
+}
+
+// BodyLimitOptions returns options of ddhttp.NewBodyLimit declared by @maxbody annotations in svc.go
+func BodyLimitOptions() []ddhttp.BodyLimitOption {
+	return []ddhttp.BodyLimitOption{}
+}
 `
 	file := dir + "/transport/httpsrv/handler.go"
 	f, err := os.Open(file)
@@ -118,3 +196,61 @@ func Routes(handler TestfileshttphandlerHandler) []ddmodel.Route {
 	}
 	assert.Equal(t, expect, string(content))
 }
+
+func TestGenHttpHandler_Auth(t *testing.T) {
+	dir := testDir + "httphandlerauth"
+	defer os.RemoveAll(dir)
+	meta := astutils.InterfaceMeta{
+		Name: "Usersvc",
+		Methods: []astutils.MethodMeta{
+			{
+				Name:        "GetUser",
+				Annotations: astutils.NewAnnotations([]string{"@public", "@cache public max-age=60"}),
+			},
+			{
+				Name:        "DeleteOrder",
+				Annotations: astutils.NewAnnotations([]string{"@role admin", "@role auditor"}),
+			},
+			{
+				Name:        "PageUsers",
+				Annotations: astutils.NewAnnotations([]string{"@idempotent", "@maxbody 10MB"}),
+			},
+		},
+	}
+	genHttpHandler(dir, meta, "adminhandler.go", "AdminsvcRoutes", "AdminsvcAuthOptions", "AdminsvcCacheOptions",
+		"AdminsvcIdempotencyOptions", "AdminsvcBodyLimitOptions")
+	content, err := ioutil.ReadFile(dir + "/transport/httpsrv/adminhandler.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `// AdminsvcAuthOptions returns options of ddhttp.NewAuth declared by @public and @role annotations in svc.go
+func AdminsvcAuthOptions() []ddhttp.AuthOption {
+	return []ddhttp.AuthOption{
+		ddhttp.WithPublicRoutes("User"),
+		ddhttp.WithRouteRoles("Order", "admin", "auditor"),
+	}
+}
+
+// AdminsvcCacheOptions returns options of ddhttp.NewCache declared by @cache annotations in svc.go
+func AdminsvcCacheOptions() []ddhttp.CacheOption {
+	return []ddhttp.CacheOption{
+		ddhttp.WithRouteCacheControl("User", "public, max-age=60"),
+	}
+}
+
+// AdminsvcIdempotencyOptions returns options of ddhttp.NewIdempotency declared by @idempotent annotations in svc.go
+func AdminsvcIdempotencyOptions() []ddhttp.IdempotencyOption {
+	return []ddhttp.IdempotencyOption{
+		ddhttp.WithIdempotentRoutes("PageUsers"),
+	}
+}
+
+// AdminsvcBodyLimitOptions returns options of ddhttp.NewBodyLimit declared by @maxbody annotations in svc.go
+func AdminsvcBodyLimitOptions() []ddhttp.BodyLimitOption {
+	return []ddhttp.BodyLimitOption{
+		ddhttp.WithRouteMaxBody("PageUsers", 10485760),
+	}
+}
+`
+	assert.Contains(t, string(content), expect)
+}
@@ -6,11 +6,8 @@ import (
 	"github.com/iancoleman/strcase"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
-	"github.com/unionj-cloud/go-doudou/copier"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
-	"go/ast"
-	"go/parser"
-	"go/token"
+	"github.com/unionj-cloud/go-doudou/stringutils"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -42,7 +39,14 @@ func New{{.Meta.Name}}Handler({{.Meta.Name | toLowerCamel}} {{.ServiceAlias}}.{{
 }
 `
 
+// GenHttpHandlerImpl generates an empty implementation of handler interface for each interface in svc.go if not exists
 func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
+	for i, meta := range ic.Interfaces {
+		genHttpHandlerImpl(dir, ic, meta, fileOf("handlerimpl.go", i, meta))
+	}
+}
+
+func genHttpHandlerImpl(dir string, ic astutils.InterfaceCollector, meta astutils.InterfaceMeta, file string) {
 	var (
 		err             error
 		modfile         string
@@ -60,7 +64,7 @@ func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
 		panic(err)
 	}
 
-	handlerimplfile = filepath.Join(httpDir, "handlerimpl.go")
+	handlerimplfile = filepath.Join(httpDir, file)
 	if _, err = os.Stat(handlerimplfile); os.IsNotExist(err) {
 		modfile = filepath.Join(dir, "go.mod")
 		if f, err = os.Open(modfile); err != nil {
@@ -92,7 +96,7 @@ func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
 			ServicePackage: modName,
 			ServiceAlias:   ic.Package.Name,
 			VoPackage:      modName + "/vo",
-			Meta:           ic.Interfaces[0],
+			Meta:           meta,
 		}); err != nil {
 			panic(err)
 		}
@@ -117,8 +121,8 @@ var appendHttpHandlerImplTmpl = `
 		)
 		{{- range $p := $m.Params }}
 		{{- if contains $p.Type "*multipart.FileHeader" }}
-		if err := _req.ParseMultipartForm(32 << 20); err != nil {
-			http.Error(_writer, err.Error(), http.StatusBadRequest)
+		if err := _req.ParseMultipartForm(ddhttp.MaxMultipartMemory); err != nil {
+			ddhttp.HandleBadRequest(_writer, err)
 			return
 		}
 		{{$p.Name}}Files := _req.MultipartForm.File["{{$p.Name}}"]
@@ -131,20 +135,55 @@ var appendHttpHandlerImplTmpl = `
 		{{- end}}
 		{{- else if eq $p.Type "context.Context" }}
 		{{$p.Name}} = _req.Context()
+		{{- else if isPathVar $m $p.Name }}
+		{{- if $p.Type | isSupport }}
+		if casted, err := _cast.{{$p.Type | castFunc}}E(mux.Vars(_req)["{{$p.Name}}"]); err != nil {
+			ddhttp.HandleBadRequest(_writer, err)
+			return
+		} else {
+			{{$p.Name}} = casted
+		}
+		{{- else }}
+		{{$p.Name}} = mux.Vars(_req)["{{$p.Name}}"]
+		{{- end }}
+		{{- else if headerOf $m $p.Name }}
+		{{- if $p.Type | isSupport }}
+		if casted, err := _cast.{{$p.Type | castFunc}}E(_req.Header.Get("{{headerOf $m $p.Name}}")); err != nil {
+			ddhttp.HandleBadRequest(_writer, err)
+			return
+		} else {
+			{{$p.Name}} = casted
+		}
+		{{- else }}
+		{{$p.Name}} = _req.Header.Get("{{headerOf $m $p.Name}}")
+		{{- end }}
+		{{- else if cookieOf $m $p.Name }}
+		if _cookie, err := _req.Cookie("{{cookieOf $m $p.Name}}"); err == nil {
+			{{- if $p.Type | isSupport }}
+			if casted, err := _cast.{{$p.Type | castFunc}}E(_cookie.Value); err != nil {
+				ddhttp.HandleBadRequest(_writer, err)
+				return
+			} else {
+				{{$p.Name}} = casted
+			}
+			{{- else }}
+			{{$p.Name}} = _cookie.Value
+			{{- end }}
+		}
 		{{- else if not (isBuiltin $p)}}
 		if err := json.NewDecoder(_req.Body).Decode(&{{$p.Name}}); err != nil {
-			http.Error(_writer, err.Error(), http.StatusBadRequest)
+			ddhttp.HandleBadRequest(_writer, err)
 			return
 		}
 		defer _req.Body.Close()
 		{{- else if contains $p.Type "["}}
 		if err := _req.ParseForm(); err != nil {
-			http.Error(_writer, err.Error(), http.StatusBadRequest)
+			ddhttp.HandleBadRequest(_writer, err)
 			return
 		}
 		{{- if $p.Type | isSupport }}
 		if casted, err := _cast.{{$p.Type | castFunc}}E(_req.Form["{{$p.Name}}"]); err != nil {
-			http.Error(_writer, err.Error(), http.StatusBadRequest)
+			ddhttp.HandleBadRequest(_writer, err)
 			return
 		} else {
 			{{$p.Name}} = casted
@@ -155,7 +194,7 @@ var appendHttpHandlerImplTmpl = `
 		{{- else }}
 		{{- if $p.Type | isSupport }}
 		if casted, err := _cast.{{$p.Type | castFunc}}E(_req.FormValue("{{$p.Name}}")); err != nil {
-			http.Error(_writer, err.Error(), http.StatusBadRequest)
+			ddhttp.HandleBadRequest(_writer, err)
 			return
 		} else {
 			{{$p.Name}} = casted
@@ -165,6 +204,21 @@ var appendHttpHandlerImplTmpl = `
 		{{- end }}
 		{{- end }}
 		{{- end }}
+		{{- if needValidate $m }}
+		var _verrs validate.Errors
+		{{- range $p := $m.Params }}
+		{{- with rulesOf $m $p.Name }}
+		_verrs = append(_verrs, validate.Var("{{$p.Name}}", {{$p.Name}}, {{printf "%q" .}})...)
+		{{- end }}
+		{{- if isJsonBody $p }}
+		_verrs = append(_verrs, validate.Struct({{$p.Name}})...)
+		{{- end }}
+		{{- end }}
+		if len(_verrs) > 0 {
+			ddhttp.HandleError(_writer, ddhttp.NewHttpError(http.StatusBadRequest, http.StatusBadRequest, "validation failed").WithDetails(_verrs))
+			return
+		}
+		{{- end }}
 		{{ range $i, $r := $m.Results }}{{- if $i}},{{- end}}{{- $r.Name }}{{- end }} = receiver.{{$.Meta.Name | toLowerCamel}}.{{$m.Name}}(
 			{{- range $p := $m.Params }}
 			{{ $p.Name }},
@@ -173,11 +227,7 @@ var appendHttpHandlerImplTmpl = `
 		{{- range $r := $m.Results }}
 			{{- if eq $r.Type "error" }}
 				if {{ $r.Name }} != nil {
-					if {{ $r.Name }} == context.Canceled {
-						http.Error(_writer, {{ $r.Name }}.Error(), http.StatusBadRequest)
-					} else {
-						http.Error(_writer, {{ $r.Name }}.Error(), http.StatusInternalServerError)
-					}
+					ddhttp.HandleError(_writer, {{ $r.Name }})
 					return
 				}
 			{{- end }}
@@ -186,13 +236,13 @@ var appendHttpHandlerImplTmpl = `
 		{{- range $r := $m.Results }}
 			{{- if eq $r.Type "*os.File" }}
 				if {{$r.Name}} == nil {
-					http.Error(_writer, "No file returned", http.StatusInternalServerError)
+					ddhttp.HandleError(_writer, errors.New("No file returned"))
 					return
 				}
 				var _fi os.FileInfo
 				_fi, _err := {{$r.Name}}.Stat()
 				if _err != nil {
-					http.Error(_writer, _err.Error(), http.StatusInternalServerError)
+					ddhttp.HandleError(_writer, _err)
 					return
 				}
 				_writer.Header().Set("Content-Disposition", "attachment; filename="+_fi.Name())
@@ -200,6 +250,37 @@ var appendHttpHandlerImplTmpl = `
 				_writer.Header().Set("Content-Length", fmt.Sprintf("%d", _fi.Size()))
 				io.Copy(_writer, {{$r.Name}})
 				{{- $done = true }}	
+			{{- else if isEventStream $r.Type }}
+				if {{$r.Name}} == nil {
+					ddhttp.HandleError(_writer, errors.New("No channel returned"))
+					return
+				}
+				_writer = ddhttp.NewStreamWriter(_writer, _req)
+				ddhttp.StartEventStream(_writer)
+				for {
+					select {
+					case <-_req.Context().Done():
+						return
+					case _event, _ok := <-{{$r.Name}}:
+						if !_ok {
+							return
+						}
+						if _err := ddhttp.WriteEvent(_writer, _event); _err != nil {
+							logrus.Errorf("write event of {{$m.Name}} failed: %v", _err)
+							return
+						}
+					}
+				}
+				{{- $done = true }}
+			{{- else if isByteStream $r.Type }}
+				if {{$r.Name}} == nil {
+					ddhttp.HandleError(_writer, errors.New("No reader returned"))
+					return
+				}
+				if _err := ddhttp.WriteStream(ddhttp.NewStreamWriter(_writer, _req), {{$r.Name}}); _err != nil {
+					logrus.Errorf("write stream of {{$m.Name}} failed: %v", _err)
+				}
+				{{- $done = true }}
 			{{- end }}
 		{{- end }}
 		{{- if not $done }}
@@ -216,7 +297,7 @@ var appendHttpHandlerImplTmpl = `
 				{{- end }}
 				{{- end }}
 			}); err != nil {
-				http.Error(_writer, err.Error(), http.StatusInternalServerError)
+				ddhttp.HandleError(_writer, err)
 				return
 			}
 		{{- end }}
@@ -230,8 +311,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	_cast "github.com/unionj-cloud/go-doudou/cast"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"github.com/unionj-cloud/go-doudou/validate"
 	{{.ServiceAlias}} "{{.ServicePackage}}"
 	"net/http"
 	"{{.VoPackage}}"
@@ -269,6 +354,22 @@ var castFuncMap = map[string]string{
 	"[]int":         "ToIntSlice",
 }
 
+// isJsonBody returns true if the parameter is decoded from json request body
+func isJsonBody(param astutils.FieldMeta) bool {
+	return param.Type != "context.Context" && !strings.Contains(param.Type, "*multipart.FileHeader") && !v3.IsBuiltin(param)
+}
+
+// needValidate returns true if any parameter of the method declares validation rules by @validate annotation
+// or is decoded from json request body which may declare validation rules by `validate` struct tags
+func needValidate(method astutils.MethodMeta) bool {
+	for _, param := range method.Params {
+		if stringutils.IsNotEmpty(rulesOf(method, param.Name)) || isJsonBody(param) {
+			return true
+		}
+	}
+	return false
+}
+
 func isSupport(t string) bool {
 	_, exists := castFuncMap[t]
 	return exists
@@ -281,83 +382,63 @@ func castFunc(t string) string {
 // Parsed value from query string parameters or application/x-www-form-urlencoded form will be string type.
 // You may need to convert the type by yourself.
 func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omitempty bool, caseconvertor func(string) string) {
+	for i, inter := range ic.Interfaces {
+		genHttpHandlerImplWithImpl(dir, ic, inter, fileOf("handlerimpl.go", i, inter), omitempty, caseconvertor)
+	}
+}
+
+func genHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, inter astutils.InterfaceMeta, file string, omitempty bool, caseconvertor func(string) string) {
+	handlerimplfile, original, merged := mergedHttpHandlerImpl(dir, ic, inter, file, omitempty, caseconvertor)
+	if original != nil {
+		logrus.Warningf("file %s will be merged with newly generated code\n", file)
+	}
+	if err := ioutil.WriteFile(handlerimplfile, merged, os.ModePerm); err != nil {
+		panic(err)
+	}
+}
+
+// DiffHttpHandlerImplWithImpl returns changes which GenHttpHandlerImplWithImpl will make in unified diff format without writing any file
+func DiffHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omitempty bool, caseconvertor func(string) string) string {
+	var sb strings.Builder
+	for i, inter := range ic.Interfaces {
+		handlerimplfile, original, merged := mergedHttpHandlerImpl(dir, ic, inter, fileOf("handlerimpl.go", i, inter), omitempty, caseconvertor)
+		sb.WriteString(unifiedDiff(relOf(dir, handlerimplfile), original, merged))
+	}
+	return sb.String()
+}
+
+// mergedHttpHandlerImpl returns path of handlerimpl file, its original content and the content merged with newly generated code.
+// Original content is nil if file not exists.
+func mergedHttpHandlerImpl(dir string, ic astutils.InterfaceCollector, inter astutils.InterfaceMeta, file string, omitempty bool, caseconvertor func(string) string) (string, []byte, []byte) {
 	var (
 		err             error
 		modfile         string
 		modName         string
 		firstLine       string
 		handlerimplfile string
-		f               *os.File
 		modf            *os.File
 		tpl             *template.Template
 		buf             bytes.Buffer
 		httpDir         string
-		fi              os.FileInfo
-		tmpl            string
-		meta            astutils.InterfaceMeta
+		original        []byte
+		merged          []byte
 	)
 	httpDir = filepath.Join(dir, "transport/httpsrv")
 	if err = os.MkdirAll(httpDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
-	handlerimplfile = filepath.Join(httpDir, "handlerimpl.go")
-	fi, err = os.Stat(handlerimplfile)
+	handlerimplfile = filepath.Join(httpDir, file)
+	original, err = ioutil.ReadFile(handlerimplfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 
-	err = copier.DeepCopy(ic.Interfaces[0], &meta)
-	if err != nil {
-		panic(err)
-	}
-	if fi != nil {
-		logrus.Warningln("New content will be append to file handlerimpl.go")
-		if f, err = os.OpenFile(handlerimplfile, os.O_APPEND, os.ModePerm); err != nil {
-			panic(err)
-		}
-		defer f.Close()
-		tmpl = appendHttpHandlerImplTmpl
-
-		fset := token.NewFileSet()
-		root, err := parser.ParseFile(fset, handlerimplfile, nil, parser.ParseComments)
-		if err != nil {
-			panic(err)
-		}
-		sc := astutils.NewStructCollector(astutils.ExprString)
-		ast.Walk(sc, root)
-		if handlers, exists := sc.Methods[meta.Name+"HandlerImpl"]; exists {
-			var notimplemented []astutils.MethodMeta
-			for _, item := range meta.Methods {
-				for _, handler := range handlers {
-					if len(handler.Params) != 2 {
-						continue
-					}
-					if handler.Params[0].Type == "http.ResponseWriter" &&
-						handler.Params[1].Type == "*http.Request" &&
-						item.Name == handler.Name {
-						goto L
-					}
-				}
-				notimplemented = append(notimplemented, item)
-
-			L:
-			}
-
-			meta.Methods = notimplemented
-		}
-	} else {
-		if f, err = os.Create(handlerimplfile); err != nil {
-			panic(err)
-		}
-		defer f.Close()
-		tmpl = initHttpHandlerImplTmpl
-	}
-
 	modfile = filepath.Join(dir, "go.mod")
 	if modf, err = os.Open(modfile); err != nil {
 		panic(err)
 	}
+	defer modf.Close()
 	reader := bufio.NewReader(modf)
 	if firstLine, err = reader.ReadString('\n'); err != nil {
 		panic(err)
@@ -372,7 +453,15 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 	funcMap["isSupport"] = isSupport
 	funcMap["castFunc"] = castFunc
 	funcMap["convertCase"] = caseconvertor
-	if tpl, err = template.New("handlerimpl.go.tmpl").Funcs(funcMap).Parse(tmpl); err != nil {
+	funcMap["isPathVar"] = isPathVar
+	funcMap["headerOf"] = headerOf
+	funcMap["cookieOf"] = cookieOf
+	funcMap["rulesOf"] = rulesOf
+	funcMap["isJsonBody"] = isJsonBody
+	funcMap["needValidate"] = needValidate
+	funcMap["isEventStream"] = IsEventStream
+	funcMap["isByteStream"] = IsByteStream
+	if tpl, err = template.New("handlerimpl.go.tmpl").Funcs(funcMap).Parse(initHttpHandlerImplTmpl); err != nil {
 		panic(err)
 	}
 	if err = tpl.Execute(&buf, struct {
@@ -385,18 +474,22 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 		ServicePackage: modName,
 		ServiceAlias:   ic.Package.Name,
 		VoPackage:      modName + "/vo",
-		Meta:           meta,
+		Meta:           inter,
 		Omitempty:      omitempty,
 	}); err != nil {
 		panic(err)
 	}
 
-	original, err := ioutil.ReadAll(f)
-	if err != nil {
+	if merged, err = markGenerated(handlerimplfile, buf.Bytes(), inter.Name+"HandlerImpl"); err != nil {
 		panic(err)
 	}
-
-	original = append(original, buf.Bytes()...)
-	//fmt.Println(string(original))
-	astutils.FixImport(original, handlerimplfile)
+	if original != nil {
+		if merged, err = mergeHandlerImpl(handlerimplfile, original, merged, inter.Name+"HandlerImpl"); err != nil {
+			panic(err)
+		}
+	}
+	if merged, err = astutils.GoImports(merged, handlerimplfile); err != nil {
+		panic(err)
+	}
+	return handlerimplfile, original, merged
 }
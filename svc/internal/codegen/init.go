@@ -98,7 +98,11 @@ const gitignoreTmpl = "# Binaries for programs and plugins\n*.exe\n*.exe~\n*.dll
 const envTmpl = `GDD_BANNER=on
 GDD_BANNERTEXT=Go-doudou
 GDD_LOGLEVEL=
+# accept 'json' for json logs, otherwise logs are text
+GDD_LOG_FORMAT=
 GDD_GRACETIMEOUT=15s
+# how long to keep serving after readiness checks start failing on shutdown
+GDD_SHUTDOWN_DELAY=5s
 
 DB_HOST=localhost
 DB_PORT=3306
@@ -109,12 +113,49 @@ DB_CHARSET=utf8mb4
 DB_DRIVER=mysql
 
 GDD_WRITETIMEOUT=15s
+# how long each write of event and byte streams may take, GDD_WRITETIMEOUT is used if empty
+GDD_STREAM_TIMEOUT=
 GDD_READTIMEOUT=15s
 GDD_IDLETIMEOUT=60s
+# how long to wait for request headers, GDD_READTIMEOUT is used if empty
+GDD_READ_HEADER_TIMEOUT=5s
+GDD_MAX_HEADER_BYTES=1MB
+# max size of request bodies, which is overridden by @maxbody annotations of methods in svc.go, bodies are not limited if 0
+GDD_MAX_BODY=32MB
 
 # add prefix path to all routes
 GDD_ROUTE_ROOT_PATH=
 
+# accept 'chi' for go-chi/chi based http server, otherwise gorilla/mux based http server is used
+GDD_ROUTER=
+
+# accept 'stdout' or a file path to export spans started by ddhttp.Tracing middleware and clients as json lines
+GDD_TRACING_OUTPUT=
+
+# comma separated buckets in seconds of latency histograms exposed by /go-doudou/prometheus, e.g. 0.01,0.05,0.1,0.5,1
+# default buckets of prometheus client are used if empty
+GDD_PROMETHEUS_BUCKETS=
+
+# serve https if both GDD_TLS_CERT and GDD_TLS_KEY are set, the certificate is also the client certificate of clients
+# created by ddhttp.NewClient. Certificates are reloaded once their files change
+GDD_TLS_CERT=
+GDD_TLS_KEY=
+# CA certificate to verify client certificates by the server and server certificates by clients
+GDD_TLS_CA=
+# accept 'require' to require client certificates verified by GDD_TLS_CA for mutual tls, or 'verify' to verify them only if given
+GDD_TLS_CLIENT_AUTH=
+
+# comma separated origins allowed by ddhttp.Cors middleware, * is wildcard, e.g. https://*.example.com,http://localhost:*
+# CORS is disabled if empty
+GDD_CORS_ALLOWED_ORIGINS=
+# GET,POST,PUT,PATCH,DELETE,HEAD by default
+GDD_CORS_ALLOWED_METHODS=
+# headers requested by browsers are allowed if empty
+GDD_CORS_ALLOWED_HEADERS=
+GDD_CORS_EXPOSED_HEADERS=
+GDD_CORS_ALLOW_CREDENTIALS=false
+GDD_CORS_MAX_AGE=10m
+
 # if true, it will add built-in apis with /go-doudou path prefix for online api document and service status monitor etc.
 # if you don't' need the feature, just set it false or remove it
 GDD_MANAGE_ENABLE=true
@@ -127,6 +168,8 @@ GDD_NAME={{.SvcName}}
 GDD_PORT=6060
 GDD_MEM_PORT=
 GDD_BASE_URL=
+# weight of the node for weighted round robin load balancing of clients, 1 by default
+GDD_WEIGHT=
 GDD_SEED=192.168.101.6:52634
 # accept 'mono' for monolith mode or 'micro' for microservice mode
 GDD_MODE=micro`
@@ -0,0 +1,71 @@
+package codegen
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+// fileOf returns name of the file generated from the index-th interface in svc.go.
+// The first interface is the main service interface, so its file name is the same as before,
+// while lower-cased interface name is prepended to file name for the others, e.g. handlerimpl.go and adminsvchandlerimpl.go
+func fileOf(file string, index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return file
+	}
+	return strings.ToLower(meta.Name) + file
+}
+
+// routesFuncOf returns name of the generated function returning routes of the index-th interface in svc.go.
+// It is Routes for the main service interface and prepended by interface name for the others, e.g. AdminsvcRoutes
+func routesFuncOf(index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return "Routes"
+	}
+	return meta.Name + "Routes"
+}
+
+// authOptionsFuncOf returns name of the generated function returning auth options of the index-th interface in svc.go.
+// It is AuthOptions for the main service interface and prepended by interface name for the others, e.g. AdminsvcAuthOptions
+func authOptionsFuncOf(index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return "AuthOptions"
+	}
+	return meta.Name + "AuthOptions"
+}
+
+// cacheOptionsFuncOf returns name of the generated function returning cache options of the index-th interface in svc.go.
+// It is CacheOptions for the main service interface and prepended by interface name for the others, e.g. AdminsvcCacheOptions
+func cacheOptionsFuncOf(index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return "CacheOptions"
+	}
+	return meta.Name + "CacheOptions"
+}
+
+// idempotencyOptionsFuncOf returns name of the generated function returning idempotency options of the index-th interface
+// in svc.go. It is IdempotencyOptions for the main service interface and prepended by interface name for the others
+func idempotencyOptionsFuncOf(index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return "IdempotencyOptions"
+	}
+	return meta.Name + "IdempotencyOptions"
+}
+
+// bodyLimitOptionsFuncOf returns name of the generated function returning body limit options of the index-th interface
+// in svc.go. It is BodyLimitOptions for the main service interface and prepended by interface name for the others
+func bodyLimitOptionsFuncOf(index int, meta astutils.InterfaceMeta) string {
+	if index == 0 {
+		return "BodyLimitOptions"
+	}
+	return meta.Name + "BodyLimitOptions"
+}
+
+// relOf returns path of file relative to dir for printing
+func relOf(dir, file string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+func Test_fileOf(t *testing.T) {
+	meta := astutils.InterfaceMeta{Name: "Adminsvc"}
+	tests := []struct {
+		name  string
+		file  string
+		index int
+		want  string
+	}{
+		{"main interface", "handlerimpl.go", 0, "handlerimpl.go"},
+		{"other interface", "handlerimpl.go", 1, "adminsvchandlerimpl.go"},
+		{"client", "client.go", 2, "adminsvcclient.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fileOf(tt.file, tt.index, meta); got != tt.want {
+				t.Errorf("fileOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_routesFuncOf(t *testing.T) {
+	meta := astutils.InterfaceMeta{Name: "Adminsvc"}
+	if got := routesFuncOf(0, meta); got != "Routes" {
+		t.Errorf("routesFuncOf() = %v, want %v", got, "Routes")
+	}
+	if got := routesFuncOf(1, meta); got != "AdminsvcRoutes" {
+		t.Errorf("routesFuncOf() = %v, want %v", got, "AdminsvcRoutes")
+	}
+}
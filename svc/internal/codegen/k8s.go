@@ -37,6 +37,17 @@ spec:
             requests:
               cpu: 100m
               memory: 128Mi
+          livenessProbe:
+            httpGet:
+              path: /go-doudou/livez
+              port: http-port
+            initialDelaySeconds: 10
+            periodSeconds: 10
+          readinessProbe:
+            httpGet:
+              path: /go-doudou/readyz
+              port: http-port
+            periodSeconds: 5
       restartPolicy: Always
 ---
 apiVersion: v1
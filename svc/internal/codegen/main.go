@@ -13,6 +13,7 @@ import (
 var mainTmpl = `package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/ascarter/requestid"
 	"github.com/gorilla/handlers"
@@ -20,7 +21,10 @@ import (
 	"github.com/unionj-cloud/go-doudou/pathutils"
 	ddconfig "github.com/unionj-cloud/go-doudou/svc/config"
 	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"github.com/unionj-cloud/go-doudou/svc/http/health"
+	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
 	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"time"
 	{{.ServiceAlias}} "{{.ServicePackage}}"
     "{{.ConfigPackage}}"
 	"{{.DbPackage}}"
@@ -35,16 +39,10 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	defer func() {
-		if conn == nil {
-			return
-		}
-		if err := conn.Close(); err == nil {
-			logrus.Infoln("Database connection is closed")
-		} else {
-			logrus.Warnln("Failed to close database connection")
-		}
-	}()
+	ddhttp.OnShutdown(ddhttp.PhaseCleanup, "database", func(ctx context.Context) error {
+		return conn.Close()
+	})
+	health.Register("db", health.DbCheck(conn))
 
 	if ddconfig.GddMode.Load() == "micro" {
 		node, err := registry.NewNode()
@@ -52,18 +50,60 @@ func main() {
 			logrus.Panicln(fmt.Sprintf("%+v", err))
 		}
 		logrus.Infof("Memberlist created. Local node is %s\n", node)
+		health.Register("memberlist", health.MemberlistCheck(node))
+		onlinedoc.SetRegistry(node)
+		ddhttp.OnShutdown(ddhttp.PhaseDeregister, "memberlist", func(ctx context.Context) error {
+			return node.Leave(5 * time.Second)
+		})
 	}
 
     svc := {{.ServiceAlias}}.New{{.SvcName}}(conf, conn)
 
 	handler := httpsrv.New{{.SvcName}}Handler(svc)
-	srv := ddhttp.NewDefaultHttpSrv()
-	srv.AddMiddleware(ddhttp.Metrics, requestid.RequestIDHandler, handlers.CompressHandler, handlers.ProxyHeaders, ddhttp.Logger, ddhttp.Rest)
+	srv := ddhttp.NewHttpSrv()
+	bodyLimits := httpsrv.BodyLimitOptions()
+	{{- range .Others }}
+	bodyLimits = append(bodyLimits, httpsrv.{{.BodyLimitOptionsFunc}}()...)
+	{{- end }}
+	srv.AddMiddleware(ddhttp.Metrics, ddhttp.Cors, ddhttp.NewBodyLimit(bodyLimits...).Middleware, requestid.RequestIDHandler, handlers.CompressHandler, handlers.ProxyHeaders, ddhttp.Tracing, ddhttp.Logger, ddhttp.Rest)
+	authOptions := httpsrv.AuthOptions()
+	{{- range .Others }}
+	authOptions = append(authOptions, httpsrv.{{.AuthOptionsFunc}}()...)
+	{{- end }}
+	// @public and @role annotations in svc.go take effect once auth middleware is added with authenticators, e.g.
+	// jwt, err := ddhttp.NewJWTAuthenticator(ddhttp.WithHMACSecret([]byte(os.Getenv("JWT_SECRET"))))
+	// srv.AddMiddleware(ddhttp.NewAuth(append(authOptions, ddhttp.WithAuthenticators(jwt))...).Middleware)
+	// then remove the warning below
+	if len(authOptions) > 0 {
+		logrus.Warnln("auth middleware is not added in cmd/main.go, @public and @role annotations in svc.go are ignored")
+	}
+	cacheOptions := append(httpsrv.CacheOptions(), ddhttp.WithResponseCache(ddhttp.DefaultResponseCache))
+	{{- range .Others }}
+	cacheOptions = append(cacheOptions, httpsrv.{{.CacheOptionsFunc}}()...)
+	{{- end }}
+	idempotencyOptions := httpsrv.IdempotencyOptions()
+	{{- range .Others }}
+	idempotencyOptions = append(idempotencyOptions, httpsrv.{{.IdempotencyOptionsFunc}}()...)
+	{{- end }}
+	srv.AddMiddleware(ddhttp.NewCache(cacheOptions...).Middleware, ddhttp.NewIdempotency(idempotencyOptions...).Middleware)
 	srv.AddRoute(httpsrv.Routes(handler)...)
+	{{- range .Others }}
+	srv.AddRoute(httpsrv.{{.RoutesFunc}}(httpsrv.New{{.Name}}Handler({{$.ServiceAlias}}.New{{.Name}}(conf, conn)))...)
+	{{- end }}
 	srv.Run()
 }
 `
 
+// mainInterface is an interface other than the main service interface in svc.go whose routes are added in main function
+type mainInterface struct {
+	Name                   string
+	RoutesFunc             string
+	BodyLimitOptionsFunc   string
+	AuthOptionsFunc        string
+	CacheOptionsFunc       string
+	IdempotencyOptionsFunc string
+}
+
 func GenMain(dir string, ic astutils.InterfaceCollector) {
 	var (
 		err       error
@@ -76,6 +116,7 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 		cmdDir    string
 		svcName   string
 		alias     string
+		others    []mainInterface
 	)
 	cmdDir = filepath.Join(dir, "cmd")
 	if err = os.MkdirAll(cmdDir, os.ModePerm); err != nil {
@@ -84,6 +125,16 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 
 	svcName = ic.Interfaces[0].Name
 	alias = ic.Package.Name
+	for i, item := range ic.Interfaces[1:] {
+		others = append(others, mainInterface{
+			Name:                   item.Name,
+			RoutesFunc:             routesFuncOf(i+1, item),
+			BodyLimitOptionsFunc:   bodyLimitOptionsFuncOf(i+1, item),
+			AuthOptionsFunc:        authOptionsFuncOf(i+1, item),
+			CacheOptionsFunc:       cacheOptionsFuncOf(i+1, item),
+			IdempotencyOptionsFunc: idempotencyOptionsFuncOf(i+1, item),
+		})
+	}
 	mainfile = filepath.Join(cmdDir, "main.go")
 	if _, err = os.Stat(mainfile); os.IsNotExist(err) {
 		modfile = filepath.Join(dir, "go.mod")
@@ -111,6 +162,7 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 			HttpPackage    string
 			SvcName        string
 			ServiceAlias   string
+			Others         []mainInterface
 		}{
 			ServicePackage: modName,
 			ConfigPackage:  modName + "/config",
@@ -118,6 +170,7 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 			HttpPackage:    modName + "/transport/httpsrv",
 			SvcName:        svcName,
 			ServiceAlias:   alias,
+			Others:         others,
 		}); err != nil {
 			panic(err)
 		}
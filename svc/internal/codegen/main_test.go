@@ -16,6 +16,7 @@ func TestGenMain(t *testing.T) {
 	expect := `package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/ascarter/requestid"
 	"github.com/gorilla/handlers"
@@ -23,7 +24,10 @@ import (
 	"github.com/unionj-cloud/go-doudou/pathutils"
 	ddconfig "github.com/unionj-cloud/go-doudou/svc/config"
 	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	"github.com/unionj-cloud/go-doudou/svc/http/health"
+	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
 	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"time"
 	service "testfilesmain"
     "testfilesmain/config"
 	"testfilesmain/db"
@@ -38,16 +42,10 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	defer func() {
-		if conn == nil {
-			return
-		}
-		if err := conn.Close(); err == nil {
-			logrus.Infoln("Database connection is closed")
-		} else {
-			logrus.Warnln("Failed to close database connection")
-		}
-	}()
+	ddhttp.OnShutdown(ddhttp.PhaseCleanup, "database", func(ctx context.Context) error {
+		return conn.Close()
+	})
+	health.Register("db", health.DbCheck(conn))
 
 	if ddconfig.GddMode.Load() == "micro" {
 		node, err := registry.NewNode()
@@ -55,13 +53,30 @@ func main() {
 			logrus.Panicln(fmt.Sprintf("%+v", err))
 		}
 		logrus.Infof("Memberlist created. Local node is %s\n", node)
+		health.Register("memberlist", health.MemberlistCheck(node))
+		onlinedoc.SetRegistry(node)
+		ddhttp.OnShutdown(ddhttp.PhaseDeregister, "memberlist", func(ctx context.Context) error {
+			return node.Leave(5 * time.Second)
+		})
 	}
 
     svc := service.NewTestfilesmain(conf, conn)
 
 	handler := httpsrv.NewTestfilesmainHandler(svc)
-	srv := ddhttp.NewDefaultHttpSrv()
-	srv.AddMiddleware(ddhttp.Metrics, requestid.RequestIDHandler, handlers.CompressHandler, handlers.ProxyHeaders, ddhttp.Logger, ddhttp.Rest)
+	srv := ddhttp.NewHttpSrv()
+	bodyLimits := httpsrv.BodyLimitOptions()
+	srv.AddMiddleware(ddhttp.Metrics, ddhttp.Cors, ddhttp.NewBodyLimit(bodyLimits...).Middleware, requestid.RequestIDHandler, handlers.CompressHandler, handlers.ProxyHeaders, ddhttp.Tracing, ddhttp.Logger, ddhttp.Rest)
+	authOptions := httpsrv.AuthOptions()
+	// @public and @role annotations in svc.go take effect once auth middleware is added with authenticators, e.g.
+	// jwt, err := ddhttp.NewJWTAuthenticator(ddhttp.WithHMACSecret([]byte(os.Getenv("JWT_SECRET"))))
+	// srv.AddMiddleware(ddhttp.NewAuth(append(authOptions, ddhttp.WithAuthenticators(jwt))...).Middleware)
+	// then remove the warning below
+	if len(authOptions) > 0 {
+		logrus.Warnln("auth middleware is not added in cmd/main.go, @public and @role annotations in svc.go are ignored")
+	}
+	cacheOptions := append(httpsrv.CacheOptions(), ddhttp.WithResponseCache(ddhttp.DefaultResponseCache))
+	idempotencyOptions := httpsrv.IdempotencyOptions()
+	srv.AddMiddleware(ddhttp.NewCache(cacheOptions...).Middleware, ddhttp.NewIdempotency(idempotencyOptions...).Middleware)
 	srv.AddRoute(httpsrv.Routes(handler)...)
 	srv.Run()
 }
@@ -0,0 +1,334 @@
+package codegen
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// goFile is a parsed go source file
+type goFile struct {
+	src  []byte
+	fset *token.FileSet
+	root *ast.File
+}
+
+func parseGoFile(file string, src []byte) (goFile, error) {
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return goFile{}, errors.Wrapf(err, "parse %s", file)
+	}
+	return goFile{src, fset, root}, nil
+}
+
+func (f goFile) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+// text returns source code between pos and end
+func (f goFile) text(pos, end token.Pos) string {
+	return string(f.src[f.offset(pos):f.offset(end)])
+}
+
+// declStart returns start position of the function including its doc comments
+func declStart(decl *ast.FuncDecl) token.Pos {
+	if decl.Doc != nil {
+		return decl.Doc.Pos()
+	}
+	return decl.Pos()
+}
+
+// methodsOf returns methods with receiver of type recv or *recv in the order of declaration
+func (f goFile) methodsOf(recv string) []*ast.FuncDecl {
+	var ret []*ast.FuncDecl
+	for _, decl := range f.root.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || len(fd.Recv.List) == 0 {
+			continue
+		}
+		t := fd.Recv.List[0].Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		if ident, ok := t.(*ast.Ident); ok && ident.Name == recv {
+			ret = append(ret, fd)
+		}
+	}
+	return ret
+}
+
+func findMethod(methods []*ast.FuncDecl, name string) *ast.FuncDecl {
+	for _, item := range methods {
+		if item.Name.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// normalize removes all white spaces so that formatted and unformatted source code can be compared
+func normalize(code string) string {
+	return strings.Join(strings.Fields(code), "")
+}
+
+// edit replaces source code between offset start and end with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	ret := string(src)
+	for _, item := range edits {
+		ret = ret[:item.start] + item.text + ret[item.end:]
+	}
+	return []byte(ret)
+}
+
+// importEdits adds imports of generated file missing from original file. Unused imports will be removed by goimports
+func importEdits(original, generated goFile) []edit {
+	existing := make(map[string]bool)
+	for _, spec := range original.root.Imports {
+		existing[spec.Path.Value] = true
+	}
+	var missing []string
+	for _, spec := range generated.root.Imports {
+		if existing[spec.Path.Value] {
+			continue
+		}
+		missing = append(missing, generated.text(spec.Pos(), spec.End()))
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	for _, decl := range original.root.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT || !gd.Lparen.IsValid() {
+			continue
+		}
+		pos := original.offset(gd.Rparen)
+		return []edit{{pos, pos, "\t" + strings.Join(missing, "\n\t") + "\n"}}
+	}
+	pos := original.offset(original.root.Name.End())
+	return []edit{{pos, pos, "\n\nimport (\n\t" + strings.Join(missing, "\n\t") + "\n)"}}
+}
+
+// appendEdit appends methods to the end of file
+func appendEdit(original, generated goFile, methods []*ast.FuncDecl) []edit {
+	if len(methods) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	for _, item := range methods {
+		sb.WriteString("\n\n")
+		sb.WriteString(generated.text(declStart(item), item.End()))
+	}
+	sb.WriteString("\n")
+	end := len(strings.TrimRight(string(original.src), "\n"))
+	return []edit{{end, len(original.src), sb.String()}}
+}
+
+// commentOut returns source code of the method with each line commented out.
+// A blank line is appended so that it won't become doc comments of the next declaration
+func commentOut(f goFile, decl *ast.FuncDecl) string {
+	lines := strings.Split(f.text(declStart(decl), decl.End()), "\n")
+	for i, line := range lines {
+		lines[i] = "// " + line
+	}
+	return "// " + decl.Name.Name + " is removed from service interface\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// isHttpHandler returns true if the method has the same signature as http.HandlerFunc
+func isHttpHandler(f goFile, decl *ast.FuncDecl) bool {
+	return sameTypes(typesOf(f, decl.Type.Params), []string{"http.ResponseWriter", "*http.Request"}) &&
+		len(typesOf(f, decl.Type.Results)) == 0
+}
+
+// handlerSignature returns the first var declaration in generated handler method body, in which parameters
+// and results of service method are declared. Empty string is returned if not found, which means the method
+// is written by user. It is only used for methods generated by previous versions without generatedMarker.
+func handlerSignature(f goFile, decl *ast.FuncDecl) string {
+	if decl.Body == nil || len(decl.Body.List) == 0 {
+		return ""
+	}
+	ds, ok := decl.Body.List[0].(*ast.DeclStmt)
+	if !ok {
+		return ""
+	}
+	if gd, ok := ds.Decl.(*ast.GenDecl); !ok || gd.Tok != token.VAR {
+		return ""
+	}
+	return normalize(f.text(ds.Pos(), ds.End()))
+}
+
+// generatedMarker is the doc comment of generated handler methods followed by hash of the method body,
+// by which we know whether the method is edited by user since generated
+const generatedMarker = "//gdd:generated "
+
+// bodyHash returns hash of normalized method body
+func bodyHash(f goFile, decl *ast.FuncDecl) string {
+	if decl.Body == nil {
+		return ""
+	}
+	sum := sha1.Sum([]byte(normalize(f.text(decl.Body.Pos(), decl.Body.End()))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// generatedHash returns hash recorded in generatedMarker of the method, or empty string if not found
+func generatedHash(decl *ast.FuncDecl) string {
+	if decl.Doc == nil {
+		return ""
+	}
+	for _, c := range decl.Doc.List {
+		if strings.HasPrefix(c.Text, generatedMarker) {
+			return strings.TrimSpace(strings.TrimPrefix(c.Text, generatedMarker))
+		}
+	}
+	return ""
+}
+
+// markGenerated adds generatedMarker with hash of the method body to each generated method of recv
+func markGenerated(file string, src []byte, recv string) ([]byte, error) {
+	f, err := parseGoFile(file, src)
+	if err != nil {
+		return nil, err
+	}
+	var edits []edit
+	for _, decl := range f.methodsOf(recv) {
+		pos := f.offset(decl.Pos())
+		edits = append(edits, edit{pos, pos, generatedMarker + bodyHash(f, decl) + "\n"})
+	}
+	return applyEdits(src, edits), nil
+}
+
+// mergeHandlerImpl merges generated handler implementation into original one.
+// Methods newly added to service interface are appended. Generated methods not edited by user since generated are
+// replaced if newly generated code is different, while the others including user edited ones are kept.
+// Methods removed from service interface are commented out.
+func mergeHandlerImpl(file string, original, generated []byte, recv string) ([]byte, error) {
+	of, err := parseGoFile(file, original)
+	if err != nil {
+		return nil, err
+	}
+	gf, err := parseGoFile(file, generated)
+	if err != nil {
+		return nil, err
+	}
+	edits := importEdits(of, gf)
+	omethods := of.methodsOf(recv)
+	gmethods := gf.methodsOf(recv)
+	var added []*ast.FuncDecl
+	for _, gm := range gmethods {
+		om := findMethod(omethods, gm.Name.Name)
+		if om == nil {
+			added = append(added, gm)
+			continue
+		}
+		body, gbody := bodyHash(of, om), bodyHash(gf, gm)
+		switch hash := generatedHash(om); {
+		case body == gbody:
+			if hash == gbody {
+				continue
+			}
+			// same as newly generated code but without marker or with stale marker, replace it to update the marker
+		case hash == "":
+			osig := handlerSignature(of, om)
+			if osig == "" {
+				logrus.Warnf("method %s of %s in file %s is not generated by go-doudou, skip", om.Name.Name, recv, file)
+				continue
+			}
+			if osig == handlerSignature(gf, gm) {
+				logrus.Warnf("method %s of %s in file %s differs from newly generated code but may be edited, skip. "+
+					"Remove it to regenerate", om.Name.Name, recv, file)
+				continue
+			}
+		case hash != body:
+			logrus.Warnf("method %s of %s in file %s is edited, skip. Remove it to regenerate", om.Name.Name, recv, file)
+			continue
+		}
+		edits = append(edits, edit{of.offset(declStart(om)), of.offset(om.End()), gf.text(declStart(gm), gm.End())})
+	}
+	for _, om := range omethods {
+		if findMethod(gmethods, om.Name.Name) == nil && isHttpHandler(of, om) && isGenerated(of, om) {
+			edits = append(edits, edit{of.offset(declStart(om)), of.offset(om.End()), commentOut(of, om)})
+		}
+	}
+	edits = append(edits, appendEdit(of, gf, added)...)
+	return applyEdits(original, edits), nil
+}
+
+// isGenerated returns true if the method is generated by go-doudou, no matter whether it is edited by user
+func isGenerated(f goFile, decl *ast.FuncDecl) bool {
+	return generatedHash(decl) != "" || handlerSignature(f, decl) != ""
+}
+
+// typesOf returns normalized types of fields, e.g. ["context.Context", "int"] for (ctx context.Context, id int)
+func typesOf(f goFile, fields *ast.FieldList) []string {
+	var ret []string
+	if fields == nil {
+		return ret
+	}
+	for _, field := range fields.List {
+		t := normalize(f.text(field.Type.Pos(), field.Type.End()))
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func sameTypes(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+// mergeSvcImpl merges generated service implementation into original one without touching method bodies written by user.
+// Methods newly added to service interface are appended. Signatures of methods whose parameter or result types changed
+// are replaced. Methods removed from service interface are kept.
+func mergeSvcImpl(file string, original, generated []byte, recv string) ([]byte, error) {
+	of, err := parseGoFile(file, original)
+	if err != nil {
+		return nil, err
+	}
+	gf, err := parseGoFile(file, generated)
+	if err != nil {
+		return nil, err
+	}
+	edits := importEdits(of, gf)
+	omethods := of.methodsOf(recv)
+	gmethods := gf.methodsOf(recv)
+	var added []*ast.FuncDecl
+	for _, gm := range gmethods {
+		om := findMethod(omethods, gm.Name.Name)
+		if om == nil {
+			added = append(added, gm)
+			continue
+		}
+		if sameTypes(typesOf(of, om.Type.Params), typesOf(gf, gm.Type.Params)) &&
+			sameTypes(typesOf(of, om.Type.Results), typesOf(gf, gm.Type.Results)) {
+			continue
+		}
+		edits = append(edits, edit{of.offset(om.Type.Params.Pos()), of.offset(om.Type.End()), gf.text(gm.Type.Params.Pos(), gm.Type.End())})
+	}
+	for _, om := range omethods {
+		if findMethod(gmethods, om.Name.Name) == nil {
+			logrus.Infof("method %s of %s in file %s is not in service interface, keep it", om.Name.Name, recv, file)
+		}
+	}
+	edits = append(edits, appendEdit(of, gf, added)...)
+	return applyEdits(original, edits), nil
+}
@@ -0,0 +1,240 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+const originalHandlerImpl = `package httpsrv
+
+import (
+	"net/http"
+)
+
+func (receiver *UsersvcHandlerImpl) GetUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		id int
+	)
+	_writer.Write([]byte("edited"))
+}
+
+func (receiver *UsersvcHandlerImpl) Custom(_writer http.ResponseWriter, _req *http.Request) {
+	_writer.Write([]byte("custom"))
+}
+
+func (receiver *UsersvcHandlerImpl) ExistUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		id int
+	)
+}
+
+func (receiver *UsersvcHandlerImpl) PageUsers(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		page int
+	)
+}
+`
+
+const generatedHandlerImpl = `package httpsrv
+
+import (
+	"context"
+	"net/http"
+)
+
+func (receiver *UsersvcHandlerImpl) GetUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		id int
+	)
+	_writer.Write([]byte("generated"))
+}
+
+func (receiver *UsersvcHandlerImpl) PageUsers(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		ctx  context.Context
+		page int
+	)
+}
+
+func (receiver *UsersvcHandlerImpl) CountUsers(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		ctx context.Context
+	)
+}
+`
+
+func TestMergeHandlerImpl(t *testing.T) {
+	got, err := mergeHandlerImpl("handlerimpl.go", []byte(originalHandlerImpl), []byte(generatedHandlerImpl), "UsersvcHandlerImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := string(got)
+	for _, want := range []string{
+		`"context"`,
+		`_writer.Write([]byte("edited"))`,
+		`_writer.Write([]byte("custom"))`,
+		"// ExistUser is removed from service interface\n// func (receiver *UsersvcHandlerImpl) ExistUser(",
+		"ctx  context.Context\n\t\tpage int",
+		"func (receiver *UsersvcHandlerImpl) CountUsers(",
+	} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("mergeHandlerImpl() should contain %q, got:\n%s", want, merged)
+		}
+	}
+	if strings.Contains(merged, `[]byte("generated")`) {
+		t.Errorf("mergeHandlerImpl() should not overwrite unchanged method, got:\n%s", merged)
+	}
+	if _, err = parseGoFile("handlerimpl.go", got); err != nil {
+		t.Errorf("mergeHandlerImpl() got invalid code: %v", err)
+	}
+}
+
+const previousHandlerImpl = `package httpsrv
+
+import (
+	"net/http"
+)
+
+func (receiver *UsersvcHandlerImpl) GetUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		id int
+	)
+	id = _cast.ToIntOrDefault(_req.FormValue("id"), 0)
+}
+
+func (receiver *UsersvcHandlerImpl) PageUsers(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		page int
+	)
+	page = _cast.ToIntOrDefault(_req.FormValue("page"), 0)
+}
+`
+
+const regeneratedHandlerImpl = `package httpsrv
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func (receiver *UsersvcHandlerImpl) GetUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		id int
+	)
+	id = _cast.ToIntOrDefault(mux.Vars(_req)["id"], 0)
+}
+
+func (receiver *UsersvcHandlerImpl) PageUsers(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		page int
+	)
+	page = _cast.ToIntOrDefault(mux.Vars(_req)["page"], 0)
+}
+`
+
+func TestMergeHandlerImpl_Marker(t *testing.T) {
+	previous, err := markGenerated("handlerimpl.go", []byte(previousHandlerImpl), "UsersvcHandlerImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PageUsers is edited by user since generated while GetUser is not
+	original := strings.Replace(string(previous), `page = _cast.ToIntOrDefault(_req.FormValue("page"), 0)`, `page = 1`, 1)
+	generated, err := markGenerated("handlerimpl.go", []byte(regeneratedHandlerImpl), "UsersvcHandlerImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mergeHandlerImpl("handlerimpl.go", []byte(original), generated, "UsersvcHandlerImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := string(got)
+	for _, want := range []string{
+		`id = _cast.ToIntOrDefault(mux.Vars(_req)["id"], 0)`,
+		"page = 1",
+	} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("mergeHandlerImpl() should contain %q, got:\n%s", want, merged)
+		}
+	}
+	if strings.Contains(merged, `_req.FormValue("id")`) {
+		t.Errorf("mergeHandlerImpl() should replace unedited method, got:\n%s", merged)
+	}
+	if strings.Count(merged, generatedMarker) != 2 {
+		t.Errorf("mergeHandlerImpl() should keep markers, got:\n%s", merged)
+	}
+
+	again, err := mergeHandlerImpl("handlerimpl.go", got, generated, "UsersvcHandlerImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != merged {
+		t.Errorf("mergeHandlerImpl() should not change merged code, got:\n%s", again)
+	}
+}
+
+const originalSvcImpl = `package service
+
+import (
+	"context"
+)
+
+type UsersvcImpl struct {
+}
+
+func (receiver *UsersvcImpl) PageUsers(ctx context.Context, page int) (data string, err error) {
+	return "edited", nil
+}
+
+func (receiver *UsersvcImpl) GetUser(_ context.Context, id int) (data string, err error) {
+	return "edited", nil
+}
+
+func (receiver *UsersvcImpl) ExistUser(ctx context.Context, id int) (bool, error) {
+	return true, nil
+}
+`
+
+const generatedSvcImpl = `package service
+
+import (
+	"context"
+	"testsvc/vo"
+)
+
+type UsersvcImpl struct {
+}
+
+func (receiver *UsersvcImpl) PageUsers(ctx context.Context, query vo.PageQuery) (data string, err error) {
+	var _result struct {
+		Data string
+	}
+	return _result.Data, nil
+}
+
+func (receiver *UsersvcImpl) GetUser(ctx context.Context, id int) (data string, err error) {
+	return "", nil
+}
+
+func (receiver *UsersvcImpl) CountUsers(ctx context.Context) (total int, err error) {
+	return 0, nil
+}
+`
+
+func TestMergeSvcImpl(t *testing.T) {
+	got, err := mergeSvcImpl("svcimpl.go", []byte(originalSvcImpl), []byte(generatedSvcImpl), "UsersvcImpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := string(got)
+	for _, want := range []string{
+		`"testsvc/vo"`,
+		"func (receiver *UsersvcImpl) PageUsers(ctx context.Context, query vo.PageQuery) (data string, err error) {\n\treturn \"edited\", nil",
+		"func (receiver *UsersvcImpl) GetUser(_ context.Context, id int) (data string, err error) {\n\treturn \"edited\", nil",
+		"func (receiver *UsersvcImpl) ExistUser(",
+		"func (receiver *UsersvcImpl) CountUsers(",
+	} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("mergeSvcImpl() should contain %q, got:\n%s", want, merged)
+		}
+	}
+}
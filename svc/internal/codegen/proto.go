@@ -0,0 +1,395 @@
+package codegen
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+)
+
+type protoKind int
+
+const (
+	kindScalar protoKind = iota
+	kindMessage
+	kindRepeated
+	kindMap
+	// kindJson is for go types which cannot be represented by protobuf types, e.g. interface{}, anonymous struct
+	// and nested slice. Values of them are encoded to json and transferred as bytes
+	kindJson
+)
+
+// protoType describes how a go type in svc.go or vo package is represented in protobuf
+type protoType struct {
+	kind protoKind
+	// name is protobuf type name of scalar and message, e.g. int64, PageQuery
+	name string
+	// pbType is go type of scalar in code generated by protoc-gen-go, e.g. int64
+	pbType string
+	// goType is go type qualified by vo package without leading *, e.g. vo.PageQuery, []int
+	goType string
+	// ptr is true for pointer to scalar or message
+	ptr bool
+	// elem is element type of repeated and value type of map
+	elem *protoType
+}
+
+var protoScalars = map[string]protoType{
+	"bool":    {name: "bool", pbType: "bool"},
+	"string":  {name: "string", pbType: "string"},
+	"int":     {name: "int64", pbType: "int64"},
+	"int64":   {name: "int64", pbType: "int64"},
+	"int8":    {name: "int32", pbType: "int32"},
+	"int16":   {name: "int32", pbType: "int32"},
+	"int32":   {name: "int32", pbType: "int32"},
+	"rune":    {name: "int32", pbType: "int32"},
+	"uint":    {name: "uint64", pbType: "uint64"},
+	"uint64":  {name: "uint64", pbType: "uint64"},
+	"uint8":   {name: "uint32", pbType: "uint32"},
+	"byte":    {name: "uint32", pbType: "uint32"},
+	"uint16":  {name: "uint32", pbType: "uint32"},
+	"uint32":  {name: "uint32", pbType: "uint32"},
+	"float32": {name: "float", pbType: "float32"},
+	"float64": {name: "double", pbType: "float64"},
+	"[]byte":  {name: "bytes", pbType: "[]byte"},
+	"[]uint8": {name: "bytes", pbType: "[]byte"},
+}
+
+// decl returns type in protobuf field declaration, e.g. repeated PageQuery
+func (t protoType) decl() string {
+	switch t.kind {
+	case kindRepeated:
+		return "repeated " + t.elem.name
+	case kindMap:
+		return "map<string, " + t.elem.name + ">"
+	case kindScalar:
+		if t.ptr {
+			return "optional " + t.name
+		}
+		return t.name
+	default:
+		return t.name
+	}
+}
+
+// pbGoType returns go type of the field in code generated by protoc-gen-go
+func (t protoType) pbGoType() string {
+	switch t.kind {
+	case kindScalar:
+		if t.ptr {
+			return "*" + t.pbType
+		}
+		return t.pbType
+	case kindMessage:
+		return "*" + t.name
+	case kindRepeated:
+		return "[]" + t.elem.pbGoType()
+	case kindMap:
+		return "map[string]" + t.elem.pbGoType()
+	default:
+		return "[]byte"
+	}
+}
+
+// protoResolver resolves go types in svc.go and vo package to protobuf types
+type protoResolver struct {
+	// structs are exported structs in vo package
+	structs map[string]astutils.StructMeta
+	// named are non-struct types declared in vo package with their underlying types, e.g. type Status int
+	named map[string]string
+}
+
+func newProtoResolver(dir string) protoResolver {
+	ret := protoResolver{
+		structs: make(map[string]astutils.StructMeta),
+		named:   make(map[string]string),
+	}
+	for _, item := range voStructsOf(dir, ret.named) {
+		ret.structs[item.Name] = item
+	}
+	return ret
+}
+
+// voStructsOf returns exported structs in vo package with embedded fields flattened,
+// and collects non-struct types into named
+func voStructsOf(dir string, named map[string]string) []astutils.StructMeta {
+	vodir := filepath.Join(dir, "vo")
+	var files []string
+	if err := filepath.Walk(vodir, astutils.Visit(&files)); err != nil {
+		panic(err)
+	}
+	sc := astutils.NewStructCollector(ExprStringP)
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		root, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ParseComments)
+		if err != nil {
+			panic(err)
+		}
+		ast.Walk(sc, root)
+	}
+	for name, expr := range sc.NonStructTypeMap {
+		named[name] = ExprStringP(expr)
+	}
+	return sc.DocFlatEmbed()
+}
+
+func (r protoResolver) typeOf(goType string) protoType {
+	return r.resolve(goType, nil)
+}
+
+func (r protoResolver) resolve(goType string, visiting []string) protoType {
+	json := protoType{kind: kindJson, name: "bytes"}
+	t := goType
+	var ptr bool
+	if strings.HasPrefix(t, "*") {
+		ptr = true
+		t = t[1:]
+	}
+	if scalar, ok := protoScalars[t]; ok {
+		if ptr && scalar.name == "bytes" {
+			return json
+		}
+		scalar.kind = kindScalar
+		scalar.goType = t
+		scalar.ptr = ptr
+		return scalar
+	}
+	ident := strings.TrimPrefix(t, "vo.")
+	if _, ok := r.structs[ident]; ok {
+		return protoType{kind: kindMessage, name: ident, goType: "vo." + ident, ptr: ptr}
+	}
+	if underlying, ok := r.named[ident]; ok {
+		if sliceutils.StringContains(visiting, ident) {
+			return json
+		}
+		ret := r.resolve(underlying, append(visiting, ident))
+		if ret.kind == kindJson || ret.kind == kindMessage || (ptr && ret.kind != kindScalar) || ret.ptr {
+			return json
+		}
+		ret.goType = "vo." + ident
+		ret.ptr = ptr
+		return ret
+	}
+	if ptr {
+		return json
+	}
+	var elem protoType
+	if strings.HasPrefix(t, "[]") {
+		elem = r.resolve(strings.TrimPrefix(t, "[]"), visiting)
+	} else if strings.HasPrefix(t, "map[string]") {
+		elem = r.resolve(strings.TrimPrefix(t, "map[string]"), visiting)
+	} else {
+		return json
+	}
+	if elem.kind == kindJson || elem.kind == kindRepeated || elem.kind == kindMap || (elem.kind == kindScalar && elem.ptr) {
+		return json
+	}
+	elemGoType := elem.goType
+	if elem.ptr {
+		elemGoType = "*" + elemGoType
+	}
+	if strings.HasPrefix(t, "[]") {
+		return protoType{kind: kindRepeated, goType: "[]" + elemGoType, elem: &elem}
+	}
+	return protoType{kind: kindMap, goType: "map[string]" + elemGoType, elem: &elem}
+}
+
+// protoField is a field of protobuf message
+type protoField struct {
+	// Name is protobuf field name in snake case
+	Name string
+	// GoName is name of the go struct field or method parameter/result
+	GoName   string
+	Number   int
+	Type     protoType
+	Comments []string
+}
+
+// Decl returns protobuf field declaration, e.g. repeated string names = 1;
+func (f protoField) Decl() string {
+	ret := fmt.Sprintf("%s %s = %d;", f.Type.decl(), f.Name, f.Number)
+	if f.Type.kind == kindJson {
+		ret += " // json encoded"
+	}
+	return ret
+}
+
+// PbName returns name of the field in go struct generated by protoc-gen-go
+func (f protoField) PbName() string {
+	name := goCamelCase(f.Name)
+	// protoc-gen-go appends underscore to field names conflicting with generated methods
+	for sliceutils.StringContains([]string{"Reset", "String", "ProtoMessage", "Marshal", "Unmarshal",
+		"ExtensionRangeArray", "ExtensionMap", "Descriptor"}, name) {
+		name += "_"
+	}
+	return name
+}
+
+// protoMessage is a protobuf message generated from vo struct or parameters and results of service method
+type protoMessage struct {
+	Name     string
+	Fields   []protoField
+	Comments []string
+}
+
+// protoMethod is a rpc method of protobuf service
+type protoMethod struct {
+	Meta     astutils.MethodMeta
+	Request  protoMessage
+	Response protoMessage
+}
+
+// protoService is a protobuf service generated from an interface in svc.go
+type protoService struct {
+	Meta    astutils.InterfaceMeta
+	Methods []protoMethod
+}
+
+// goCamelCase converts protobuf field name to go field name in the same way as protoc-gen-go
+func goCamelCase(s string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+		case isDigit(c):
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func protoFieldName(name string) string {
+	return strcase.ToSnake(name)
+}
+
+// grpcUnsupported returns true if the method has parameters or results which cannot be transferred by grpc,
+// e.g. *multipart.FileHeader, *os.File and streaming results
+func grpcUnsupported(method astutils.MethodMeta) bool {
+	for _, item := range append(append([]astutils.FieldMeta{}, method.Params...), method.Results...) {
+		if strings.Contains(item.Type, "multipart.FileHeader") || strings.Contains(item.Type, "os.File") || IsStream(item.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcParams returns parameters of the method to be put into request message, context.Context is excluded
+func rpcParams(method astutils.MethodMeta) []astutils.FieldMeta {
+	var ret []astutils.FieldMeta
+	for _, item := range method.Params {
+		if item.Type == "context.Context" {
+			continue
+		}
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+// rpcResults returns results of the method to be put into response message, the last error result is excluded
+func rpcResults(method astutils.MethodMeta) []astutils.FieldMeta {
+	if len(method.Results) == 0 {
+		return nil
+	}
+	return method.Results[:len(method.Results)-1]
+}
+
+func (r protoResolver) messageOf(name string, fields []astutils.FieldMeta, comments []string) protoMessage {
+	ret := protoMessage{
+		Name:     name,
+		Comments: comments,
+	}
+	for _, item := range fields {
+		if jsonNameOf(item) == "-" {
+			continue
+		}
+		ret.Fields = append(ret.Fields, protoField{
+			Name:     protoFieldName(item.Name),
+			GoName:   item.Name,
+			Number:   len(ret.Fields) + 1,
+			Type:     r.typeOf(item.Type),
+			Comments: item.Comments,
+		})
+	}
+	return ret
+}
+
+// jsonNameOf returns name in json tag of the field
+func jsonNameOf(field astutils.FieldMeta) string {
+	return strings.Split(reflect.StructTag(field.Tag).Get("json"), ",")[0]
+}
+
+// messages returns protobuf messages of exported vo structs in the order of name
+func (r protoResolver) messages() []protoMessage {
+	var names []string
+	for name := range r.structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ret []protoMessage
+	for _, name := range names {
+		item := r.structs[name]
+		ret = append(ret, r.messageOf(item.Name, item.Fields, item.Comments))
+	}
+	return ret
+}
+
+// services returns protobuf services of interfaces in svc.go. Methods not supported by grpc are skipped
+func (r protoResolver) services(ic astutils.InterfaceCollector) []protoService {
+	var ret []protoService
+	for _, inter := range ic.Interfaces {
+		service := protoService{
+			Meta: inter,
+		}
+		for _, method := range inter.Methods {
+			if grpcUnsupported(method) {
+				continue
+			}
+			service.Methods = append(service.Methods, protoMethod{
+				Meta:     method,
+				Request:  r.messageOf(method.Name+"Request", rpcParams(method), nil),
+				Response: r.messageOf(method.Name+"Response", rpcResults(method), nil),
+			})
+		}
+		ret = append(ret, service)
+	}
+	return ret
+}
+
+// modNameOf returns module name in go.mod file of the service
+func modNameOf(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	firstLine, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		panic(err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+}
@@ -0,0 +1,48 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/unionj-cloud/go-doudou/astutils"
+)
+
+// IsEventStream returns true if the type is receive-only channel, e.g. <-chan vo.Event.
+// Values received from the channel are sent to clients as server-sent events
+func IsEventStream(t string) bool {
+	return strings.HasPrefix(t, "<-chan ")
+}
+
+// IsByteStream returns true if the type is io.Reader which is sent to clients as chunked response
+func IsByteStream(t string) bool {
+	return t == "io.Reader"
+}
+
+// IsStream returns true if the type is or contains streaming type, e.g. <-chan vo.Event, []io.Reader
+func IsStream(t string) bool {
+	return strings.Contains(t, "chan ") || strings.Contains(t, "io.Reader")
+}
+
+// StreamOf returns the streaming result of the method if any
+func StreamOf(method astutils.MethodMeta) *astutils.FieldMeta {
+	for _, item := range method.Results {
+		if IsStream(item.Type) {
+			return &item
+		}
+	}
+	return nil
+}
+
+// eventOf returns type of values of the receive-only channel, e.g. vo.Event for <-chan vo.Event
+func eventOf(t string) string {
+	return strings.TrimPrefix(t, "<-chan ")
+}
+
+// CtxOf returns name of context.Context parameter of the method, empty string if not found
+func CtxOf(method astutils.MethodMeta) string {
+	for _, item := range method.Params {
+		if item.Type == "context.Context" {
+			return item.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package codegen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iancoleman/strcase"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+)
+
+const streamSvc = `package service
+
+import (
+	"context"
+	"io"
+	"testfilesstream/vo"
+)
+
+type Testfilesstream interface {
+	// Watch streams events of the topic
+	// @route GET /events
+	Watch(ctx context.Context, topic string) (events <-chan vo.Event, err error)
+
+	Export(ctx context.Context) (data io.Reader, err error)
+}
+`
+
+const streamVo = `package vo
+
+type Event struct {
+	Id   int
+	Kind string
+}
+`
+
+func initStreamSvc(t *testing.T) (string, astutils.InterfaceCollector) {
+	dir := testDir + "stream"
+	InitSvc(dir)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	if err := ioutil.WriteFile(filepath.Join(dir, "svc.go"), []byte(streamSvc), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "vo", "event.go"), []byte(streamVo), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return dir, astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), ExprStringP)
+}
+
+func TestExprStringP_Stream(t *testing.T) {
+	_, ic := initStreamSvc(t)
+	methods := ic.Interfaces[0].Methods
+	if got := methods[0].Results[0].Type; got != "<-chan vo.Event" {
+		t.Errorf("want <-chan vo.Event, got %s", got)
+	}
+	if got := methods[1].Results[0].Type; got != "io.Reader" {
+		t.Errorf("want io.Reader, got %s", got)
+	}
+}
+
+func TestGenStream(t *testing.T) {
+	dir, ic := initStreamSvc(t)
+	GenHttpHandlerImplWithImpl(dir, ic, true, strcase.ToLowerCamel)
+	GenGoClient(dir, ic, "")
+
+	handlerimpl, err := ioutil.ReadFile(filepath.Join(dir, "transport", "httpsrv", "handlerimpl.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"_writer = ddhttp.NewStreamWriter(_writer, _req)\n\tddhttp.StartEventStream(_writer)",
+		"case _event, _ok := <-events:",
+		"ddhttp.WriteEvent(_writer, _event)",
+		"ddhttp.WriteStream(ddhttp.NewStreamWriter(_writer, _req), data)",
+	} {
+		if !strings.Contains(string(handlerimpl), want) {
+			t.Errorf("handlerimpl.go should contain %s", want)
+		}
+	}
+
+	client, err := ioutil.ReadFile(filepath.Join(dir, "client", "client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`_resp, _err := receiver.CallStream(receiver.provider, _req, "Watch", "GET", "/events")`,
+		"_ch := make(chan vo.Event)",
+		"ddhttp.ReadEvents(_body, func(_data []byte) bool {",
+		"case <-ctx.Done():",
+		"data = _resp.RawBody()",
+	} {
+		if !strings.Contains(string(client), want) {
+			t.Errorf("client.go should contain %s", want)
+		}
+	}
+}
+
+func TestGenDoc_Stream(t *testing.T) {
+	dir, ic := initStreamSvc(t)
+	GenDoc(dir, ic)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "testfilesstream_openapi3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var api v3.Api
+	if err = json.Unmarshal(b, &api); err != nil {
+		t.Fatal(err)
+	}
+	content := api.Paths["/events"].Get.Responses.Resp200.Content
+	if content.EventStream == nil || content.EventStream.Schema.Ref != "#/components/schemas/Event" || content.Json != nil {
+		t.Errorf("response of Watch should be text/event-stream of Event, got %+v", content)
+	}
+	content = api.Paths["/testfilesstream/export"].Post.Responses.Resp200.Content
+	if content.Stream == nil || content.Json != nil {
+		t.Errorf("response of Export should be application/octet-stream, got %+v", content)
+	}
+}
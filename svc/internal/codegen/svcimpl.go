@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -43,7 +44,36 @@ func New{{.Meta.Name}}(conf config.Config, db *sqlx.DB) {{.Meta.Name}} {
 }
 `
 
+// GenSvcImpl generates an empty implementation for each interface in svc.go if not exists,
+// otherwise merges newly added methods and changed signatures into it without touching method bodies
 func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
+	for i, meta := range ic.Interfaces {
+		svcimplfile, original, merged := mergedSvcImpl(dir, ic, meta, fileOf("svcimpl.go", i, meta))
+		if original != nil {
+			if string(original) == string(merged) {
+				continue
+			}
+			logrus.Warnf("file %s will be merged with newly generated code", svcimplfile)
+		}
+		if err := ioutil.WriteFile(svcimplfile, merged, os.ModePerm); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// DiffSvcImpl returns changes which GenSvcImpl will make in unified diff format without writing any file
+func DiffSvcImpl(dir string, ic astutils.InterfaceCollector) string {
+	var sb strings.Builder
+	for i, meta := range ic.Interfaces {
+		svcimplfile, original, merged := mergedSvcImpl(dir, ic, meta, fileOf("svcimpl.go", i, meta))
+		sb.WriteString(unifiedDiff(relOf(dir, svcimplfile), original, merged))
+	}
+	return sb.String()
+}
+
+// mergedSvcImpl returns path of service implementation file, its original content and the content merged with newly generated code.
+// Original content is nil if file not exists.
+func mergedSvcImpl(dir string, ic astutils.InterfaceCollector, meta astutils.InterfaceMeta, file string) (string, []byte, []byte) {
 	var (
 		err         error
 		modfile     string
@@ -52,46 +82,59 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		firstLine   string
 		f           *os.File
 		tpl         *template.Template
-		source      string
 		sqlBuf      bytes.Buffer
+		original    []byte
+		merged      []byte
 	)
-	svcimplfile = filepath.Join(dir, "svcimpl.go")
-	if _, err = os.Stat(svcimplfile); os.IsNotExist(err) {
-		modfile = filepath.Join(dir, "go.mod")
-		if f, err = os.Open(modfile); err != nil {
-			panic(err)
-		}
-		reader := bufio.NewReader(f)
-		if firstLine, err = reader.ReadString('\n'); err != nil {
-			panic(err)
-		}
-		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+	svcimplfile = filepath.Join(dir, file)
+	original, err = ioutil.ReadFile(svcimplfile)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
 
-		if f, err = os.Create(svcimplfile); err != nil {
-			panic(err)
-		}
-		defer f.Close()
+	modfile = filepath.Join(dir, "go.mod")
+	if f, err = os.Open(modfile); err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		panic(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-		if tpl, err = template.New("svcimpl.go.tmpl").Parse(svcimplTmpl); err != nil {
-			panic(err)
-		}
-		if err = tpl.Execute(&sqlBuf, struct {
-			ConfigPackage string
-			VoPackage     string
-			SvcPackage    string
-			Meta          astutils.InterfaceMeta
-		}{
-			VoPackage:     modName + "/vo",
-			ConfigPackage: modName + "/config",
-			SvcPackage:    ic.Package.Name,
-			Meta:          ic.Interfaces[0],
-		}); err != nil {
+	if tpl, err = template.New("svcimpl.go.tmpl").Parse(svcimplTmpl); err != nil {
+		panic(err)
+	}
+	if err = tpl.Execute(&sqlBuf, struct {
+		ConfigPackage string
+		VoPackage     string
+		SvcPackage    string
+		Meta          astutils.InterfaceMeta
+	}{
+		VoPackage:     modName + "/vo",
+		ConfigPackage: modName + "/config",
+		SvcPackage:    ic.Package.Name,
+		Meta:          meta,
+	}); err != nil {
+		panic(err)
+	}
+
+	if original == nil {
+		if merged, err = astutils.GoImports([]byte(strings.TrimSpace(sqlBuf.String())), svcimplfile); err != nil {
 			panic(err)
 		}
-
-		source = strings.TrimSpace(sqlBuf.String())
-		astutils.FixImport([]byte(source), svcimplfile)
-	} else {
-		logrus.Warnf("file %s already exists.", svcimplfile)
+		return svcimplfile, original, merged
+	}
+	if merged, err = mergeSvcImpl(svcimplfile, original, sqlBuf.Bytes(), meta.Name+"Impl"); err != nil {
+		panic(err)
+	}
+	if string(merged) == string(original) {
+		// keep user code as it is if nothing changed
+		return svcimplfile, original, original
+	}
+	if merged, err = astutils.GoImports(merged, svcimplfile); err != nil {
+		panic(err)
 	}
+	return svcimplfile, original, merged
 }
@@ -0,0 +1,3 @@
+module testgrpc
+
+go 1.15
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"mime/multipart"
+	"testgrpc/vo"
+)
+
+// Ordersvc is order service
+type Ordersvc interface {
+	// CreateOrder creates order
+	// @validate order required
+	CreateOrder(ctx context.Context, order vo.Order) (id int64, err error)
+
+	ListOrders(ctx context.Context, status *vo.Status, tags []string) (orders []*vo.Order, total int, err error)
+
+	Upload(ctx context.Context, file *multipart.FileHeader) (err error)
+}
+
+// Ordersvcadmin is admin api of order service
+type Ordersvcadmin interface {
+	Stat(ctx context.Context) (stat map[string]vo.Item, err error)
+}
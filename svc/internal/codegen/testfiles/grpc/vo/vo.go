@@ -0,0 +1,26 @@
+package vo
+
+type Status int
+
+type Base struct {
+	CreatedBy string `json:"createdBy"`
+}
+
+// Order is order
+type Order struct {
+	Base
+	// Id is order id
+	Id       int64            `json:"id"`
+	Status   Status           `json:"status"`
+	Remark   *string          `json:"remark"`
+	Items    []Item           `json:"items"`
+	Extra    map[string]*Item `json:"extra"`
+	Matrix   [][]int          `json:"matrix"`
+	Data     interface{}      `json:"data"`
+	Internal string           `json:"-"`
+}
+
+type Item struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
@@ -21,6 +21,8 @@ func (e eventDelegate) NotifyJoin(node *memberlist.Node) {
 		return
 	}
 	e.local.registry.members = append(e.local.registry.members, node)
+	memberCount.WithLabelValues(mm.Meta.Service).Inc()
+	memberEvents.WithLabelValues(mm.Meta.Service, "join").Inc()
 	logrus.Infof("Node %s joined, supplying %s service", node.String(), mm.Meta.Service)
 }
 
@@ -35,6 +37,8 @@ func (e eventDelegate) NotifyLeave(node *memberlist.Node) {
 	}
 	index, _ := sliceutils.IndexOfAny(node, e.local.registry.members)
 	e.local.registry.members = append(e.local.registry.members[:index], e.local.registry.members[index+1:]...)
+	memberCount.WithLabelValues(mm.Meta.Service).Dec()
+	memberEvents.WithLabelValues(mm.Meta.Service, "leave").Inc()
 	logrus.Infof("Node %s left, supplying %s service", node.FullAddress(), mm.Meta.Service)
 }
 
@@ -49,5 +53,6 @@ func (e eventDelegate) NotifyUpdate(node *memberlist.Node) {
 	}
 	index, _ := sliceutils.IndexOfAny(node, e.local.registry.members)
 	e.local.registry.members[index] = node
+	memberEvents.WithLabelValues(mm.Meta.Service, "update").Inc()
 	logrus.Infof("Node %s updated, supplying %s service", node.FullAddress(), mm.Meta.Service)
 }
@@ -0,0 +1,16 @@
+package registry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var memberCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "registry_members",
+	Help: "Number of members in the cluster by service, including the local node.",
+}, []string{"service"})
+
+var memberEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "registry_member_events_total",
+	Help: "Number of join, leave and update events of members by service.",
+}, []string{"service", "event"})
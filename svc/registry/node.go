@@ -9,8 +9,11 @@ import (
 	"github.com/unionj-cloud/go-doudou/cast"
 	"github.com/unionj-cloud/go-doudou/stringutils"
 	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/tlsutils"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type IRegistry interface {
@@ -45,6 +48,27 @@ func (r *registry) Register() error {
 }
 
 func (r *registry) Discover(svc string) ([]*Node, error) {
+	return r.discover(func(service string) bool {
+		return service == svc
+	})
+}
+
+// Services returns base urls of alive nodes of each service in the cluster
+func (r *registry) Services() (map[string][]string, error) {
+	nodes, err := r.discover(func(service string) bool {
+		return stringutils.IsNotEmpty(service)
+	})
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string][]string)
+	for _, node := range nodes {
+		services[node.mmeta.Meta.Service] = append(services[node.mmeta.Meta.Service], node.BaseUrl())
+	}
+	return services, nil
+}
+
+func (r *registry) discover(match func(service string) bool) ([]*Node, error) {
 	if r.memberlist == nil {
 		return nil, errors.New("Memberlist is nil")
 	}
@@ -56,10 +80,10 @@ func (r *registry) Discover(svc string) ([]*Node, error) {
 			if err := json.Unmarshal(member.Meta, &mmeta); err != nil {
 				return nil, errors.Wrap(err, "")
 			}
-			if mmeta.Meta.Service == svc {
+			if match(mmeta.Meta.Service) {
 				nodes = append(nodes, &Node{
 					mmeta:      mmeta,
-					state:      Alive,
+					state:      int32(Alive),
 					memberNode: member,
 					remote:     true,
 				})
@@ -74,6 +98,9 @@ type nodeMeta struct {
 	BaseUrl string `json:"baseUrl"`
 	Port    int    `json:"port"`
 	Host    string `json:"host"`
+	Weight  int    `json:"weight,omitempty"`
+	// Scheme is 'https' if the node serves https, empty for 'http'
+	Scheme string `json:"scheme,omitempty"`
 }
 
 func newMeta(mnode *memberlist.Node) (mergedMeta, error) {
@@ -114,8 +141,9 @@ type mergedMeta struct {
 }
 
 type Node struct {
-	mmeta      mergedMeta
-	state      NodeState
+	mmeta mergedMeta
+	// state is NodeState accessed atomically, as it is written by Leave during shutdown while read by readiness probes
+	state      int32
 	memberNode *memberlist.Node
 	*registry
 	// check the node is a local node or remote node
@@ -184,6 +212,10 @@ func NewNode(opts ...NodeOption) (*Node, error) {
 		Service: service,
 		Port:    port,
 		BaseUrl: baseUrl,
+		Weight:  cast.ToInt(config.GddWeight.Load()),
+	}
+	if tlsutils.Enabled() {
+		node.mmeta.Meta.Scheme = "https"
 	}
 	mconf.Delegate = &delegate{node}
 	mconf.Events = &eventDelegate{node}
@@ -200,7 +232,7 @@ func NewNode(opts ...NodeOption) (*Node, error) {
 		node.registry.memberlist.Shutdown()
 		return nil, errors.Wrap(err, "NewNode() error: Node register failed")
 	}
-	node.state = Alive
+	node.setState(Alive)
 	node.memberNode = list.LocalNode()
 	return node, nil
 }
@@ -213,11 +245,48 @@ func (n *Node) NumNodes() (numNodes int) {
 	return numNodes
 }
 
+// BaseUrl returns GDD_BASE_URL of the node if set, otherwise url made of the scheme, address and service port of the node
 func (n *Node) BaseUrl() string {
 	if stringutils.IsNotEmpty(n.mmeta.Meta.BaseUrl) {
 		return n.mmeta.Meta.BaseUrl
 	}
-	return fmt.Sprintf("http://%s:%d", n.memberNode.Addr.String(), n.mmeta.Meta.Port)
+	scheme := n.mmeta.Meta.Scheme
+	if stringutils.IsEmpty(scheme) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, n.memberNode.Addr.String(), n.mmeta.Meta.Port)
+}
+
+// Weight returns weight of the node set by GDD_WEIGHT environment variable, 1 by default
+func (n *Node) Weight() int {
+	if n.mmeta.Meta.Weight > 0 {
+		return n.mmeta.Meta.Weight
+	}
+	return 1
+}
+
+// Leave broadcasts that the node is leaving the cluster and waits at most timeout for the broadcast,
+// then shuts down memberlist, so that other nodes stop sending requests to it at once instead of detecting its failure later
+func (n *Node) Leave(timeout time.Duration) error {
+	n.setState(Leaving)
+	if err := n.memberlist.Leave(timeout); err != nil {
+		return errors.Wrap(err, "Leave() error: Failed to broadcast leaving")
+	}
+	n.setState(Left)
+	if err := n.memberlist.Shutdown(); err != nil {
+		return errors.Wrap(err, "Leave() error: Failed to shutdown memberlist")
+	}
+	n.setState(Shutdown)
+	return nil
+}
+
+// State returns state of the node, it is Alive once NewNode returns
+func (n *Node) State() NodeState {
+	return NodeState(atomic.LoadInt32(&n.state))
+}
+
+func (n *Node) setState(state NodeState) {
+	atomic.StoreInt32(&n.state, int32(state))
 }
 
 func (n *Node) String() string {
@@ -13,8 +13,11 @@ import (
 	"github.com/unionj-cloud/go-doudou/esutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
 	"github.com/unionj-cloud/go-doudou/openapi/v3/codegen/client"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
 	"github.com/unionj-cloud/go-doudou/svc/internal/codegen"
+	"github.com/unionj-cloud/go-doudou/validate"
+	"go/ast"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -27,6 +30,7 @@ import (
 type SvcCmd interface {
 	Init()
 	Http()
+	Grpc()
 }
 
 type Svc struct {
@@ -48,6 +52,9 @@ type Svc struct {
 
 	Watch bool
 
+	// DryRun prints changes to handlerimpl.go and svcimpl.go files as unified diff instead of generating code
+	DryRun bool
+
 	*exec.Cmd
 	RestartSig chan int
 }
@@ -61,7 +68,40 @@ func validateDataType(dir string) {
 		logrus.Panicln(err)
 	}
 	for _, file := range files {
-		astutils.BuildStructCollector(file, codegen.ExprStringP)
+		sc := astutils.BuildStructCollector(file, codegen.ExprStringP)
+		for _, structmeta := range sc.Structs {
+			for _, field := range structmeta.Fields {
+				if codegen.IsStream(field.Type) {
+					logrus.Panicf("not support %s as type of field %s of struct %s", field.Type, field.Name, structmeta.Name)
+				}
+				if _, err := validate.RulesOf(field.Tag); err != nil {
+					logrus.Panicf("validation rules of field %s of struct %s: %s", field.Name, structmeta.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// buildInterfaceCollector collects exported interfaces from svc.go. The first one is the main service interface
+// whose name is used as service name, the others are served by the same service with their own handlers, routes and clients
+func buildInterfaceCollector(dir string) astutils.InterfaceCollector {
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	var exported []astutils.InterfaceMeta
+	for _, item := range ic.Interfaces {
+		if ast.IsExported(item.Name) {
+			exported = append(exported, item)
+		}
+	}
+	ic.Interfaces = exported
+	return ic
+}
+
+func caseconvertorOf(jsonattrcase string) func(string) string {
+	switch jsonattrcase {
+	case "snake":
+		return strcase.ToSnake
+	default:
+		return strcase.ToLowerCamel
 	}
 }
 
@@ -71,9 +111,23 @@ func (receiver Svc) Http() {
 		validateDataType(dir)
 	}
 
-	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(dir)
 	validateRestApi(ic)
 
+	if receiver.DryRun {
+		var diff string
+		if receiver.Handler {
+			diff += codegen.DiffHttpHandlerImplWithImpl(dir, ic, receiver.Omitempty, caseconvertorOf(receiver.Jsonattrcase))
+		}
+		diff += codegen.DiffSvcImpl(dir, ic)
+		if stringutils.IsEmpty(diff) {
+			logrus.Infoln("no change")
+			return
+		}
+		fmt.Print(diff)
+		return
+	}
+
 	codegen.GenConfig(dir)
 	codegen.GenDotenv(dir)
 	codegen.GenDb(dir)
@@ -82,14 +136,7 @@ func (receiver Svc) Http() {
 	codegen.GenMain(dir, ic)
 	codegen.GenHttpHandler(dir, ic)
 	if receiver.Handler {
-		var caseconvertor func(string) string
-		switch receiver.Jsonattrcase {
-		case "snake":
-			caseconvertor = strcase.ToSnake
-		default:
-			caseconvertor = strcase.ToLowerCamel
-		}
-		codegen.GenHttpHandlerImplWithImpl(dir, ic, receiver.Omitempty, caseconvertor)
+		codegen.GenHttpHandlerImplWithImpl(dir, ic, receiver.Omitempty, caseconvertorOf(receiver.Jsonattrcase))
 	} else {
 		codegen.GenHttpHandlerImpl(dir, ic)
 	}
@@ -111,11 +158,22 @@ func (receiver Svc) Http() {
 // If there are *multipart.FileHeader parameters, go-doudou will assume you want a multipart/form-data api
 // Support struct, map[string]ANY, built-in type and corresponding slice only
 // Not not support anonymous struct as parameter
+// Parameters bound by @path, @header or @cookie annotation must be built-in type and not slice
+// Validation rules declared by @validate annotation must be valid and declared at most once for each parameter
+// Methods declared public by @public annotation must not be restricted to roles by @role annotation
+// Sizes declared by @maxbody annotation must be positive like 100MB
+// All exported interfaces in svc.go are checked, and routes of them must not conflict with each other
 func validateRestApi(ic astutils.InterfaceCollector) {
 	if len(ic.Interfaces) == 0 {
 		panic(errors.New("no service interface found"))
 	}
-	svcInter := ic.Interfaces[0]
+	validateRoutes(ic)
+	for _, svcInter := range ic.Interfaces {
+		validateMethods(svcInter)
+	}
+}
+
+func validateMethods(svcInter astutils.InterfaceMeta) {
 	re := regexp.MustCompile(`anonystruct«(.*)»`)
 	for _, method := range svcInter.Methods {
 		// Append *multipart.FileHeader value to nonBasicTypes only once at most as multipart/form-data support multiple fields as file type
@@ -153,11 +211,216 @@ func validateRestApi(ic astutils.InterfaceCollector) {
 		if len(nonBasicTypes) > 1 {
 			panic("Too many golang non-built-in type parameters, can't decide which one should be put into request body!")
 		}
+		bound := codegen.PathVars(method)
+		for _, item := range codegen.HeaderVars(method) {
+			bound = append(bound, item.Param)
+		}
+		for _, item := range codegen.CookieVars(method) {
+			bound = append(bound, item.Param)
+		}
+		bindmap := make(map[string]int)
+		for _, name := range bound {
+			if _, exists := bindmap[name]; exists {
+				panic(fmt.Sprintf("parameter %s of method %s is bound more than once", name, method.Name))
+			}
+			bindmap[name]++
+			var found bool
+			for _, param := range method.Params {
+				if param.Name != name {
+					continue
+				}
+				found = true
+				if !v3.IsBuiltin(param) || strings.HasPrefix(param.Type, "[") || strings.HasPrefix(param.Type, "*[") {
+					panic(fmt.Sprintf("parameter %s of method %s bound by annotation must be built-in type and not slice", name, method.Name))
+				}
+			}
+			if !found {
+				panic(fmt.Sprintf("parameter %s bound by annotation not found in method %s", name, method.Name))
+			}
+		}
+		rulemap := make(map[string]int)
+		for _, item := range codegen.ValidateVars(method) {
+			if _, exists := rulemap[item.Param]; exists {
+				panic(fmt.Sprintf("parameter %s of method %s has more than one @validate annotation", item.Param, method.Name))
+			}
+			rulemap[item.Param]++
+			var found bool
+			for _, param := range method.Params {
+				if param.Name == item.Param {
+					found = true
+					break
+				}
+			}
+			if !found {
+				panic(fmt.Sprintf("parameter %s declared by @validate annotation not found in method %s", item.Param, method.Name))
+			}
+			if _, err := validate.ParseRules(item.Key); err != nil {
+				panic(fmt.Sprintf("validation rules of parameter %s of method %s: %s", item.Param, method.Name, err))
+			}
+		}
+		if codegen.IsPublic(method) && len(codegen.RolesOf(method)) > 0 {
+			panic(fmt.Sprintf("method %s cannot be both @public and restricted to roles by @role annotation", method.Name))
+		}
+		if codegen.IsCached(method) {
+			if codegen.HttpMethodOf(method) != "GET" {
+				panic(fmt.Sprintf("method %s is not GET method, only responses of GET methods can be cached by @cache annotation", method.Name))
+			}
+			if stringutils.IsEmpty(codegen.CacheControlOf(method)) {
+				panic(fmt.Sprintf("@cache annotation of method %s requires Cache-Control directives, e.g. @cache max-age=60", method.Name))
+			}
+		}
+		if codegen.IsIdempotent(method) && codegen.HttpMethodOf(method) == "GET" {
+			panic(fmt.Sprintf("method %s is GET method which is idempotent already, @idempotent annotation is for POST, PUT, PATCH and DELETE methods", method.Name))
+		}
+		if _, err := codegen.MaxBodyOf(method); err != nil {
+			panic(err.Error())
+		}
 		for _, param := range method.Results {
 			if re.MatchString(param.Type) {
 				panic("not support anonymous struct as parameter")
 			}
 		}
+		validateStream(method)
+	}
+}
+
+// validateStream checks that streaming types are only used as the first one of two results of the method,
+// and the other result is error, e.g. (<-chan vo.Event, error) or (io.Reader, error). Methods returning channels
+// must accept context.Context, by which callers of generated clients stop receiving events
+func validateStream(method astutils.MethodMeta) {
+	for _, param := range method.Params {
+		if codegen.IsStream(param.Type) {
+			panic(fmt.Sprintf("not support %s as parameter of method %s", param.Type, method.Name))
+		}
+	}
+	stream := codegen.StreamOf(method)
+	if stream == nil {
+		return
+	}
+	if !codegen.IsEventStream(stream.Type) && !codegen.IsByteStream(stream.Type) {
+		panic(fmt.Sprintf("not support %s as result of method %s", stream.Type, method.Name))
+	}
+	if codegen.IsEventStream(stream.Type) && codegen.IsStream(strings.TrimPrefix(stream.Type, "<-chan ")) {
+		panic(fmt.Sprintf("not support %s as result of method %s", stream.Type, method.Name))
+	}
+	if len(method.Results) != 2 || method.Results[0].Type != stream.Type || method.Results[1].Type != "error" {
+		panic(fmt.Sprintf("method %s returning %s must return (%s, error)", method.Name, stream.Type, stream.Type))
+	}
+	if codegen.IsEventStream(stream.Type) && stringutils.IsEmpty(codegen.CtxOf(method)) {
+		panic(fmt.Sprintf("method %s returning %s must accept context.Context as parameter", method.Name, stream.Type))
+	}
+}
+
+var httpMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
+
+// validateRoutes checks @route annotations and makes sure that no two methods of all interfaces in svc.go
+// are routed to the same http method and path, or share the same route name with different route options
+func validateRoutes(ic astutils.InterfaceCollector) {
+	pathVarRe := regexp.MustCompile(`{[^{}]*}`)
+	routemap := make(map[string]string)
+	namemap := make(map[string]namedMethod)
+	for _, svcInter := range ic.Interfaces {
+		for _, method := range svcInter.Methods {
+			for _, item := range method.Annotations {
+				if item.Name != astutils.AnnotationRoute {
+					continue
+				}
+				if len(item.Params) != 2 {
+					panic(fmt.Sprintf("@route annotation of method %s should be like @route PATCH /orders/{id}", method.Name))
+				}
+				if !sliceutils.StringContains(httpMethods, strings.ToUpper(item.Params[0])) {
+					panic(fmt.Sprintf("http method %s of method %s not support", item.Params[0], method.Name))
+				}
+				if !strings.HasPrefix(item.Params[1], "/") {
+					panic(fmt.Sprintf("path %s of method %s should start with /", item.Params[1], method.Name))
+				}
+			}
+			qualified := svcInter.Name + "." + method.Name
+			// route options generated from annotations are keyed by route name, so methods sharing
+			// the same route name, e.g. GetUser and DeleteUser, must not declare different ones
+			name := codegen.RouteNameOf(method)
+			if existing, exists := namemap[name]; exists && routeOptionsOf(existing.method) != routeOptionsOf(method) {
+				panic(fmt.Sprintf("method %s and %s have the same route name %s but different @public, @role, @cache, @idempotent or @maxbody annotations",
+					existing.qualified, qualified, name))
+			} else if !exists {
+				namemap[name] = namedMethod{qualified, method}
+			}
+			// path variables with different names in the same position still conflict with each other
+			route := codegen.HttpMethodOf(method) + " " + pathVarRe.ReplaceAllString(codegen.Endpoint(svcInter.Name, method), "{}")
+			if existing, exists := routemap[route]; exists {
+				panic(fmt.Sprintf("method %s and %s are routed to the same http method and path %s", existing, qualified, route))
+			}
+			routemap[route] = qualified
+		}
+	}
+}
+
+type namedMethod struct {
+	qualified string
+	method    astutils.MethodMeta
+}
+
+// routeOptionsOf returns route options declared by annotations of method in comparable form
+func routeOptionsOf(method astutils.MethodMeta) string {
+	maxBody, _ := codegen.MaxBodyOf(method)
+	return fmt.Sprint(codegen.IsPublic(method), codegen.RolesOf(method), codegen.CacheControlOf(method),
+		codegen.IsIdempotent(method), maxBody)
+}
+
+// Grpc generates .proto file, grpc server adapters and go grpc clients from all interfaces in svc.go and vo package.
+// Go code of protobuf messages and grpc services is generated by protoc if it is found in PATH
+func (receiver Svc) Grpc() {
+	dir := receiver.Dir
+	validateDataType(dir)
+	ic := buildInterfaceCollector(dir)
+	validateGrpcApi(dir, ic)
+
+	codegen.GenGrpcProto(dir, ic)
+	codegen.GenGrpcConvert(dir, ic)
+	codegen.GenGrpcServer(dir, ic)
+	codegen.GenGrpcClient(dir, ic)
+
+	if _, err := exec.LookPath("protoc"); err != nil {
+		logrus.Warnln("protoc not found in PATH. Please install protoc, protoc-gen-go and protoc-gen-go-grpc, then run 'go generate ./transport/grpcsrv/pb'")
+		return
+	}
+	cmd := exec.Command("go", "generate", "./transport/grpcsrv/pb")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		panic(err)
+	}
+}
+
+// validateGrpcApi checks that the last result of each method is error as grpc call may fail,
+// and names of exported structs in vo package don't conflict with generated protobuf services and messages
+func validateGrpcApi(dir string, ic astutils.InterfaceCollector) {
+	if len(ic.Interfaces) == 0 {
+		panic(errors.New("no service interface found"))
+	}
+	names := make(map[string]string)
+	for _, svcInter := range ic.Interfaces {
+		names[svcInter.Name] = "service " + svcInter.Name
+		for _, method := range svcInter.Methods {
+			if len(method.Results) == 0 || method.Results[len(method.Results)-1].Type != "error" {
+				panic(fmt.Sprintf("the last result of method %s should be error", method.Name))
+			}
+			names[method.Name+"Request"] = "request message of method " + method.Name
+			names[method.Name+"Response"] = "response message of method " + method.Name
+		}
+	}
+	var files []string
+	if err := filepath.Walk(filepath.Join(dir, "vo"), astutils.Visit(&files)); err != nil {
+		logrus.Panicln(err)
+	}
+	for _, file := range files {
+		sc := astutils.BuildStructCollector(file, codegen.ExprStringP)
+		for _, structmeta := range sc.Structs {
+			if existing, exists := names[structmeta.Name]; exists && structmeta.IsExport {
+				panic(fmt.Sprintf("struct %s in vo package conflicts with %s in .proto file", structmeta.Name, existing))
+			}
+		}
 	}
 }
 
@@ -166,7 +429,7 @@ func (receiver Svc) Init() {
 }
 
 func (receiver Svc) Push() {
-	ic := astutils.BuildInterfaceCollector(filepath.Join(receiver.Dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(receiver.Dir)
 	validateRestApi(ic)
 	svcname := strings.ToLower(ic.Interfaces[0].Name)
 
@@ -202,7 +465,7 @@ func (receiver Svc) Push() {
 }
 
 func (receiver Svc) Deploy() {
-	ic := astutils.BuildInterfaceCollector(filepath.Join(receiver.Dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(receiver.Dir)
 	validateRestApi(ic)
 	svcname := strings.ToLower(ic.Interfaces[0].Name)
 	k8sfile := receiver.K8sfile
@@ -218,7 +481,7 @@ func (receiver Svc) Deploy() {
 }
 
 func (receiver Svc) Shutdown() {
-	ic := astutils.BuildInterfaceCollector(filepath.Join(receiver.Dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(receiver.Dir)
 	validateRestApi(ic)
 	svcname := strings.ToLower(ic.Interfaces[0].Name)
 	k8sfile := receiver.K8sfile
@@ -234,7 +497,7 @@ func (receiver Svc) Shutdown() {
 }
 
 func (receiver Svc) Scale() {
-	ic := astutils.BuildInterfaceCollector(filepath.Join(receiver.Dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(receiver.Dir)
 	validateRestApi(ic)
 	svcname := strings.ToLower(ic.Interfaces[0].Name)
 	cmd := exec.Command("kubectl", "scale", fmt.Sprintf("--replicas=%d", receiver.N), fmt.Sprintf("deployment/%s-deployment", svcname))
@@ -246,7 +509,7 @@ func (receiver Svc) Scale() {
 }
 
 func (receiver Svc) Publish() string {
-	ic := astutils.BuildInterfaceCollector(filepath.Join(receiver.Dir, "svc.go"), astutils.ExprString)
+	ic := buildInterfaceCollector(receiver.Dir)
 	validateRestApi(ic)
 	svcname := strings.ToLower(ic.Interfaces[0].Name)
 	docpath := receiver.DocPath
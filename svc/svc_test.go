@@ -155,6 +155,111 @@ func Test_checkIc1(t *testing.T) {
 	}
 }
 
+func Test_validateRoutes(t *testing.T) {
+	svcfile := testDir + "/svcroute.go"
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	assert.Panics(t, func() {
+		validateRoutes(ic)
+	})
+}
+
+func Test_validateRoutesMultiInterfaces(t *testing.T) {
+	svcfile := testDir + "/svcmulti.go"
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	assert.Panics(t, func() {
+		validateRoutes(ic)
+	})
+}
+
+func Test_validateRoutesName(t *testing.T) {
+	ic := astutils.InterfaceCollector{Interfaces: []astutils.InterfaceMeta{{Name: "Usersvc", Methods: []astutils.MethodMeta{
+		{Name: "GetUser", Annotations: astutils.NewAnnotations([]string{"@role admin"})},
+		{Name: "DeleteUser", Annotations: astutils.NewAnnotations([]string{"@role admin"})},
+		{Name: "PageUsers"},
+	}}}}
+	assert.NotPanics(t, func() {
+		validateRoutes(ic)
+	})
+	ic.Interfaces[0].Methods[1].Annotations = astutils.NewAnnotations([]string{"@role admin", "@role auditor"})
+	assert.Panics(t, func() {
+		validateRoutes(ic)
+	})
+}
+
+func Test_validateMethodsAuth(t *testing.T) {
+	method := astutils.MethodMeta{
+		Name:        "DeleteUser",
+		Annotations: astutils.NewAnnotations([]string{"@public", "@role admin"}),
+	}
+	assert.Panics(t, func() {
+		validateMethods(astutils.InterfaceMeta{Name: "Usersvc", Methods: []astutils.MethodMeta{method}})
+	})
+}
+
+func Test_validateMethodsCache(t *testing.T) {
+	valid := astutils.MethodMeta{
+		Name:        "GetUser",
+		Annotations: astutils.NewAnnotations([]string{"@cache public max-age=60"}),
+	}
+	assert.NotPanics(t, func() {
+		validateMethods(astutils.InterfaceMeta{Name: "Usersvc", Methods: []astutils.MethodMeta{valid}})
+	})
+	invalid := []astutils.MethodMeta{
+		{Name: "PageUsers", Annotations: astutils.NewAnnotations([]string{"@cache max-age=60"})},
+		{Name: "GetUser", Annotations: astutils.NewAnnotations([]string{"@cache"})},
+	}
+	for _, item := range invalid {
+		method := item
+		assert.Panics(t, func() {
+			validateMethods(astutils.InterfaceMeta{Name: "Usersvc", Methods: []astutils.MethodMeta{method}})
+		}, method.Name)
+	}
+}
+
+func Test_validateMethodsIdempotent(t *testing.T) {
+	assert.NotPanics(t, func() {
+		validateMethods(astutils.InterfaceMeta{Name: "Usersvc", Methods: []astutils.MethodMeta{
+			{Name: "CreateOrder", Annotations: astutils.NewAnnotations([]string{"@idempotent"})},
+		}})
+	})
+	assert.Panics(t, func() {
+		validateMethods(astutils.InterfaceMeta{Name: "Usersvc", Methods: []astutils.MethodMeta{
+			{Name: "GetOrder", Annotations: astutils.NewAnnotations([]string{"@idempotent"})},
+		}})
+	})
+}
+
+func Test_validateStream(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	errResult := astutils.FieldMeta{Name: "err", Type: "error"}
+	valid := []astutils.MethodMeta{
+		{Name: "Watch", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "events", Type: "<-chan vo.Event"}, errResult}},
+		{Name: "Export", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "data", Type: "io.Reader"}, errResult}},
+		{Name: "GetUser", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "user", Type: "vo.UserVo"}, errResult}},
+	}
+	for _, item := range valid {
+		method := item
+		assert.NotPanics(t, func() {
+			validateStream(method)
+		}, method.Name)
+	}
+	invalid := []astutils.MethodMeta{
+		{Name: "Publish", Params: []astutils.FieldMeta{ctx, {Name: "events", Type: "<-chan vo.Event"}}, Results: []astutils.FieldMeta{errResult}},
+		{Name: "Upload", Params: []astutils.FieldMeta{ctx, {Name: "data", Type: "io.Reader"}}, Results: []astutils.FieldMeta{errResult}},
+		{Name: "WatchWithCode", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "code", Type: "int"}, {Name: "events", Type: "<-chan vo.Event"}, errResult}},
+		{Name: "WatchWithoutError", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "events", Type: "<-chan vo.Event"}}},
+		{Name: "ExportAll", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "data", Type: "[]io.Reader"}, errResult}},
+		{Name: "WatchNested", Params: []astutils.FieldMeta{ctx}, Results: []astutils.FieldMeta{{Name: "events", Type: "<-chan <-chan int"}, errResult}},
+		{Name: "WatchWithoutCtx", Results: []astutils.FieldMeta{{Name: "events", Type: "<-chan vo.Event"}, errResult}},
+	}
+	for _, item := range invalid {
+		method := item
+		assert.Panics(t, func() {
+			validateStream(method)
+		}, method.Name)
+	}
+}
+
 func TestSvc_Publish(t *testing.T) {
 	terminator, host, port := test.PrepareTestEnvironment()
 	defer terminator()
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+)
+
+type Shopsvc interface {
+	GetOrder(ctx context.Context, id int) (code int, msg error)
+}
+
+type Shopadminsvc interface {
+	// @route GET /admin/orders
+	// @role admin
+	GetOrder(ctx context.Context, id int) (code int, msg error)
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+)
+
+type Ordersvc interface {
+	// @route PATCH /orders/{id}
+	UpdateOrder(ctx context.Context, id int, status string) (code int, msg error)
+
+	// @route PATCH /orders/{orderId}
+	PatchOrder(ctx context.Context, orderId int, remark string) (code int, msg error)
+}
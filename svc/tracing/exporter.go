@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Exporter sends ended spans to tracing backends. Export is called synchronously when spans end,
+// so implementations sending spans over network should buffer them and send in background
+type Exporter interface {
+	Export(span SpanData)
+}
+
+// WriterExporter writes each span as a json line to the writer
+type WriterExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewWriterExporter creates WriterExporter, e.g. NewWriterExporter(os.Stdout)
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (e *WriterExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.encoder.Encode(span); err != nil {
+		logrus.Errorf("export span %s failed: %v", span.SpanID, err)
+	}
+}
+
+// FileExporter appends spans as json lines to a file
+type FileExporter struct {
+	*WriterExporter
+	file *os.File
+}
+
+// NewFileExporter creates FileExporter writing to the file, which is created if not exists
+func NewFileExporter(file string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "NewFileExporter() fail")
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFileExporter() fail")
+	}
+	return &FileExporter{
+		WriterExporter: NewWriterExporter(f),
+		file:           f,
+	}, nil
+}
+
+func (e *FileExporter) Close() error {
+	return e.file.Close()
+}
@@ -0,0 +1,289 @@
+// Package tracing implements distributed tracing with a span model compatible with OpenTelemetry
+// and W3C trace context propagation (https://www.w3.org/TR/trace-context/)
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TraceparentHeader is the http header carrying SpanContext across services
+const TraceparentHeader = "traceparent"
+
+// TracestateHeader is the http header carrying vendor specific trace data, which is passed through as is
+const TracestateHeader = "tracestate"
+
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext identifies a span in a trace
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats sc as value of traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses value of traceparent header
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+	// version-traceid-spanid-flags, future versions may append fields
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' ||
+		(len(traceparent) > 55 && traceparent[55] != '-') {
+		return sc, errors.Errorf("invalid traceparent %q", traceparent)
+	}
+	version, err := hex.DecodeString(traceparent[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(traceparent) != 55) {
+		return sc, errors.Errorf("invalid version of traceparent %q", traceparent)
+	}
+	if _, err = hex.Decode(sc.TraceID[:], []byte(traceparent[3:35])); err != nil {
+		return sc, errors.Wrapf(err, "invalid trace id of traceparent %q", traceparent)
+	}
+	if _, err = hex.Decode(sc.SpanID[:], []byte(traceparent[36:52])); err != nil {
+		return sc, errors.Wrapf(err, "invalid span id of traceparent %q", traceparent)
+	}
+	flags, err := hex.DecodeString(traceparent[53:55])
+	if err != nil {
+		return sc, errors.Wrapf(err, "invalid flags of traceparent %q", traceparent)
+	}
+	if !sc.IsValid() {
+		return sc, errors.Errorf("all zero trace id or span id of traceparent %q", traceparent)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+type SpanKind string
+
+const (
+	SpanKindInternal SpanKind = "internal"
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+)
+
+type StatusCode string
+
+const (
+	StatusUnset StatusCode = "unset"
+	StatusOk    StatusCode = "ok"
+	StatusError StatusCode = "error"
+)
+
+// SpanData is the snapshot of an ended span passed to Exporter
+type SpanData struct {
+	TraceID           string                 `json:"traceId"`
+	SpanID            string                 `json:"spanId"`
+	ParentSpanID      string                 `json:"parentSpanId,omitempty"`
+	Name              string                 `json:"name"`
+	Kind              SpanKind               `json:"kind"`
+	StartTime         time.Time              `json:"startTime"`
+	EndTime           time.Time              `json:"endTime"`
+	Attributes        map[string]interface{} `json:"attributes,omitempty"`
+	StatusCode        StatusCode             `json:"statusCode"`
+	StatusDescription string                 `json:"statusDescription,omitempty"`
+}
+
+// Span is a timed operation in a trace. It is safe for concurrent use
+type Span struct {
+	mu         sync.Mutex
+	tracer     *Tracer
+	sc         SpanContext
+	parent     SpanID
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	status     StatusCode
+	statusDesc string
+}
+
+// Context returns SpanContext of the span, which is propagated to downstream services
+func (s *Span) Context() SpanContext {
+	return s.sc
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+func (s *Span) SetStatus(code StatusCode, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.statusDesc = description
+}
+
+// End ends the span and exports it if sampled, calls after the first one are ignored
+func (s *Span) End() {
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	data := s.data()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+func (s *Span) data() SpanData {
+	data := SpanData{
+		TraceID:           s.sc.TraceID.String(),
+		SpanID:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTime:         s.start,
+		EndTime:           s.end,
+		StatusCode:        s.status,
+		StatusDescription: s.statusDesc,
+	}
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	if len(s.attributes) > 0 {
+		data.Attributes = make(map[string]interface{}, len(s.attributes))
+		for k, v := range s.attributes {
+			data.Attributes[k] = v
+		}
+	}
+	return data
+}
+
+type spanCtx struct{}
+
+type remoteCtx struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanCtx{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanCtx{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx carrying sc extracted from the upstream service,
+// spans started from the ctx are children of it
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteCtx{}, sc)
+}
+
+// SpanContextFromContext returns SpanContext of the span carried by ctx, or the remote one if no span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.Context()
+	}
+	sc, _ := ctx.Value(remoteCtx{}).(SpanContext)
+	return sc
+}
+
+// Tracer starts spans and exports them by its Exporter
+type Tracer struct {
+	mu       sync.RWMutex
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// SetExporter replaces exporter of the tracer, nil disables exporting
+func (t *Tracer) SetExporter(exporter Exporter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exporter = exporter
+}
+
+func (t *Tracer) export(data SpanData) {
+	t.mu.RLock()
+	exporter := t.exporter
+	t.mu.RUnlock()
+	if exporter != nil {
+		exporter.Export(data)
+	}
+}
+
+// Start starts a span which is a child of the span or remote SpanContext carried by ctx,
+// or the root of a new trace if there is none. The returned ctx carries the new span
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		status: StatusUnset,
+	}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.sc = parent
+		span.parent = parent.SpanID
+	} else {
+		span.sc.Sampled = true
+		rand.Read(span.sc.TraceID[:])
+	}
+	rand.Read(span.sc.SpanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+var defaultTracer = NewTracer(nil)
+
+// DefaultTracer returns the tracer used by ddhttp.Tracing middleware and generated clients
+func DefaultTracer() *Tracer {
+	return defaultTracer
+}
+
+// Start starts a span by DefaultTracer
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return defaultTracer.Start(ctx, name, kind)
+}
+
+// SetExporter sets exporter of DefaultTracer
+func SetExporter(exporter Exporter) {
+	defaultTracer.SetExporter(exporter)
+}
@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	spans []SpanData
+}
+
+func (r *recorder) Export(span SpanData) {
+	r.spans = append(r.spans, span)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		wantErr     bool
+		wantSampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", true, false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", true, false},
+		{"", true, false},
+	}
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.traceparent)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTraceparent(%q) error = %v, wantErr %v", tt.traceparent, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sc.Sampled != tt.wantSampled {
+			t.Errorf("ParseTraceparent(%q) sampled = %v", tt.traceparent, sc.Sampled)
+		}
+		if got := sc.Traceparent(); got[3:] != tt.traceparent[3:55] {
+			t.Errorf("Traceparent() = %s", got)
+		}
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec)
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("http.status_code", 500)
+	child.SetStatus(StatusError, "Internal Server Error")
+	child.End()
+	child.End()
+	root.End()
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(rec.spans))
+	}
+	c, r := rec.spans[0], rec.spans[1]
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || r.ParentSpanID != "" || c.SpanID == r.SpanID {
+		t.Errorf("child %+v is not a child of root %+v", c, r)
+	}
+	if c.Kind != SpanKindClient || c.StatusCode != StatusError || c.Attributes["http.status_code"] != 500 {
+		t.Errorf("unexpected child %+v", c)
+	}
+	if r.StatusCode != StatusUnset || r.EndTime.Before(r.StartTime) {
+		t.Errorf("unexpected root %+v", r)
+	}
+}
+
+func TestTracer_StartRemote(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	remote.TraceState = "congo=t61rcWkgMzE"
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	if got := SpanContextFromContext(ctx); got != remote {
+		t.Errorf("SpanContextFromContext() = %+v", got)
+	}
+	ctx, span := tracer.Start(ctx, "GetUser", SpanKindServer)
+	span.End()
+	sc := SpanContextFromContext(ctx)
+	if sc.TraceID != remote.TraceID || sc.SpanID == remote.SpanID || sc.TraceState != remote.TraceState {
+		t.Errorf("span context %+v should continue %+v", sc, remote)
+	}
+	if len(rec.spans) != 1 || rec.spans[0].ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("unexpected spans %+v", rec.spans)
+	}
+
+	remote.Sampled = false
+	_, span = tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "GetUser", SpanKindServer)
+	span.End()
+	if len(rec.spans) != 1 {
+		t.Error("spans not sampled by upstream should not be exported")
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewWriterExporter(&buf))
+	_, span := tracer.Start(context.Background(), "GetUser", SpanKindServer)
+	span.End()
+	_, span = tracer.Start(context.Background(), "PageUsers", SpanKindServer)
+	span.End()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var data SpanData
+	if err := json.Unmarshal([]byte(lines[1]), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Name != "PageUsers" || len(data.TraceID) != 32 || len(data.SpanID) != 16 {
+		t.Errorf("unexpected span %+v", data)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "spans", "spans.json")
+	exporter, err := NewFileExporter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := NewTracer(exporter).Start(context.Background(), "GetUser", SpanKindServer)
+	span.End()
+	exporter.Close()
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"name":"GetUser"`) {
+		t.Errorf("unexpected content %s", content)
+	}
+}
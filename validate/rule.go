@@ -0,0 +1,220 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+)
+
+// TagName is the struct tag key of validation rules, e.g. `validate:"required,maxlen=20"`
+const TagName = "validate"
+
+// Rules are validation rules declared by `validate` struct tag on vo struct fields or @validate annotation
+// on method params. They are separated by comma, e.g. "required,min=1,max=100,maxlen=20,enum=a|b|c,pattern=^\w+$".
+// Rule required means value must not be zero value, e.g. empty string, 0, nil pointer or empty slice.
+// Rules min and max are bounds of numbers, while minlen and maxlen are bounds of length of strings, slices and maps.
+// Rule enum declares allowed values separated by |. Rule pattern declares regular expression that strings must match,
+// and it must be the last one as it may contain comma.
+// Rules except required are not checked against nil pointers, empty strings, slices and maps, so add required if they are
+// not allowed. Numbers are checked against min, max and enum even if they are 0
+type Rules struct {
+	Required bool
+	Min      *float64
+	Max      *float64
+	MinLen   *int
+	MaxLen   *int
+	Enum     []string
+	Pattern  string
+}
+
+// IsEmpty returns true if no rule declared
+func (r Rules) IsEmpty() bool {
+	return !r.Required && r.Min == nil && r.Max == nil && r.MinLen == nil && r.MaxLen == nil && len(r.Enum) == 0 && r.Pattern == ""
+}
+
+// ParseRules parses comma separated rules, e.g. "required,min=1,max=100"
+func ParseRules(rules string) (Rules, error) {
+	var ret Rules
+	rules = strings.TrimSpace(rules)
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "pattern=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "required":
+			ret.Required = true
+		case "min", "max":
+			f, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return Rules{}, errors.Errorf("invalid rule %s: %s", rule, err)
+			}
+			if name == "min" {
+				ret.Min = &f
+			} else {
+				ret.Max = &f
+			}
+		case "minlen", "maxlen":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return Rules{}, errors.Errorf("invalid rule %s: should be non-negative integer", rule)
+			}
+			if name == "minlen" {
+				ret.MinLen = &n
+			} else {
+				ret.MaxLen = &n
+			}
+		case "enum":
+			if arg == "" {
+				return Rules{}, errors.Errorf("invalid rule %s: no value", rule)
+			}
+			ret.Enum = strings.Split(arg, "|")
+		case "pattern":
+			if _, err := regexpOf(arg); err != nil {
+				return Rules{}, errors.Errorf("invalid rule %s: %s", rule, err)
+			}
+			ret.Pattern = arg
+		default:
+			return Rules{}, errors.Errorf("unknown rule %s", rule)
+		}
+	}
+	return ret, nil
+}
+
+// RulesOf parses rules from `validate` struct tag
+func RulesOf(tag string) (Rules, error) {
+	return ParseRules(reflect.StructTag(tag).Get(TagName))
+}
+
+var rulesCache sync.Map
+
+// cachedRules parses rules only once as they are declared in code and never change
+func cachedRules(rules string) (Rules, error) {
+	type parsed struct {
+		rules Rules
+		err   error
+	}
+	if v, ok := rulesCache.Load(rules); ok {
+		return v.(parsed).rules, v.(parsed).err
+	}
+	r, err := ParseRules(rules)
+	rulesCache.Store(rules, parsed{r, err})
+	return r, err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (r Rules) check(field string, value reflect.Value) Errors {
+	var errs Errors
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() || isZero(value) {
+		if r.Required {
+			errs = append(errs, FieldError{Field: field, Rule: "required", Message: "is required"})
+			return errs
+		}
+		if !value.IsValid() || !isNumber(value) {
+			return errs
+		}
+	}
+	if isNumber(value) {
+		f := toFloat(value)
+		if r.Min != nil && f < *r.Min {
+			errs = append(errs, FieldError{Field: field, Rule: "min", Message: "should not be less than " + formatFloat(*r.Min)})
+		}
+		if r.Max != nil && f > *r.Max {
+			errs = append(errs, FieldError{Field: field, Rule: "max", Message: "should not be greater than " + formatFloat(*r.Max)})
+		}
+	}
+	var length = -1
+	switch value.Kind() {
+	case reflect.String:
+		length = len([]rune(value.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		length = value.Len()
+	}
+	if length >= 0 {
+		if r.MinLen != nil && length < *r.MinLen {
+			errs = append(errs, FieldError{Field: field, Rule: "minlen", Message: fmt.Sprintf("length should not be less than %d", *r.MinLen)})
+		}
+		if r.MaxLen != nil && length > *r.MaxLen {
+			errs = append(errs, FieldError{Field: field, Rule: "maxlen", Message: fmt.Sprintf("length should not be greater than %d", *r.MaxLen)})
+		}
+	}
+	if r.Pattern != "" && value.Kind() == reflect.String {
+		re, _ := regexpOf(r.Pattern)
+		if !re.MatchString(value.String()) {
+			errs = append(errs, FieldError{Field: field, Rule: "pattern", Message: "should match " + r.Pattern})
+		}
+	}
+	if len(r.Enum) > 0 {
+		var elems []reflect.Value
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			for i := 0; i < value.Len(); i++ {
+				elems = append(elems, value.Index(i))
+			}
+		} else {
+			elems = append(elems, value)
+		}
+		for _, elem := range elems {
+			if !sliceutils.StringContains(r.Enum, fmt.Sprint(elem.Interface())) {
+				errs = append(errs, FieldError{Field: field, Rule: "enum", Message: "should be one of " + strings.Join(r.Enum, ", ")})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func isNumber(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func toFloat(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint())
+	default:
+		return value.Float()
+	}
+}
+
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return value.IsZero()
+}
@@ -0,0 +1,118 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FieldError describes which rule a field violates
+type FieldError struct {
+	// Field is json path of the field, e.g. page.size or items[0].name
+	Field string `json:"field"`
+	// Rule is name of the violated rule, e.g. required or maxlen
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is list of field errors, used as details of 400 error responses from generated http handlers
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	var msgs []string
+	for _, item := range e {
+		msgs = append(msgs, fmt.Sprintf("%s %s", item.Field, item.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var regexpCache sync.Map
+
+func regexpOf(pattern string) (*regexp.Regexp, error) {
+	if v, ok := regexpCache.Load(pattern); ok {
+		return v.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
+
+// Var validates value against rules, e.g. Var("size", size, "min=1,max=100").
+// Invalid rules are reported as field error of rule validate
+func Var(field string, value interface{}, rules string) Errors {
+	r, err := cachedRules(rules)
+	if err != nil {
+		return Errors{{Field: field, Rule: TagName, Message: err.Error()}}
+	}
+	return r.check(field, reflect.ValueOf(value))
+}
+
+// Struct validates fields of struct v against rules declared by `validate` struct tags recursively,
+// including nested structs and elements of slices. Fields are named after their json names.
+// It returns nil if v is not struct or pointer to struct
+func Struct(v interface{}) Errors {
+	return walk("", reflect.ValueOf(v))
+}
+
+func walk(path string, value reflect.Value) Errors {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	var errs Errors
+	switch value.Kind() {
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				// unexported
+				continue
+			}
+			name := jsonName(field)
+			if name == "-" {
+				continue
+			}
+			fv := value.Field(i)
+			if field.Anonymous && name == "" {
+				errs = append(errs, walk(path, fv)...)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			fpath := name
+			if path != "" {
+				fpath = path + "." + name
+			}
+			if tag := field.Tag.Get(TagName); tag != "" {
+				errs = append(errs, Var(fpath, fv.Interface(), tag)...)
+			}
+			errs = append(errs, walk(fpath, fv)...)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			errs = append(errs, walk(fmt.Sprintf("%s[%d]", path, i), value.Index(i))...)
+		}
+	}
+	return errs
+}
+
+// jsonName returns name of field in json tag. Empty string is returned for embedded struct without json name
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" && field.Anonymous {
+		return ""
+	}
+	return tag
+}
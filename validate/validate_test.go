@@ -0,0 +1,136 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	min, max, maxlen := 1.0, 100.0, 20
+	tests := []struct {
+		name    string
+		rules   string
+		want    Rules
+		wantErr bool
+	}{
+		{
+			name:  "all",
+			rules: "required,min=1,max=100,maxlen=20,enum=a|b,pattern=^[a-z,]+$",
+			want: Rules{
+				Required: true,
+				Min:      &min,
+				Max:      &max,
+				MaxLen:   &maxlen,
+				Enum:     []string{"a", "b"},
+				Pattern:  "^[a-z,]+$",
+			},
+		},
+		{
+			name:  "empty",
+			rules: "",
+			want:  Rules{},
+		},
+		{
+			name:    "unknown rule",
+			rules:   "required,foo",
+			wantErr: true,
+		},
+		{
+			name:    "invalid min",
+			rules:   "min=a",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			rules:   "pattern=[",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRules() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVar(t *testing.T) {
+	name := "jack"
+	tests := []struct {
+		name  string
+		value interface{}
+		rules string
+		want  []string
+	}{
+		{"required", "", "required", []string{"required"}},
+		{"nil pointer", (*string)(nil), "required", []string{"required"}},
+		{"pointer", &name, "required,maxlen=3", []string{"maxlen"}},
+		{"zero", 0, "min=1", []string{"min"}},
+		{"zero in range", 0, "min=0,max=10,enum=0|1", nil},
+		{"zero not in enum", 0.0, "enum=1|2", []string{"enum"}},
+		{"optional nil pointer", (*int)(nil), "min=1", nil},
+		{"optional empty string", "", "minlen=2,pattern=^[a-z]+$", nil},
+		{"min", -1, "min=1", []string{"min"}},
+		{"max", 101, "max=100", []string{"max"}},
+		{"minlen", []int{1}, "minlen=2", []string{"minlen"}},
+		{"pattern", "Jack", "pattern=^[a-z]+$", []string{"pattern"}},
+		{"enum", "c", "enum=a|b", []string{"enum"}},
+		{"enum slice", []string{"a", "c"}, "enum=a|b", []string{"enum"}},
+		{"valid", 10, "required,min=1,max=100,enum=10|20", nil},
+		{"invalid rules", 10, "foo", []string{TagName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, item := range Var("field", tt.value, tt.rules) {
+				got = append(got, item.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Var() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type base struct {
+	Id int `json:"id" validate:"required"`
+}
+
+type item struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type order struct {
+	base
+	Status string  `json:"status,omitempty" validate:"enum=paid|shipped"`
+	Items  []item  `json:"items" validate:"minlen=1"`
+	Remark *string `validate:"maxlen=2"`
+	Ignore string  `json:"-" validate:"required"`
+	secret string  `validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	remark := "abc"
+	o := order{
+		Status: "new",
+		Items:  []item{{Name: "a"}, {}},
+		Remark: &remark,
+	}
+	var got []string
+	for _, item := range Struct(&o) {
+		got = append(got, item.Field+":"+item.Rule)
+	}
+	want := []string{"id:required", "status:enum", "items[1].name:required", "Remark:maxlen"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct() got = %v, want %v", got, want)
+	}
+	if errs := Struct(map[string]string{}); errs != nil {
+		t.Errorf("Struct() got = %v, want nil", errs)
+	}
+}